@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 07. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-09-06 15:57:17 krylon>
+// Time-stamp: <2026-10-16 17:29:11 krylon>
 
 package database
 
@@ -172,3 +172,28 @@ func TestDeviceGetByName(t *testing.T) {
 		}
 	}
 } // func TestDeviceGetByID(t *testing.T)
+
+func TestDeviceSetCritical(t *testing.T) {
+	if tdb == nil || len(tdev) == 0 || tdev[0] == nil {
+		t.SkipNow()
+	}
+
+	var (
+		err  error
+		xdev *model.Device
+		dev  = tdev[0]
+	)
+
+	for _, critical := range []bool{true, false} {
+		if err = tdb.DeviceSetCritical(dev, critical); err != nil {
+			t.Fatalf("Failed to set Critical flag of %s to %t: %s",
+				dev.Name,
+				critical,
+				err.Error())
+		} else if xdev, err = tdb.DeviceGetByID(dev.ID); err != nil {
+			t.Fatalf("Failed to load Device %s: %s", dev.Name, err.Error())
+		} else if xdev.Critical != critical {
+			t.Fatalf("Critical flag of %s should be %t", dev.Name, critical)
+		}
+	}
+} // func TestDeviceSetCritical(t *testing.T)
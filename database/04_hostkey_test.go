@@ -0,0 +1,66 @@
+// /home/krylon/go/src/github.com/blicero/carebear/database/04_hostkey_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 16:51:30 krylon>
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blicero/carebear/model"
+)
+
+func TestHostKeyReplace(t *testing.T) {
+	if tdb == nil || len(tdev) == 0 || tdev[0] == nil {
+		t.SkipNow()
+	}
+
+	var (
+		err  error
+		keys []*model.HostKey
+		dev  = tdev[0]
+		now  = time.Now()
+		old  = &model.HostKey{
+			DevID:       dev.ID,
+			KeyType:     "ssh-ed25519",
+			Fingerprint: "SHA256:old",
+			Key:         "AAAA",
+			FirstSeen:   now,
+			LastSeen:    now,
+			Trusted:     true,
+		}
+		nkey = &model.HostKey{
+			DevID:       dev.ID,
+			KeyType:     "ssh-ed25519",
+			Fingerprint: "SHA256:new",
+			Key:         "BBBB",
+			FirstSeen:   now,
+			LastSeen:    now,
+		}
+	)
+
+	if err = tdb.HostKeyAdd(old); err != nil {
+		t.Fatalf("Failed to add host key %s: %s", old.Fingerprint, err.Error())
+	} else if err = tdb.HostKeyAdd(nkey); err != nil {
+		t.Fatalf("Failed to add host key %s: %s", nkey.Fingerprint, err.Error())
+	} else if keys, err = tdb.HostKeyGetByDevice(dev); err != nil {
+		t.Fatalf("Failed to load host keys of %s: %s", dev.Name, err.Error())
+	} else if len(keys) != 2 {
+		t.Fatalf("Expected 2 host keys for %s, got %d", dev.Name, len(keys))
+	}
+
+	if err = tdb.HostKeyDeleteOther(nkey); err != nil {
+		t.Fatalf("Failed to delete old host keys: %s", err.Error())
+	} else if err = tdb.HostKeySetTrusted(nkey, true); err != nil {
+		t.Fatalf("Failed to trust host key %s: %s", nkey.Fingerprint, err.Error())
+	} else if keys, err = tdb.HostKeyGetByDevice(dev); err != nil {
+		t.Fatalf("Failed to load host keys of %s: %s", dev.Name, err.Error())
+	} else if len(keys) != 1 {
+		t.Fatalf("Expected 1 host key for %s, got %d", dev.Name, len(keys))
+	} else if keys[0].Fingerprint != nkey.Fingerprint || !keys[0].Trusted {
+		t.Fatalf("Unexpected host key after replacement: %#v", keys[0])
+	}
+} // func TestHostKeyReplace(t *testing.T)
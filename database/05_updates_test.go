@@ -0,0 +1,62 @@
+// /home/krylon/go/src/github.com/blicero/carebear/database/05_updates_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:43:03 krylon>
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blicero/carebear/model"
+)
+
+func TestUpdatesByPackage(t *testing.T) {
+	if tdb == nil || len(tdev) < 2 || tdev[1] == nil {
+		t.SkipNow()
+	}
+
+	var (
+		err  error
+		pkgs []*model.PackageUpdate
+		sets []*model.Updates
+		now  = time.Now()
+	)
+
+	for i, dev := range tdev[:2] {
+		var upd = &model.Updates{
+			DevID:     dev.ID,
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+			AvailableUpdates: []*model.PackageUpdate{
+				{Name: "openssl", CurrentVersion: "3.0.14", NewVersion: "3.0.15", Repo: "stable-security", Source: "debian"},
+				{Name: "tzdata", CurrentVersion: "2024b", NewVersion: "2025b", Repo: "stable-updates", Source: "debian"},
+			},
+		}
+
+		if i == 1 {
+			upd.AvailableUpdates = upd.AvailableUpdates[1:]
+		}
+
+		if err = tdb.UpdatesAdd(upd); err != nil {
+			t.Fatalf("Failed to add updates for %s: %s", dev.Name, err.Error())
+		}
+	}
+
+	if sets, err = tdb.UpdatesGetByDevice(tdev[0], 1); err != nil {
+		t.Fatalf("Failed to load updates for %s: %s", tdev[0].Name, err.Error())
+	} else if len(sets) != 1 {
+		t.Fatalf("Expected 1 set of updates for %s, got %d", tdev[0].Name, len(sets))
+	} else if len(sets[0].AvailableUpdates) != 2 {
+		t.Fatalf("Expected 2 pending updates for %s, got %d",
+			tdev[0].Name,
+			len(sets[0].AvailableUpdates))
+	} else if pkgs, err = tdb.PackageUpdateGetByName("openssl"); err != nil {
+		t.Fatalf("Failed to look up pending updates for openssl: %s", err.Error())
+	} else if len(pkgs) != 1 {
+		t.Fatalf("Expected 1 Device to need an update of openssl, got %d", len(pkgs))
+	} else if pkgs[0].DevID != tdev[0].ID || pkgs[0].NewVersion != "3.0.15" || pkgs[0].Source != "debian" {
+		t.Fatalf("Unexpected pending update: %#v", pkgs[0])
+	}
+} // func TestUpdatesByPackage(t *testing.T)
@@ -0,0 +1,135 @@
+// /home/krylon/go/src/github.com/blicero/carebear/database/06_info_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:46:10 krylon>
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blicero/carebear/model"
+	"github.com/blicero/carebear/model/info"
+)
+
+func TestNeedReboot(t *testing.T) {
+	if tdb == nil || len(tdev) == 0 || tdev[0] == nil {
+		t.SkipNow()
+	}
+
+	var (
+		err    error
+		status map[int64]*model.NeedReboot
+		rec    *model.NeedReboot
+		dev    = tdev[0]
+		now    = time.Now()
+	)
+
+	for i, req := range []bool{false, true} {
+		var r = &model.NeedReboot{
+			DevID:     dev.ID,
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+			Required:  req,
+		}
+
+		if err = tdb.NeedRebootAdd(r); err != nil {
+			t.Fatalf("Failed to add reboot status for %s: %s", dev.Name, err.Error())
+		}
+	}
+
+	if rec, err = tdb.NeedRebootGetByDevice(dev); err != nil {
+		t.Fatalf("Failed to load reboot status of %s: %s", dev.Name, err.Error())
+	} else if rec == nil || !rec.Required {
+		t.Fatalf("Expected %s to need a reboot: %#v", dev.Name, rec)
+	} else if status, err = tdb.NeedRebootGetRecent(); err != nil {
+		t.Fatalf("Failed to load reboot status of all Devices: %s", err.Error())
+	} else if rec = status[dev.ID]; rec == nil || !rec.Required {
+		t.Fatalf("Expected %s to need a reboot: %#v", dev.Name, rec)
+	}
+} // func TestNeedReboot(t *testing.T)
+
+func TestCustomValue(t *testing.T) {
+	if tdb == nil || len(tdev) == 0 || tdev[0] == nil {
+		t.SkipNow()
+	}
+
+	const probe = "entropy"
+
+	var (
+		err      error
+		id1, id2 info.ID
+		vals     []*model.CustomValue
+		dev      = tdev[0]
+		now      = time.Now()
+	)
+
+	if id1, err = tdb.InfoTypeRegister(probe); err != nil {
+		t.Fatalf("Failed to register info type %s: %s", probe, err.Error())
+	} else if id1 < info.CustomBase {
+		t.Fatalf("Custom info type %s got a builtin ID: %d", probe, id1)
+	} else if id2, err = tdb.InfoTypeRegister("temperature_gpu"); err != nil {
+		t.Fatalf("Failed to register second info type: %s", err.Error())
+	} else if id2 == id1 {
+		t.Fatalf("Both custom info types got the same ID %d", id1)
+	} else if info.Name(id1) != probe {
+		t.Errorf("Unexpected name for info type %d: %s", id1, info.Name(id1))
+	}
+
+	for i, num := range []string{"256", "3712"} {
+		var v = &model.CustomValue{
+			DevID:     dev.ID,
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+			Probe:     probe,
+			Values:    map[string]string{"value": num},
+		}
+
+		if err = tdb.CustomValueAdd(v); err != nil {
+			t.Fatalf("Failed to add result of %s for %s: %s", probe, dev.Name, err.Error())
+		}
+	}
+
+	if vals, err = tdb.CustomValueGetByDevice(dev, probe, 10); err != nil {
+		t.Fatalf("Failed to load results of %s for %s: %s", probe, dev.Name, err.Error())
+	} else if len(vals) != 2 {
+		t.Fatalf("Unexpected number of results: %d (expected 2)", len(vals))
+	} else if num, ok := vals[0].Number("value"); !ok || num != 3712 {
+		t.Errorf("Unexpected most recent result: %#v", vals[0].Values)
+	}
+} // func TestCustomValue(t *testing.T)
+
+func TestGuests(t *testing.T) {
+	if tdb == nil || len(tdev) < 2 || tdev[0] == nil || tdev[1] == nil {
+		t.SkipNow()
+	}
+
+	var (
+		err    error
+		rec    *model.Guests
+		recent map[int64]*model.Guests
+		host   = tdev[0]
+		g      = &model.Guests{
+			DevID:     host.ID,
+			Timestamp: time.Now(),
+			Guests: []model.Guest{
+				{Kind: model.GuestLibvirt, Name: tdev[1].Name, State: model.GuestStateRunning, DevID: tdev[1].ID},
+				{Kind: model.GuestDocker, Name: "web", Image: "nginx:latest", State: "exited"},
+			},
+		}
+	)
+
+	if err = tdb.GuestsAdd(g); err != nil {
+		t.Fatalf("Failed to add guests of %s: %s", host.Name, err.Error())
+	} else if rec, err = tdb.GuestsGetByDevice(host); err != nil {
+		t.Fatalf("Failed to load guests of %s: %s", host.Name, err.Error())
+	} else if rec == nil || len(rec.Guests) != 2 {
+		t.Fatalf("Unexpected guests of %s: %#v", host.Name, rec)
+	} else if rec.Guests[1].Image != "nginx:latest" || rec.Guests[1].Running() {
+		t.Errorf("Unexpected guest: %#v", rec.Guests[1])
+	} else if recent, err = tdb.GuestsGetRecent(); err != nil {
+		t.Fatalf("Failed to load guests of all Devices: %s", err.Error())
+	} else if rec = recent[host.ID]; rec == nil || rec.Find(tdev[1].ID) == nil {
+		t.Errorf("Expected %s to be a guest of %s: %#v", tdev[1].Name, host.Name, rec)
+	}
+} // func TestGuests(t *testing.T)
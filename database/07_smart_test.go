@@ -0,0 +1,59 @@
+// /home/krylon/go/src/github.com/blicero/carebear/database/07_smart_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:25:55 krylon>
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blicero/carebear/model"
+)
+
+func TestSmartFailing(t *testing.T) {
+	if tdb == nil || len(tdev) == 0 || tdev[0] == nil {
+		t.SkipNow()
+	}
+
+	var (
+		err     error
+		disks   []*model.SmartInfo
+		failing map[int64][]*model.SmartInfo
+		dev     = tdev[0]
+		now     = time.Now()
+	)
+
+	// sda failed in the past but has since been replaced, sdb fails now.
+	// sdc failed, too, but it was removed, the latest probe did not see it.
+	for _, si := range []*model.SmartInfo{
+		{Disk: "/dev/sda", Passed: false, Timestamp: now.Add(-time.Hour)},
+		{Disk: "/dev/sdc", Passed: false, Timestamp: now.Add(-time.Hour)},
+		{Disk: "/dev/sda", Passed: true, Timestamp: now},
+		{Disk: "/dev/sdb", Passed: true, Timestamp: now.Add(-time.Hour)},
+		{Disk: "/dev/sdb", Passed: false, Timestamp: now},
+	} {
+		si.DevID = dev.ID
+		si.Reallocated = -1
+		si.PowerOnHours = -1
+		si.Temperature = -1
+
+		if err = tdb.SmartAdd(si); err != nil {
+			t.Fatalf("Failed to add SMART status of %s: %s", si.Disk, err.Error())
+		}
+	}
+
+	if disks, err = tdb.SmartGetByDevice(dev); err != nil {
+		t.Fatalf("Failed to load SMART status of %s: %s", dev.Name, err.Error())
+	} else if len(disks) != 2 {
+		t.Fatalf("Expected 2 disks, got %d", len(disks))
+	} else if !disks[0].Passed || disks[1].Passed {
+		t.Errorf("Unexpected SMART status: %#v, %#v", disks[0], disks[1])
+	} else if failing, err = tdb.SmartGetFailing(); err != nil {
+		t.Fatalf("Failed to load failing disks: %s", err.Error())
+	} else if len(failing[dev.ID]) != 1 || failing[dev.ID][0].Disk != "/dev/sdb" {
+		t.Errorf("Unexpected failing disks: %#v", failing[dev.ID])
+	}
+} // func TestSmartFailing(t *testing.T)
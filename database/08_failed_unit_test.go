@@ -0,0 +1,55 @@
+// /home/krylon/go/src/github.com/blicero/carebear/database/08_failed_unit_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:17:03 krylon>
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blicero/carebear/model"
+)
+
+func TestFailedUnit(t *testing.T) {
+	if tdb == nil || len(tdev) == 0 || tdev[0] == nil {
+		t.SkipNow()
+	}
+
+	var (
+		err    error
+		units  []*model.FailedUnit
+		active map[int64][]*model.FailedUnit
+		dev    = tdev[0]
+		now    = time.Now()
+	)
+
+	for _, name := range []string{"nfs-server.service", "logrotate.service"} {
+		var u = &model.FailedUnit{
+			DevID:    dev.ID,
+			Name:     name,
+			Since:    now,
+			LastSeen: now,
+		}
+
+		if err = tdb.FailedUnitAdd(u); err != nil {
+			t.Fatalf("Failed to add failed unit %s: %s", name, err.Error())
+		}
+	}
+
+	if units, err = tdb.FailedUnitGetByDevice(dev); err != nil {
+		t.Fatalf("Failed to load failed units of %s: %s", dev.Name, err.Error())
+	} else if len(units) != 2 {
+		t.Fatalf("Expected 2 failed units, got %d", len(units))
+	} else if err = tdb.FailedUnitClear(units[0], now.Add(time.Minute)); err != nil {
+		t.Fatalf("Failed to clear failed unit %s: %s", units[0].Name, err.Error())
+	} else if active, err = tdb.FailedUnitGetActive(); err != nil {
+		t.Fatalf("Failed to load failed units: %s", err.Error())
+	} else if len(active[dev.ID]) != 1 || active[dev.ID][0].ID != units[1].ID {
+		t.Errorf("Unexpected failed units after clearing %s: %#v",
+			units[0].Name,
+			active[dev.ID])
+	}
+} // func TestFailedUnit(t *testing.T)
@@ -0,0 +1,70 @@
+// /home/krylon/go/src/github.com/blicero/carebear/database/09_package_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:19:29 krylon>
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blicero/carebear/model"
+)
+
+func TestInstalledPackage(t *testing.T) {
+	if tdb == nil || len(tdev) < 2 || tdev[0] == nil || tdev[1] == nil {
+		t.SkipNow()
+	}
+
+	var (
+		err  error
+		pkgs []*model.InstalledPackage
+		now  = time.Now()
+	)
+
+	for i, version := range []string{"3.0.2-0ubuntu1.15", "3.0.13-0ubuntu3.4"} {
+		var pkg = &model.InstalledPackage{
+			Package: model.Package{
+				Name:    "openssl",
+				Version: version,
+				Arch:    "amd64",
+			},
+			DevID: tdev[i].ID,
+			Since: now,
+		}
+
+		if err = tdb.InstalledPackageSet(pkg); err != nil {
+			t.Fatalf("Failed to add package %s %s: %s", pkg.Name, version, err.Error())
+		}
+	}
+
+	// A Device may have several versions of a package installed, e.g.
+	// kernels on Fedora, and adding one we know again changes nothing.
+	var second = &model.InstalledPackage{
+		Package: model.Package{
+			Name:    "openssl",
+			Version: "3.0.2-0ubuntu1.18",
+			Arch:    "amd64",
+		},
+		DevID: tdev[0].ID,
+		Since: now.Add(time.Hour),
+	}
+
+	if err = tdb.InstalledPackageSet(second); err != nil {
+		t.Fatalf("Failed to add package %s %s: %s", second.Name, second.Version, err.Error())
+	} else if err = tdb.InstalledPackageSet(second); err != nil {
+		t.Fatalf("Failed to add package %s %s again: %s", second.Name, second.Version, err.Error())
+	} else if pkgs, err = tdb.InstalledPackageGetByDevice(tdev[0]); err != nil {
+		t.Fatalf("Failed to load packages of %s: %s", tdev[0].Name, err.Error())
+	} else if len(pkgs) != 2 || pkgs[1].Version != second.Version {
+		t.Fatalf("Unexpected packages on %s: %#v", tdev[0].Name, pkgs)
+	} else if pkgs, err = tdb.InstalledPackageSearch("openss*"); err != nil {
+		t.Fatalf("Failed to search for packages: %s", err.Error())
+	} else if len(pkgs) != 3 {
+		t.Fatalf("Expected 3 packages, got %d", len(pkgs))
+	} else if err = tdb.InstalledPackageDelete(pkgs[0]); err != nil {
+		t.Fatalf("Failed to delete package: %s", err.Error())
+	}
+} // func TestInstalledPackage(t *testing.T)
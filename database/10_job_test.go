@@ -0,0 +1,55 @@
+// /home/krylon/go/src/github.com/blicero/carebear/database/10_job_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:26:44 krylon>
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blicero/carebear/model"
+	"github.com/blicero/carebear/model/job"
+)
+
+func TestJob(t *testing.T) {
+	if tdb == nil || len(tdev) == 0 || tdev[0] == nil {
+		t.SkipNow()
+	}
+
+	var (
+		err  error
+		jobs []*model.Job
+		j    = &model.Job{
+			DevID:   tdev[0].ID,
+			Kind:    job.Upgrade,
+			Started: time.Now().Add(-time.Minute),
+		}
+	)
+
+	if err = tdb.JobAdd(j); err != nil {
+		t.Fatalf("Failed to add job: %s", err.Error())
+	} else if j.ID == 0 {
+		t.Fatal("Job has no ID after adding it")
+	} else if jobs, err = tdb.JobGetByDevice(tdev[0], 10); err != nil {
+		t.Fatalf("Failed to load jobs of %s: %s", tdev[0].Name, err.Error())
+	} else if len(jobs) != 1 || !jobs[0].Running() {
+		t.Fatalf("Expected one running job, got %#v", jobs)
+	}
+
+	j.Finished = time.Now()
+	j.Status = 100
+	j.Output = "E: Could not get lock /var/lib/dpkg/lock-frontend"
+
+	if err = tdb.JobFinish(j); err != nil {
+		t.Fatalf("Failed to finish job: %s", err.Error())
+	} else if jobs, err = tdb.JobGetByDevice(tdev[0], 10); err != nil {
+		t.Fatalf("Failed to load jobs of %s: %s", tdev[0].Name, err.Error())
+	} else if len(jobs) != 1 || jobs[0].Running() || jobs[0].Success() {
+		t.Fatalf("Expected one failed job, got %#v", jobs)
+	} else if jobs[0].Status != j.Status || jobs[0].Output != j.Output {
+		t.Errorf("Unexpected job: %#v", jobs[0])
+	}
+} // func TestJob(t *testing.T)
@@ -0,0 +1,240 @@
+// /home/krylon/go/src/github.com/blicero/carebear/database/11_migrate_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:19:29 krylon>
+
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/blicero/carebear/common"
+)
+
+// migratedColumns lists the columns a database created from qinitV0 must
+// have after it has been migrated.
+var migratedColumns = []struct {
+	table  string
+	column string
+}{
+	{"device", "os_id"},
+	{"device", "os_like"},
+	{"package_update", "name"},
+	{"host_key", "fingerprint"},
+	{"ssh_profile", "proxy_jump"},
+	{"event", "kind"},
+	{"smart", "reallocated"},
+	{"failed_unit", "cleared"},
+	{"installed_package", "version"},
+	{"package_change", "old_version"},
+	{"job", "output"},
+	{"device", "critical"},
+	{"info_type", "name"},
+	{"ssh_profile", "escalate"},
+	{"package_update", "source"},
+}
+
+// TestMigrate creates a database with the schema we started out with, puts
+// some data in it, and checks that Open brings it up to date.
+func TestMigrate(t *testing.T) {
+	var (
+		err     error
+		version int
+		raw     *sql.DB
+		db      *Database
+		path    = filepath.Join(common.BaseDir, "migrate.db")
+	)
+
+	if raw, err = sql.Open("sqlite3", path+"?_fk=1"); err != nil {
+		t.Fatalf("Cannot open %s: %s", path, err.Error())
+	}
+
+	for _, q := range qinitV0 {
+		if _, err = raw.Exec(q); err != nil {
+			raw.Close() // nolint: errcheck
+			t.Fatalf("Cannot execute init query: %s\n%s", err.Error(), q)
+		}
+	}
+
+	for _, q := range migrateData {
+		if _, err = raw.Exec(q); err != nil {
+			raw.Close() // nolint: errcheck
+			t.Fatalf("Cannot insert test data: %s\n%s", err.Error(), q)
+		}
+	}
+
+	if err = raw.Close(); err != nil {
+		t.Fatalf("Cannot close %s: %s", path, err.Error())
+	} else if db, err = Open(path); err != nil {
+		t.Fatalf("Cannot migrate %s: %s", path, err.Error())
+	}
+
+	defer db.Close() // nolint: errcheck
+
+	if err = db.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatalf("Cannot query schema version: %s", err.Error())
+	} else if version != len(migrations) {
+		t.Errorf("Unexpected schema version %d (expected %d)",
+			version,
+			len(migrations))
+	}
+
+	for _, c := range migratedColumns {
+		var cnt int
+
+		if err = db.db.QueryRow(
+			"SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?",
+			c.table,
+			c.column).Scan(&cnt); err != nil {
+			t.Errorf("Cannot look for column %s.%s: %s",
+				c.table,
+				c.column,
+				err.Error())
+		} else if cnt == 0 {
+			t.Errorf("Column %s.%s is missing", c.table, c.column)
+		}
+	}
+
+	if devs, err := db.DeviceGetAll(false); err != nil {
+		t.Errorf("Cannot load Devices from migrated database: %s", err.Error())
+	} else if len(devs) != 1 {
+		t.Errorf("Unexpected number of Devices: %d (expected 1)", len(devs))
+	}
+
+	var cnt int
+
+	if err = db.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('updates') WHERE name = 'updates'").Scan(&cnt); err != nil {
+		t.Errorf("Cannot look for column updates.updates: %s", err.Error())
+	} else if cnt != 0 {
+		t.Error("Column updates.updates should be gone")
+	}
+
+	if err = db.db.QueryRow("SELECT COUNT(*) FROM package_update WHERE upd_id = 1").Scan(&cnt); err != nil {
+		t.Errorf("Cannot count package updates: %s", err.Error())
+	} else if cnt != 2 {
+		t.Errorf("Unexpected number of package updates: %d (expected 2)", cnt)
+	}
+
+	for _, version := range []string{"6.10.3-200.fc40", "6.10.6-200.fc40"} {
+		if _, err = db.db.Exec(
+			"INSERT INTO installed_package (dev_id, name, version, arch, since) VALUES (1, 'kernel', ?, 'x86_64', 0)",
+			version); err != nil {
+			t.Errorf("Cannot add kernel %s: %s", version, err.Error())
+		}
+	}
+} // func TestMigrate(t *testing.T)
+
+var migrateData = []string{
+	"INSERT INTO network (id, addr) VALUES (1, '10.0.0.0/24')",
+	`INSERT INTO device (id, net_id, name, addr, os) VALUES (1, 1, 'oldhost', '["10.0.0.2"]', 'Debian')`,
+	`INSERT INTO updates (id, dev_id, timestamp, updates) VALUES (1, 1, 1700000000, '["curl","openssl"]')`,
+}
+
+// qinitV0 is the schema before we had migrations.
+var qinitV0 = []string{
+	`
+CREATE TABLE network (
+    id		INTEGER PRIMARY KEY,
+    addr	TEXT UNIQUE NOT NULL,
+    desc	TEXT NOT NULL DEFAULT '',
+    last_scan	INTEGER NOT NULL DEFAULT 0
+) STRICT
+`,
+	`
+CREATE TABLE device (
+    id		INTEGER PRIMARY KEY,
+    net_id	INTEGER NOT NULL,
+    name	TEXT UNIQUE NOT NULL,
+    addr        TEXT NOT NULL DEFAULT '[]',
+    os          TEXT NOT NULL DEFAULT '',
+    bighead     INTEGER NOT NULL DEFAULT 1,
+    last_seen   INTEGER NOT NULL DEFAULT 0,
+    CHECK (json_valid(addr)),
+    FOREIGN KEY (net_id) REFERENCES network (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+	"CREATE INDEX dev_big_idx ON device (bighead <> 0)",
+	"CREATE INDEX dev_last_idx ON device (last_seen)",
+	`
+CREATE TABLE uptime (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    timestamp INTEGER NOT NULL,
+    uptime INTEGER NOT NULL DEFAULT 0,
+    load1 REAL NOT NULL,
+    load5 REAL NOT NULL,
+    load15 REAL NOT NULL,
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE,
+    CHECK (uptime >= 0),
+    CHECK (load1 >= 0 AND load5 >= 0 AND load15 >= 0)
+) STRICT
+`,
+	"CREATE INDEX up_dev_idx ON uptime (dev_id)",
+	"CREATE INDEX up_time_idx ON uptime (timestamp)",
+	`
+CREATE TRIGGER up_host_contact_tr
+AFTER INSERT ON uptime
+BEGIN
+    UPDATE device
+    SET last_seen = NEW.timestamp
+    WHERE id = NEW.dev_id;
+END
+`,
+	`
+CREATE TABLE updates (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    timestamp INTEGER NOT NULL,
+    updates TEXT NOT NULL DEFAULT '[]',
+    UNIQUE (dev_id, timestamp),
+    CHECK (json_valid(updates)),
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+      ON UPDATE RESTRICT
+      ON DELETE CASCADE
+) STRICT
+`,
+	"CREATE INDEX upd_dev_idx ON updates (dev_id)",
+	"CREATE INDEX upd_time_idx ON updates (timestamp)",
+	`
+CREATE TRIGGER upd_host_contact_tr
+AFTER INSERT ON updates
+BEGIN
+    UPDATE device
+    SET last_seen = NEW.timestamp
+    WHERE id = NEW.dev_id;
+END
+`,
+	`
+CREATE TABLE info (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    timestamp INTEGER NOT NULL,
+    info_type INTEGER NOT NULL,
+    data TEXT NOT NULL DEFAULT '',
+    UNIQUE (dev_id, info_type, timestamp),
+    CHECK (json_valid(data)),
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+	"CREATE INDEX info_dev_idx ON info (dev_id)",
+	"CREATE INDEX info_time_idx ON info (timestamp)",
+	"CREATE INDEX info_type_idx ON info (info_type)",
+	`
+CREATE TRIGGER info_host_tr
+AFTER INSERT ON info
+BEGIN
+    UPDATE device
+    SET last_seen = NEW.timestamp
+    WHERE id = NEW.dev_id;
+END
+`,
+}
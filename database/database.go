@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 05. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-09-06 15:54:30 krylon>
+// Time-stamp: <2026-10-16 18:03:56 krylon>
 
 package database
 
@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net"
 	"os"
 	"regexp"
@@ -90,7 +91,8 @@ type Database struct {
 }
 
 // Open opens a Database. If the database specified by the path does not exist,
-// yet, it is created and initialized.
+// yet, it is created and initialized. If it does, it is migrated to the
+// current schema.
 func Open(path string) (*Database, error) {
 	var (
 		err      error
@@ -145,6 +147,12 @@ func Open(path string) (*Database, error) {
 		}
 		db.log.Printf("[INFO] Database at %s has been initialized\n",
 			path)
+	} else if err = db.migrate(); err != nil {
+		if e2 := db.db.Close(); e2 != nil {
+			db.log.Printf("[CRITICAL] Failed to close database: %s\n",
+				e2.Error())
+		}
+		return nil, err
 	}
 
 	return db, nil
@@ -181,6 +189,18 @@ func (db *Database) initialize() error {
 		}
 	}
 
+	// A fresh database has everything the migrations would add.
+	if err = setVersion(tx, len(migrations)); err != nil {
+		db.log.Printf("[ERROR] Cannot set schema version: %s\n",
+			err.Error())
+		if rbErr := tx.Rollback(); rbErr != nil {
+			db.log.Printf("[CANTHAPPEN] Cannot rollback transaction: %s\n",
+				rbErr.Error())
+			return rbErr
+		}
+		return err
+	}
+
 	if err = tx.Commit(); err != nil {
 		db.log.Printf("[CANTHAPPEN] Failed to commit init transaction: %s\n",
 			err.Error())
@@ -1030,8 +1050,8 @@ EXEC_QUERY:
 	return nil
 } // func (db *Database) DeviceUpdateLastSeen(dev *model.Device, t time.Time) error
 
-// DeviceUpdateOS sets the OS field of a Device.
-func (db *Database) DeviceUpdateOS(dev *model.Device, osname string) error {
+// DeviceUpdateOS sets the OS fields of a Device.
+func (db *Database) DeviceUpdateOS(dev *model.Device, rel *model.OSRelease) error {
 	const qid query.ID = query.DeviceUpdateOS
 	var (
 		err  error
@@ -1053,7 +1073,7 @@ func (db *Database) DeviceUpdateOS(dev *model.Device, osname string) error {
 	)
 
 EXEC_QUERY:
-	if res, err = stmt.Exec(osname, dev.ID); err != nil {
+	if res, err = stmt.Exec(rel.Name, rel.ID, rel.IDLike, dev.ID); err != nil {
 		if worthARetry(err) {
 			waitForRetry()
 			goto EXEC_QUERY
@@ -1076,10 +1096,48 @@ EXEC_QUERY:
 			dev.ID)
 	}
 
-	dev.OS = osname
+	dev.OS = rel.Name
+	dev.OSID = rel.ID
+	dev.OSLike = rel.IDLike
+
+	return nil
+} // func (db *Database) DeviceUpdateOS(dev *model.Device, rel *model.OSRelease) error
+
+// DeviceSetCritical sets or clears a Device's Critical flag.
+func (db *Database) DeviceSetCritical(dev *model.Device, critical bool) error {
+	const qid query.ID = query.DeviceSetCritical
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+EXEC_QUERY:
+	if _, err = stmt.Exec(critical, dev.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot set Critical flag of Device %s (%d): %w",
+			dev.Name,
+			dev.ID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
 
+	dev.Critical = critical
 	return nil
-} // func (db *Database) DeviceUpdateOS(dev *model.Device, osname string) error
+} // func (db *Database) DeviceSetCritical(dev *model.Device, critical bool) error
 
 // DeviceGetAll loads all Devices from the Database.
 func (db *Database) DeviceGetAll(bigheadOnly bool) ([]*model.Device, error) {
@@ -1126,7 +1184,10 @@ EXEC_QUERY:
 			&dev.Name,
 			&addr,
 			&dev.OS,
+			&dev.OSID,
+			&dev.OSLike,
 			&dev.BigHead,
+			&dev.Critical,
 			&stamp); err != nil {
 			var ex = fmt.Errorf("Failed to scan row: %w", err)
 			db.log.Printf("[ERROR] %s\n", ex.Error())
@@ -1209,7 +1270,7 @@ EXEC_QUERY:
 			dev   = &model.Device{ID: id}
 		)
 
-		if err = rows.Scan(&dev.NetID, &dev.Name, &addr, &dev.OS, &dev.BigHead, &stamp); err != nil {
+		if err = rows.Scan(&dev.NetID, &dev.Name, &addr, &dev.OS, &dev.OSID, &dev.OSLike, &dev.BigHead, &dev.Critical, &stamp); err != nil {
 			var ex = fmt.Errorf("Failed to scan row: %w", err)
 			db.log.Printf("[ERROR] %s\n", ex.Error())
 			return nil, err
@@ -1286,7 +1347,7 @@ EXEC_QUERY:
 			dev   = &model.Device{Name: name}
 		)
 
-		if err = rows.Scan(&dev.ID, &dev.NetID, &addr, &dev.OS, &dev.BigHead, &stamp); err != nil {
+		if err = rows.Scan(&dev.ID, &dev.NetID, &addr, &dev.OS, &dev.OSID, &dev.OSLike, &dev.BigHead, &dev.Critical, &stamp); err != nil {
 			var ex = fmt.Errorf("Failed to scan row: %w", err)
 			db.log.Printf("[ERROR] %s\n", ex.Error())
 			return nil, ex
@@ -1365,7 +1426,7 @@ EXEC_QUERY:
 			dev   = &model.Device{NetID: network.ID}
 		)
 
-		if err = rows.Scan(&dev.ID, &dev.Name, &addr, &dev.OS, &dev.BigHead, &stamp); err != nil {
+		if err = rows.Scan(&dev.ID, &dev.Name, &addr, &dev.OS, &dev.OSID, &dev.OSLike, &dev.BigHead, &dev.Critical, &stamp); err != nil {
 			var ex = fmt.Errorf("Failed to scan row: %w", err)
 			db.log.Printf("[ERROR] %s\n", ex.Error())
 			return nil, ex
@@ -1425,7 +1486,13 @@ func (db *Database) UptimeAdd(u *model.Uptime) error {
 	var rows *sql.Rows
 
 EXEC_QUERY:
-	if rows, err = stmt.Query(u.DevID, u.Timestamp.Unix(), u.Uptime, u.Load[0], u.Load[1], u.Load[2]); err != nil {
+	if rows, err = stmt.Query(
+		u.DevID,
+		u.Timestamp.Unix(),
+		int64(u.Uptime.Seconds()),
+		u.Load[0],
+		u.Load[1],
+		u.Load[2]); err != nil {
 		if worthARetry(err) {
 			waitForRetry()
 			goto EXEC_QUERY
@@ -1493,24 +1560,29 @@ EXEC_QUERY:
 
 	for rows.Next() {
 		var (
-			stamp int64
-			up    = &model.Uptime{DevID: d.ID}
+			stamp, uptime int64
+			up            = &model.Uptime{DevID: d.ID}
 		)
 
-		if err = rows.Scan(&up.ID, &stamp, &up.Load[0], &up.Load[1], &up.Load[2]); err != nil {
+		if err = rows.Scan(&up.ID, &stamp, &uptime, &up.Load[0], &up.Load[1], &up.Load[2]); err != nil {
 			var ex = fmt.Errorf("Failed to scan row: %w", err)
 			db.log.Printf("[ERROR] %s\n", ex.Error())
 			return nil, ex
 		}
 
 		up.Timestamp = time.Unix(stamp, 0)
+		up.Uptime = time.Duration(uptime) * time.Second
 		data = append(data, up)
 	}
 
 	return data, nil
 } // func (db *Database) UptimeGetByDevice(d *model.Device) ([]*model.Uptime, error)
 
-// UpdatesAdd adds a set of pending updates for a Device to the database.
+// UpdatesAdd adds a set of pending updates for a Device to the database,
+// along with the individual package updates it contains.
+//
+// To make sure the set is stored completely or not at all, the caller should
+// wrap this in a transaction.
 func (db *Database) UpdatesAdd(u *model.Updates) error {
 	const qid query.ID = query.UpdatesAdd
 	var (
@@ -1528,52 +1600,52 @@ func (db *Database) UpdatesAdd(u *model.Updates) error {
 	}
 
 	var (
-		rows  *sql.Rows
-		upStr string
-		buf   []byte
+		rows *sql.Rows
+		id   int64
 	)
 
-	if buf, err = json.Marshal(u.AvailableUpdates); err != nil {
-		db.log.Printf("[ERROR] Failed to serialize AvailableUpdates: %s\n\n%s\n\n",
-			err.Error(),
-			strings.Join(u.AvailableUpdates, "\n"))
-	}
-
-	upStr = string(buf)
-
 EXEC_QUERY:
-	if rows, err = stmt.Query(u.DevID, u.Timestamp.Unix(), upStr); err != nil {
+	if rows, err = stmt.Query(u.DevID, u.Timestamp.Unix()); err != nil {
 		if worthARetry(err) {
 			waitForRetry()
 			goto EXEC_QUERY
 		} else {
-			err = fmt.Errorf("Cannot add Uptime for Device %d: %w",
+			err = fmt.Errorf("Cannot add Updates for Device %d: %w",
 				u.DevID,
 				err)
 			db.log.Printf("[ERROR] %s\n", err.Error())
 			return err
 		}
-	} else {
-		var id int64
+	}
 
-		defer rows.Close()
+	if !rows.Next() {
+		// CANTHAPPEN
+		rows.Close() // nolint: errcheck
+		db.log.Printf("[ERROR] Query %s did not return a value\n",
+			qid)
+		return fmt.Errorf("Query %s did not return a value", qid)
+	} else if err = rows.Scan(&id); err != nil {
+		rows.Close() // nolint: errcheck
+		var ex = fmt.Errorf("Failed to get ID for newly added Updates: %w",
+			err)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return ex
+	}
 
-		if !rows.Next() {
-			// CANTHAPPEN
-			db.log.Printf("[ERROR] Query %s did not return a value\n",
-				qid)
-			return fmt.Errorf("Query %s did not return a value", qid)
-		} else if err = rows.Scan(&id); err != nil {
-			var ex = fmt.Errorf("Failed to get ID for newly added Uptime: %w",
-				err)
-			db.log.Printf("[ERROR] %s\n", ex.Error())
-			return ex
-		}
+	rows.Close() // nolint: errcheck
+	u.ID = id
 
-		u.ID = id
-		return nil
+	for _, pkg := range u.AvailableUpdates {
+		pkg.UpdID = u.ID
+		pkg.DevID = u.DevID
+
+		if err = db.PackageUpdateAdd(pkg); err != nil {
+			return err
+		}
 	}
-} // func (db *Database) UpdatesAdd(d *model.Device, u *model.Updates) error
+
+	return nil
+} // func (db *Database) UpdatesAdd(u *model.Updates) error
 
 // UpdatesGetByDevice loads the sets of available updates for the given Device in reverse
 // chronological order (i.e. most recent first), up to the given maximum number of sets.
@@ -1606,32 +1678,33 @@ EXEC_QUERY:
 		return nil, err
 	}
 
-	defer rows.Close() // nolint: errcheck,gosec
 	var data = make([]*model.Updates, 0, 16)
 
 	for rows.Next() {
 		var (
 			stamp int64
 			up    = &model.Updates{DevID: d.ID}
-			upstr string
 		)
 
-		if err = rows.Scan(&up.ID, &stamp, &upstr); err != nil {
+		if err = rows.Scan(&up.ID, &stamp); err != nil {
+			rows.Close() // nolint: errcheck
 			var ex = fmt.Errorf("Failed to scan row: %w", err)
 			db.log.Printf("[ERROR] %s\n", ex.Error())
 			return nil, ex
-		} else if err = json.Unmarshal([]byte(upstr), &up.AvailableUpdates); err != nil {
-			var ex = fmt.Errorf("Failed to parse AvailableUpdates from JSON: %w\n\n%s",
-				err,
-				upstr)
-			db.log.Printf("[ERROR] %s\n", ex.Error())
-			return nil, ex
 		}
 
 		up.Timestamp = time.Unix(stamp, 0)
 		data = append(data, up)
 	}
 
+	rows.Close() // nolint: errcheck
+
+	for _, up := range data {
+		if err = db.PackageUpdateGetBySet(up); err != nil {
+			return nil, err
+		}
+	}
+
 	return data, nil
 } // func (db *Database) UpdatesGetByDevice(d *model.Device, max int64) ([]*model.Updates, error)
 
@@ -1664,46 +1737,46 @@ EXEC_QUERY:
 		return nil, err
 	}
 
-	defer rows.Close() // nolint: errcheck,gosec
 	var data = make([]*model.Updates, 0, 16)
 
 	for rows.Next() {
 		var (
 			stamp int64
 			up    = new(model.Updates)
-			upstr string
 		)
 
-		if err = rows.Scan(&up.ID, &up.DevID, &stamp, &upstr); err != nil {
+		if err = rows.Scan(&up.ID, &up.DevID, &stamp); err != nil {
+			rows.Close() // nolint: errcheck
 			var ex = fmt.Errorf("Failed to scan row: %w", err)
 			db.log.Printf("[ERROR] %s\n", ex.Error())
 			return nil, ex
-		} else if err = json.Unmarshal([]byte(upstr), &up.AvailableUpdates); err != nil {
-			var ex = fmt.Errorf("Failed to parse AvailableUpdates from JSON: %w\n\n%s",
-				err,
-				upstr)
-			db.log.Printf("[ERROR] %s\n", ex.Error())
-			return nil, ex
 		}
 
 		up.Timestamp = time.Unix(stamp, 0)
 		data = append(data, up)
 	}
 
+	rows.Close() // nolint: errcheck
+
+	for _, up := range data {
+		if err = db.PackageUpdateGetBySet(up); err != nil {
+			return nil, err
+		}
+	}
+
 	return data, nil
 } // func (db *Database) UpdatesGetRecent() ([]*model.Updates, error)
 
-func (db *Database) DiskFreeAdd(dev *model.Device, free *model.DiskFree) error {
-	const qid query.ID = query.InfoAdd
+// PackageUpdateAdd adds a single package update to the Database.
+// The UpdID field must refer to the set of Updates it belongs to.
+func (db *Database) PackageUpdateAdd(pkg *model.PackageUpdate) error {
+	const qid query.ID = query.PackageUpdateAdd
 	var (
 		err  error
 		stmt *sql.Stmt
 	)
 
-	if dev.ID != free.DevID {
-		return fmt.Errorf("DiskFree info does not belong to Device %s",
-			dev.Name)
-	} else if stmt, err = db.getQuery(qid); err != nil {
+	if stmt, err = db.getQuery(qid); err != nil {
 		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
 			qid,
 			err.Error())
@@ -1712,28 +1785,25 @@ func (db *Database) DiskFreeAdd(dev *model.Device, free *model.DiskFree) error {
 		stmt = db.tx.Stmt(stmt)
 	}
 
-	var (
-		rows    *sql.Rows
-		freeStr string
-		buf     []byte
-	)
-
-	if buf, err = json.Marshal(free.PercentFree); err != nil {
-		db.log.Printf("[ERROR] Failed to serialize free disk space: %s\n\n%d\n\n",
-			err.Error(),
-			free.PercentFree)
-	}
-
-	freeStr = string(buf)
+	var rows *sql.Rows
 
 EXEC_QUERY:
-	if rows, err = stmt.Query(free.DevID, free.Timestamp.Unix(), info.DiskFree, freeStr); err != nil {
+	if rows, err = stmt.Query(
+		pkg.UpdID,
+		pkg.Name,
+		pkg.CurrentVersion,
+		pkg.NewVersion,
+		pkg.Repo,
+		pkg.Arch,
+		pkg.Security,
+		pkg.Source); err != nil {
 		if worthARetry(err) {
 			waitForRetry()
 			goto EXEC_QUERY
 		} else {
-			err = fmt.Errorf("Cannot add Uptime for Device %s: %w",
-				dev.Name,
+			err = fmt.Errorf("Cannot add update of package %s for set %d: %w",
+				pkg.Name,
+				pkg.UpdID,
 				err)
 			db.log.Printf("[ERROR] %s\n", err.Error())
 			return err
@@ -1749,19 +1819,81 @@ EXEC_QUERY:
 				qid)
 			return fmt.Errorf("Query %s did not return a value", qid)
 		} else if err = rows.Scan(&id); err != nil {
-			var ex = fmt.Errorf("Failed to get ID for newly added Uptime: %w",
+			var ex = fmt.Errorf("Failed to get ID for newly added PackageUpdate: %w",
 				err)
 			db.log.Printf("[ERROR] %s\n", ex.Error())
 			return ex
 		}
 
-		free.ID = id
+		pkg.ID = id
 		return nil
 	}
-} // func (db *Database) DiskFreeAdd(dev *model.Device, free *model.DiskFree) error
+} // func (db *Database) PackageUpdateAdd(pkg *model.PackageUpdate) error
 
-func (db *Database) DiskFreeGet() (map[int64]*model.DiskFree, error) {
-	const qid query.ID = query.InfoGetRecent
+// PackageUpdateGetBySet loads the package updates belonging to the given
+// set of Updates.
+func (db *Database) PackageUpdateGetBySet(u *model.Updates) error {
+	const qid query.ID = query.PackageUpdateGetBySet
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(u.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+	u.AvailableUpdates = make([]*model.PackageUpdate, 0, 8)
+
+	for rows.Next() {
+		var pkg = &model.PackageUpdate{
+			UpdID: u.ID,
+			DevID: u.DevID,
+		}
+
+		if err = rows.Scan(
+			&pkg.ID,
+			&pkg.Name,
+			&pkg.CurrentVersion,
+			&pkg.NewVersion,
+			&pkg.Repo,
+			&pkg.Arch,
+			&pkg.Security,
+			&pkg.Source); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return ex
+		}
+
+		u.AvailableUpdates = append(u.AvailableUpdates, pkg)
+	}
+
+	return nil
+} // func (db *Database) PackageUpdateGetBySet(u *model.Updates) error
+
+// PackageUpdateGetByName looks for pending updates of the package with the
+// given name in the most recent set of Updates of each Device, i.e. it answers
+// the question which Devices still need to install an update for the package.
+func (db *Database) PackageUpdateGetByName(name string) ([]*model.PackageUpdate, error) {
+	const qid query.ID = query.PackageUpdateGetByName
 	var (
 		err  error
 		stmt *sql.Stmt
@@ -1779,7 +1911,7 @@ func (db *Database) DiskFreeGet() (map[int64]*model.DiskFree, error) {
 	var rows *sql.Rows
 
 EXEC_QUERY:
-	if rows, err = stmt.Query(info.DiskFree); err != nil {
+	if rows, err = stmt.Query(name); err != nil {
 		if worthARetry(err) {
 			waitForRetry()
 			goto EXEC_QUERY
@@ -1789,30 +1921,2336 @@ EXEC_QUERY:
 	}
 
 	defer rows.Close() // nolint: errcheck,gosec
-	var data = make(map[int64]*model.DiskFree)
+	var pkgs = make([]*model.PackageUpdate, 0, 8)
 
 	for rows.Next() {
-		var (
-			stamp   int64
-			free    *model.DiskFree = &model.DiskFree{}
-			dataStr string
-		)
+		var pkg = new(model.PackageUpdate)
 
-		if err = rows.Scan(&free.ID, &free.DevID, &stamp, &dataStr); err != nil {
+		if err = rows.Scan(
+			&pkg.ID,
+			&pkg.UpdID,
+			&pkg.DevID,
+			&pkg.Name,
+			&pkg.CurrentVersion,
+			&pkg.NewVersion,
+			&pkg.Repo,
+			&pkg.Arch,
+			&pkg.Security,
+			&pkg.Source); err != nil {
 			var ex = fmt.Errorf("Failed to scan row: %w", err)
 			db.log.Printf("[ERROR] %s\n", ex.Error())
 			return nil, ex
-		} else if err = json.Unmarshal([]byte(dataStr), &free.PercentFree); err != nil {
-			var ex = fmt.Errorf("Failed to parse free disk space from JSON: %w\n\n%s",
-				err,
-				dataStr)
-			db.log.Printf("[ERROR] %s\n", ex.Error())
-			return nil, ex
 		}
 
-		free.Timestamp = time.Unix(stamp, 0)
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+} // func (db *Database) PackageUpdateGetByName(name string) ([]*model.PackageUpdate, error)
+
+// diskFreeData is what we store in the info table for a DiskFree sample.
+type diskFreeData struct {
+	PercentFree int64
+	Mounts      []*model.Filesystem
+}
+
+// DiskFreeAdd stores the usage of the filesystems mounted on a Device.
+func (db *Database) DiskFreeAdd(dev *model.Device, free *model.DiskFree) error {
+	var (
+		err  error
+		data = diskFreeData{
+			PercentFree: free.PercentFree,
+			Mounts:      free.Mounts,
+		}
+	)
+
+	if dev.ID != free.DevID {
+		return fmt.Errorf("DiskFree info does not belong to Device %s",
+			dev.Name)
+	} else if free.ID, err = db.infoAdd(free.DevID, free.Timestamp, info.DiskFree, &data); err != nil {
+		return err
+	}
+
+	return nil
+} // func (db *Database) DiskFreeAdd(dev *model.Device, free *model.DiskFree) error
+
+// DiskFreeGet returns the most recent disk usage of all Devices, keyed by
+// their IDs.
+func (db *Database) DiskFreeGet() (map[int64]*model.DiskFree, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetRecent(info.DiskFree); err != nil {
+		return nil, err
+	}
+
+	var data = make(map[int64]*model.DiskFree, len(records))
+
+	for _, rec := range records {
+		var free *model.DiskFree
+
+		if free, err = db.diskFreeFromRecord(rec); err != nil {
+			return nil, err
+		}
+
 		data[free.DevID] = free
 	}
 
 	return data, nil
-} // func (db *Database) DiskFreeGet(dev *model.Device) (*model.DiskFree, error)
+} // func (db *Database) DiskFreeGet() (map[int64]*model.DiskFree, error)
+
+// DiskFreeGetByDevice returns the most recent disk usage of the given
+// Device, or nil if we have none.
+func (db *Database) DiskFreeGetByDevice(d *model.Device) (*model.DiskFree, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetByDevice(d.ID, info.DiskFree, 1); err != nil {
+		return nil, err
+	} else if len(records) == 0 {
+		return nil, nil
+	}
+
+	return db.diskFreeFromRecord(records[0])
+} // func (db *Database) DiskFreeGetByDevice(d *model.Device) (*model.DiskFree, error)
+
+// diskFreeFromRecord deserializes a DiskFree sample. Older samples only
+// consist of the percentage of free space on the root filesystem.
+func (db *Database) diskFreeFromRecord(rec infoRecord) (*model.DiskFree, error) {
+	var (
+		err  error
+		data diskFreeData
+		free = &model.DiskFree{
+			ID:        rec.id,
+			DevID:     rec.devID,
+			Timestamp: rec.timestamp,
+		}
+	)
+
+	if strings.HasPrefix(rec.data, "{") {
+		err = json.Unmarshal([]byte(rec.data), &data)
+	} else {
+		err = json.Unmarshal([]byte(rec.data), &data.PercentFree)
+	}
+
+	if err != nil {
+		var ex = fmt.Errorf("Failed to parse free disk space from JSON: %w\n\n%s",
+			err,
+			rec.data)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	free.PercentFree = data.PercentFree
+	free.Mounts = data.Mounts
+
+	return free, nil
+} // func (db *Database) diskFreeFromRecord(rec infoRecord) (*model.DiskFree, error)
+
+// infoRecord is a row from the info table, before its data has been
+// deserialized.
+type infoRecord struct {
+	id        int64
+	devID     int64
+	timestamp time.Time
+	data      string
+}
+
+// infoAdd serializes data to JSON and stores it in the info table.
+// It returns the ID of the new record.
+func (db *Database) infoAdd(devID int64, stamp time.Time, kind info.ID, data any) (int64, error) {
+	const qid query.ID = query.InfoAdd
+	var (
+		err  error
+		buf  []byte
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	if buf, err = json.Marshal(data); err != nil {
+		err = fmt.Errorf("Failed to serialize %s info: %w", kind, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(devID, stamp.Unix(), kind, string(buf)); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot add %s info for Device %d: %w",
+			kind,
+			devID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var id int64
+
+	if !rows.Next() {
+		// CANTHAPPEN
+		db.log.Printf("[ERROR] Query %s did not return a value\n",
+			qid)
+		return 0, fmt.Errorf("Query %s did not return a value", qid)
+	} else if err = rows.Scan(&id); err != nil {
+		err = fmt.Errorf("Failed to get ID for newly added %s info: %w",
+			kind,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	}
+
+	return id, nil
+} // func (db *Database) infoAdd(devID int64, stamp time.Time, kind info.ID, data any) (int64, error)
+
+// infoGetByDevice returns up to max records of the given kind for a Device,
+// the most recent first.
+func (db *Database) infoGetByDevice(devID int64, kind info.ID, max int64) ([]infoRecord, error) {
+	const qid query.ID = query.InfoGetByDevice
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(devID, kind, max); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var records = make([]infoRecord, 0, max)
+
+	for rows.Next() {
+		var (
+			stamp int64
+			rec   infoRecord
+		)
+
+		if err = rows.Scan(&rec.id, &stamp, &rec.data); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		rec.devID = devID
+		rec.timestamp = time.Unix(stamp, 0)
+		records = append(records, rec)
+	}
+
+	return records, nil
+} // func (db *Database) infoGetByDevice(devID int64, kind info.ID, max int64) ([]infoRecord, error)
+
+// infoGetRecent returns the most recent record of the given kind for each
+// Device that has one.
+func (db *Database) infoGetRecent(kind info.ID) ([]infoRecord, error) {
+	const qid query.ID = query.InfoGetRecent
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(kind); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var records = make([]infoRecord, 0)
+
+	for rows.Next() {
+		var (
+			stamp int64
+			rec   infoRecord
+		)
+
+		if err = rows.Scan(&rec.id, &rec.devID, &stamp, &rec.data); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		rec.timestamp = time.Unix(stamp, 0)
+		records = append(records, rec)
+	}
+
+	return records, nil
+} // func (db *Database) infoGetRecent(kind info.ID) ([]infoRecord, error)
+
+// TemperatureAdd stores a set of temperature readings.
+func (db *Database) TemperatureAdd(t *model.Temperature) error {
+	var err error
+
+	if t.ID, err = db.infoAdd(t.DevID, t.Timestamp, info.Temperature, t.Sensors); err != nil {
+		return err
+	}
+
+	return nil
+} // func (db *Database) TemperatureAdd(t *model.Temperature) error
+
+// TemperatureGetByDevice returns up to max sets of temperature readings for
+// the given Device, the most recent first.
+func (db *Database) TemperatureGetByDevice(d *model.Device, max int64) ([]*model.Temperature, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetByDevice(d.ID, info.Temperature, max); err != nil {
+		return nil, err
+	}
+
+	var temps = make([]*model.Temperature, len(records))
+
+	for i, rec := range records {
+		var t = &model.Temperature{
+			ID:        rec.id,
+			DevID:     d.ID,
+			Timestamp: rec.timestamp,
+		}
+
+		if err = json.Unmarshal([]byte(rec.data), &t.Sensors); err != nil {
+			var ex = fmt.Errorf("Failed to parse temperature readings from JSON: %w\n\n%s",
+				err,
+				rec.data)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		temps[i] = t
+	}
+
+	return temps, nil
+} // func (db *Database) TemperatureGetByDevice(d *model.Device, max int64) ([]*model.Temperature, error)
+
+// memoryData is what we store in the info table for a Memory sample.
+type memoryData struct {
+	Total     int64
+	Available int64
+	SwapTotal int64
+	SwapUsed  int64
+}
+
+// MemoryAdd stores a sample of a Device's memory usage.
+func (db *Database) MemoryAdd(m *model.Memory) error {
+	var (
+		err  error
+		data = memoryData{
+			Total:     m.Total,
+			Available: m.Available,
+			SwapTotal: m.SwapTotal,
+			SwapUsed:  m.SwapUsed,
+		}
+	)
+
+	if m.ID, err = db.infoAdd(m.DevID, m.Timestamp, info.Memory, &data); err != nil {
+		return err
+	}
+
+	return nil
+} // func (db *Database) MemoryAdd(m *model.Memory) error
+
+// MemoryGetByDevice returns up to max samples of the given Device's memory
+// usage, the most recent first.
+func (db *Database) MemoryGetByDevice(d *model.Device, max int64) ([]*model.Memory, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetByDevice(d.ID, info.Memory, max); err != nil {
+		return nil, err
+	}
+
+	var samples = make([]*model.Memory, len(records))
+
+	for i, rec := range records {
+		if samples[i], err = db.memoryFromRecord(rec); err != nil {
+			return nil, err
+		}
+	}
+
+	return samples, nil
+} // func (db *Database) MemoryGetByDevice(d *model.Device, max int64) ([]*model.Memory, error)
+
+// MemoryGetRecent returns the most recent memory usage of all Devices,
+// keyed by their IDs.
+func (db *Database) MemoryGetRecent() (map[int64]*model.Memory, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetRecent(info.Memory); err != nil {
+		return nil, err
+	}
+
+	var samples = make(map[int64]*model.Memory, len(records))
+
+	for _, rec := range records {
+		var m *model.Memory
+
+		if m, err = db.memoryFromRecord(rec); err != nil {
+			return nil, err
+		}
+
+		samples[m.DevID] = m
+	}
+
+	return samples, nil
+} // func (db *Database) MemoryGetRecent() (map[int64]*model.Memory, error)
+
+func (db *Database) memoryFromRecord(rec infoRecord) (*model.Memory, error) {
+	var data memoryData
+
+	if err := json.Unmarshal([]byte(rec.data), &data); err != nil {
+		var ex = fmt.Errorf("Failed to parse memory usage from JSON: %w\n\n%s",
+			err,
+			rec.data)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	return &model.Memory{
+		ID:        rec.id,
+		DevID:     rec.devID,
+		Timestamp: rec.timestamp,
+		Total:     data.Total,
+		Available: data.Available,
+		SwapTotal: data.SwapTotal,
+		SwapUsed:  data.SwapUsed,
+	}, nil
+} // func (db *Database) memoryFromRecord(rec infoRecord) (*model.Memory, error)
+
+// PoolStatusAdd stores the state of a Device's storage pools.
+func (db *Database) PoolStatusAdd(ps *model.PoolStatus) error {
+	var err error
+
+	if ps.ID, err = db.infoAdd(ps.DevID, ps.Timestamp, info.Pools, ps.Pools); err != nil {
+		return err
+	}
+
+	return nil
+} // func (db *Database) PoolStatusAdd(ps *model.PoolStatus) error
+
+// PoolStatusGetByDevice returns the most recent state of the given Device's
+// storage pools, or nil if we have never checked.
+func (db *Database) PoolStatusGetByDevice(d *model.Device) (*model.PoolStatus, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetByDevice(d.ID, info.Pools, 1); err != nil {
+		return nil, err
+	} else if len(records) == 0 {
+		return nil, nil
+	}
+
+	return db.poolStatusFromRecord(records[0])
+} // func (db *Database) PoolStatusGetByDevice(d *model.Device) (*model.PoolStatus, error)
+
+// PoolStatusGetRecent returns the most recent state of the storage pools of
+// all Devices, keyed by their IDs.
+func (db *Database) PoolStatusGetRecent() (map[int64]*model.PoolStatus, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetRecent(info.Pools); err != nil {
+		return nil, err
+	}
+
+	var status = make(map[int64]*model.PoolStatus, len(records))
+
+	for _, rec := range records {
+		var ps *model.PoolStatus
+
+		if ps, err = db.poolStatusFromRecord(rec); err != nil {
+			return nil, err
+		}
+
+		status[ps.DevID] = ps
+	}
+
+	return status, nil
+} // func (db *Database) PoolStatusGetRecent() (map[int64]*model.PoolStatus, error)
+
+func (db *Database) poolStatusFromRecord(rec infoRecord) (*model.PoolStatus, error) {
+	var ps = &model.PoolStatus{
+		ID:        rec.id,
+		DevID:     rec.devID,
+		Timestamp: rec.timestamp,
+	}
+
+	if err := json.Unmarshal([]byte(rec.data), &ps.Pools); err != nil {
+		var ex = fmt.Errorf("Failed to parse pool status from JSON: %w\n\n%s",
+			err,
+			rec.data)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	return ps, nil
+} // func (db *Database) poolStatusFromRecord(rec infoRecord) (*model.PoolStatus, error)
+
+// inventoryData is what we store in the info table for an Inventory.
+type inventoryData struct {
+	Kernel    string
+	OSVersion string
+	Arch      string
+	CPUModel  string
+	CPUCores  int64
+	RAM       int64
+	Virt      string
+	Vendor    string
+	Product   string
+}
+
+// InventoryAdd stores a new version of a Device's Inventory.
+func (db *Database) InventoryAdd(inv *model.Inventory) error {
+	var (
+		err  error
+		data = inventoryData{
+			Kernel:    inv.Kernel,
+			OSVersion: inv.OSVersion,
+			Arch:      inv.Arch,
+			CPUModel:  inv.CPUModel,
+			CPUCores:  inv.CPUCores,
+			RAM:       inv.RAM,
+			Virt:      inv.Virt,
+			Vendor:    inv.Vendor,
+			Product:   inv.Product,
+		}
+	)
+
+	if inv.ID, err = db.infoAdd(inv.DevID, inv.Timestamp, info.Inventory, &data); err != nil {
+		return err
+	}
+
+	return nil
+} // func (db *Database) InventoryAdd(inv *model.Inventory) error
+
+// InventoryGetByDevice returns up to max versions of the given Device's
+// Inventory, the most recent first.
+func (db *Database) InventoryGetByDevice(d *model.Device, max int64) ([]*model.Inventory, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetByDevice(d.ID, info.Inventory, max); err != nil {
+		return nil, err
+	}
+
+	var versions = make([]*model.Inventory, len(records))
+
+	for i, rec := range records {
+		var data inventoryData
+
+		if err = json.Unmarshal([]byte(rec.data), &data); err != nil {
+			var ex = fmt.Errorf("Failed to parse inventory from JSON: %w\n\n%s",
+				err,
+				rec.data)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		versions[i] = &model.Inventory{
+			ID:        rec.id,
+			DevID:     rec.devID,
+			Timestamp: rec.timestamp,
+			Kernel:    data.Kernel,
+			OSVersion: data.OSVersion,
+			Arch:      data.Arch,
+			CPUModel:  data.CPUModel,
+			CPUCores:  data.CPUCores,
+			RAM:       data.RAM,
+			Virt:      data.Virt,
+			Vendor:    data.Vendor,
+			Product:   data.Product,
+		}
+	}
+
+	return versions, nil
+} // func (db *Database) InventoryGetByDevice(d *model.Device, max int64) ([]*model.Inventory, error)
+
+// PortsAdd stores the set of ports a Device listens on.
+func (db *Database) PortsAdd(p *model.Ports) error {
+	var err error
+
+	if p.ID, err = db.infoAdd(p.DevID, p.Timestamp, info.Ports, p.Listeners); err != nil {
+		return err
+	}
+
+	return nil
+} // func (db *Database) PortsAdd(p *model.Ports) error
+
+// PortsGetByDevice returns the most recent set of ports the given Device
+// listens on, or nil if we have never checked.
+func (db *Database) PortsGetByDevice(d *model.Device) (*model.Ports, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetByDevice(d.ID, info.Ports, 1); err != nil {
+		return nil, err
+	} else if len(records) == 0 {
+		return nil, nil
+	}
+
+	var p = &model.Ports{
+		ID:        records[0].id,
+		DevID:     d.ID,
+		Timestamp: records[0].timestamp,
+	}
+
+	if err = json.Unmarshal([]byte(records[0].data), &p.Listeners); err != nil {
+		var ex = fmt.Errorf("Failed to parse listening ports from JSON: %w\n\n%s",
+			err,
+			records[0].data)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	return p, nil
+} // func (db *Database) PortsGetByDevice(d *model.Device) (*model.Ports, error)
+
+// GuestsAdd stores the containers, VMs and jails found on a Device.
+func (db *Database) GuestsAdd(g *model.Guests) error {
+	var err error
+
+	if g.ID, err = db.infoAdd(g.DevID, g.Timestamp, info.Guests, g.Guests); err != nil {
+		return err
+	}
+
+	return nil
+} // func (db *Database) GuestsAdd(g *model.Guests) error
+
+// GuestsGetByDevice returns the most recent set of guests found on the
+// given Device, or nil if we have never checked.
+func (db *Database) GuestsGetByDevice(d *model.Device) (*model.Guests, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetByDevice(d.ID, info.Guests, 1); err != nil {
+		return nil, err
+	} else if len(records) == 0 {
+		return nil, nil
+	}
+
+	return db.guestsFromRecord(records[0])
+} // func (db *Database) GuestsGetByDevice(d *model.Device) (*model.Guests, error)
+
+// GuestsGetRecent returns the most recent set of guests of all Devices,
+// keyed by the IDs of their hosts.
+func (db *Database) GuestsGetRecent() (map[int64]*model.Guests, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetRecent(info.Guests); err != nil {
+		return nil, err
+	}
+
+	var guests = make(map[int64]*model.Guests, len(records))
+
+	for _, rec := range records {
+		var g *model.Guests
+
+		if g, err = db.guestsFromRecord(rec); err != nil {
+			return nil, err
+		}
+
+		guests[g.DevID] = g
+	}
+
+	return guests, nil
+} // func (db *Database) GuestsGetRecent() (map[int64]*model.Guests, error)
+
+func (db *Database) guestsFromRecord(rec infoRecord) (*model.Guests, error) {
+	var g = &model.Guests{
+		ID:        rec.id,
+		DevID:     rec.devID,
+		Timestamp: rec.timestamp,
+	}
+
+	if err := json.Unmarshal([]byte(rec.data), &g.Guests); err != nil {
+		var ex = fmt.Errorf("Failed to parse guests from JSON: %w\n\n%s",
+			err,
+			rec.data)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	return g, nil
+} // func (db *Database) guestsFromRecord(rec infoRecord) (*model.Guests, error)
+
+// InfoTypeRegister returns the info type used to store the results of the
+// custom probe of the given name, creating it if it does not exist, yet.
+func (db *Database) InfoTypeRegister(name string) (info.ID, error) {
+	const qid query.ID = query.InfoTypeRegister
+	var (
+		err  error
+		id   info.ID
+		ok   bool
+		stmt *sql.Stmt
+	)
+
+	if id, ok = info.Lookup(name); ok {
+		return id, nil
+	} else if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(name); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot register info type %q: %w",
+			name,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var num int64
+
+	if !rows.Next() {
+		// CANTHAPPEN
+		db.log.Printf("[ERROR] Query %s did not return a value\n",
+			qid)
+		return 0, fmt.Errorf("Query %s did not return a value", qid)
+	} else if err = rows.Scan(&num); err != nil {
+		err = fmt.Errorf("Failed to get ID of info type %q: %w",
+			name,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	} else if num < 1 || num > int64(math.MaxUint8-info.CustomBase)+1 {
+		err = fmt.Errorf("Too many custom info types, cannot register %q (ID %d)",
+			name,
+			num)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	}
+
+	id = info.CustomBase + info.ID(num-1)
+	info.Register(name, id)
+
+	return id, nil
+} // func (db *Database) InfoTypeRegister(name string) (info.ID, error)
+
+// CustomValueAdd stores the result of a custom probe.
+func (db *Database) CustomValueAdd(v *model.CustomValue) error {
+	var (
+		err  error
+		kind info.ID
+	)
+
+	if kind, err = db.InfoTypeRegister(v.Probe); err != nil {
+		return err
+	} else if v.ID, err = db.infoAdd(v.DevID, v.Timestamp, kind, v.Values); err != nil {
+		return err
+	}
+
+	return nil
+} // func (db *Database) CustomValueAdd(v *model.CustomValue) error
+
+// CustomValueGetByDevice returns up to max results of the custom probe of
+// the given name for a Device, the most recent first.
+func (db *Database) CustomValueGetByDevice(d *model.Device, probe string, max int64) ([]*model.CustomValue, error) {
+	var (
+		err     error
+		kind    info.ID
+		records []infoRecord
+	)
+
+	if kind, err = db.InfoTypeRegister(probe); err != nil {
+		return nil, err
+	} else if records, err = db.infoGetByDevice(d.ID, kind, max); err != nil {
+		return nil, err
+	}
+
+	var values = make([]*model.CustomValue, len(records))
+
+	for i, rec := range records {
+		var v = &model.CustomValue{
+			ID:        rec.id,
+			DevID:     d.ID,
+			Timestamp: rec.timestamp,
+			Probe:     probe,
+		}
+
+		if err = json.Unmarshal([]byte(rec.data), &v.Values); err != nil {
+			var ex = fmt.Errorf("Failed to parse result of custom probe %s from JSON: %w\n\n%s",
+				probe,
+				err,
+				rec.data)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		values[i] = v
+	}
+
+	return values, nil
+} // func (db *Database) CustomValueGetByDevice(d *model.Device, probe string, max int64) ([]*model.CustomValue, error)
+
+// NeedRebootAdd records whether a Device needs to be rebooted.
+func (db *Database) NeedRebootAdd(r *model.NeedReboot) error {
+	var err error
+
+	if r.ID, err = db.infoAdd(r.DevID, r.Timestamp, info.NeedReboot, r.Required); err != nil {
+		return err
+	}
+
+	return nil
+} // func (db *Database) NeedRebootAdd(r *model.NeedReboot) error
+
+// NeedRebootGetByDevice returns the most recent reboot status of the given
+// Device, or nil if we have never checked.
+func (db *Database) NeedRebootGetByDevice(d *model.Device) (*model.NeedReboot, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetByDevice(d.ID, info.NeedReboot, 1); err != nil {
+		return nil, err
+	} else if len(records) == 0 {
+		return nil, nil
+	}
+
+	return db.needRebootFromRecord(records[0])
+} // func (db *Database) NeedRebootGetByDevice(d *model.Device) (*model.NeedReboot, error)
+
+// NeedRebootGetRecent returns the most recent reboot status of all Devices,
+// keyed by their IDs.
+func (db *Database) NeedRebootGetRecent() (map[int64]*model.NeedReboot, error) {
+	var (
+		err     error
+		records []infoRecord
+	)
+
+	if records, err = db.infoGetRecent(info.NeedReboot); err != nil {
+		return nil, err
+	}
+
+	var status = make(map[int64]*model.NeedReboot, len(records))
+
+	for _, rec := range records {
+		var r *model.NeedReboot
+
+		if r, err = db.needRebootFromRecord(rec); err != nil {
+			return nil, err
+		}
+
+		status[r.DevID] = r
+	}
+
+	return status, nil
+} // func (db *Database) NeedRebootGetRecent() (map[int64]*model.NeedReboot, error)
+
+func (db *Database) needRebootFromRecord(rec infoRecord) (*model.NeedReboot, error) {
+	var r = &model.NeedReboot{
+		ID:        rec.id,
+		DevID:     rec.devID,
+		Timestamp: rec.timestamp,
+	}
+
+	if err := json.Unmarshal([]byte(rec.data), &r.Required); err != nil {
+		var ex = fmt.Errorf("Failed to parse reboot status from JSON: %w\n\n%s",
+			err,
+			rec.data)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	return r, nil
+} // func (db *Database) needRebootFromRecord(rec infoRecord) (*model.NeedReboot, error)
+
+// EventAdd adds an Event to a Device's timeline.
+func (db *Database) EventAdd(ev *model.Event) error {
+	const qid query.ID = query.EventAdd
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(ev.DevID, ev.Timestamp.Unix(), ev.Kind, ev.Message); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot add %s event for Device %d: %w",
+			ev.Kind,
+			ev.DevID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	if !rows.Next() {
+		// CANTHAPPEN
+		db.log.Printf("[ERROR] Query %s did not return a value\n",
+			qid)
+		return fmt.Errorf("Query %s did not return a value", qid)
+	} else if err = rows.Scan(&ev.ID); err != nil {
+		var ex = fmt.Errorf("Failed to get ID for newly added Event: %w",
+			err)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return ex
+	}
+
+	return nil
+} // func (db *Database) EventAdd(ev *model.Event) error
+
+// EventGetByDevice returns up to max Events from the given Device's
+// timeline, the most recent first.
+func (db *Database) EventGetByDevice(d *model.Device, max int64) ([]*model.Event, error) {
+	const qid query.ID = query.EventGetByDevice
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(d.ID, max); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var events = make([]*model.Event, 0)
+
+	for rows.Next() {
+		var (
+			stamp int64
+			ev    = &model.Event{DevID: d.ID}
+		)
+
+		if err = rows.Scan(&ev.ID, &stamp, &ev.Kind, &ev.Message); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		ev.Timestamp = time.Unix(stamp, 0)
+		events = append(events, ev)
+	}
+
+	return events, nil
+} // func (db *Database) EventGetByDevice(d *model.Device, max int64) ([]*model.Event, error)
+
+// SmartAdd adds the SMART status of a disk to the Database.
+func (db *Database) SmartAdd(si *model.SmartInfo) error {
+	const qid query.ID = query.SmartAdd
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(
+		si.DevID,
+		si.Timestamp.Unix(),
+		si.Disk,
+		si.Model,
+		si.Serial,
+		si.Passed,
+		si.Reallocated,
+		si.PowerOnHours,
+		si.Temperature); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot add SMART status of %s for Device %d: %w",
+			si.Disk,
+			si.DevID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	if !rows.Next() {
+		// CANTHAPPEN
+		db.log.Printf("[ERROR] Query %s did not return a value\n",
+			qid)
+		return fmt.Errorf("Query %s did not return a value", qid)
+	} else if err = rows.Scan(&si.ID); err != nil {
+		var ex = fmt.Errorf("Failed to get ID for newly added SMART status: %w",
+			err)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return ex
+	}
+
+	return nil
+} // func (db *Database) SmartAdd(si *model.SmartInfo) error
+
+// SmartGetByDevice returns the most recent SMART status of each disk in the
+// given Device.
+func (db *Database) SmartGetByDevice(d *model.Device) ([]*model.SmartInfo, error) {
+	const qid query.ID = query.SmartGetByDevice
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(d.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var disks = make([]*model.SmartInfo, 0, 4)
+
+	for rows.Next() {
+		var (
+			stamp int64
+			si    = &model.SmartInfo{DevID: d.ID}
+		)
+
+		if err = rows.Scan(
+			&si.ID,
+			&stamp,
+			&si.Disk,
+			&si.Model,
+			&si.Serial,
+			&si.Passed,
+			&si.Reallocated,
+			&si.PowerOnHours,
+			&si.Temperature); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		si.Timestamp = time.Unix(stamp, 0)
+		disks = append(disks, si)
+	}
+
+	return disks, nil
+} // func (db *Database) SmartGetByDevice(d *model.Device) ([]*model.SmartInfo, error)
+
+// SmartGetFailing returns the disks whose most recent SMART self-assessment
+// failed, keyed by the ID of the Device they belong to.
+func (db *Database) SmartGetFailing() (map[int64][]*model.SmartInfo, error) {
+	const qid query.ID = query.SmartGetFailing
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var failing = make(map[int64][]*model.SmartInfo)
+
+	for rows.Next() {
+		var (
+			stamp int64
+			si    = new(model.SmartInfo)
+		)
+
+		if err = rows.Scan(
+			&si.ID,
+			&si.DevID,
+			&stamp,
+			&si.Disk,
+			&si.Model,
+			&si.Serial,
+			&si.Passed,
+			&si.Reallocated,
+			&si.PowerOnHours,
+			&si.Temperature); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		si.Timestamp = time.Unix(stamp, 0)
+		failing[si.DevID] = append(failing[si.DevID], si)
+	}
+
+	return failing, nil
+} // func (db *Database) SmartGetFailing() (map[int64][]*model.SmartInfo, error)
+
+// FailedUnitAdd records a service that has failed on a Device.
+func (db *Database) FailedUnitAdd(u *model.FailedUnit) error {
+	const qid query.ID = query.FailedUnitAdd
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(
+		u.DevID,
+		u.Name,
+		u.Description,
+		u.Since.Unix(),
+		u.LastSeen.Unix()); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot add failed unit %s of Device %d: %w",
+			u.Name,
+			u.DevID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	if !rows.Next() {
+		// CANTHAPPEN
+		db.log.Printf("[ERROR] Query %s did not return a value\n",
+			qid)
+		return fmt.Errorf("Query %s did not return a value", qid)
+	} else if err = rows.Scan(&u.ID); err != nil {
+		var ex = fmt.Errorf("Failed to get ID for newly added failed unit: %w",
+			err)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return ex
+	}
+
+	return nil
+} // func (db *Database) FailedUnitAdd(u *model.FailedUnit) error
+
+// FailedUnitGetByDevice returns the services that are currently failed on
+// the given Device, the oldest failure first.
+func (db *Database) FailedUnitGetByDevice(d *model.Device) ([]*model.FailedUnit, error) {
+	const qid query.ID = query.FailedUnitGetByDevice
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(d.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var units = make([]*model.FailedUnit, 0)
+
+	for rows.Next() {
+		var (
+			since, seen int64
+			u           = &model.FailedUnit{DevID: d.ID}
+		)
+
+		if err = rows.Scan(&u.ID, &u.Name, &u.Description, &since, &seen); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		u.Since = time.Unix(since, 0)
+		u.LastSeen = time.Unix(seen, 0)
+		units = append(units, u)
+	}
+
+	return units, nil
+} // func (db *Database) FailedUnitGetByDevice(d *model.Device) ([]*model.FailedUnit, error)
+
+// FailedUnitGetActive returns the services that are currently failed on any
+// Device, keyed by the ID of the Device.
+func (db *Database) FailedUnitGetActive() (map[int64][]*model.FailedUnit, error) {
+	const qid query.ID = query.FailedUnitGetActive
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var units = make(map[int64][]*model.FailedUnit)
+
+	for rows.Next() {
+		var (
+			since, seen int64
+			u           = new(model.FailedUnit)
+		)
+
+		if err = rows.Scan(&u.ID, &u.DevID, &u.Name, &u.Description, &since, &seen); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		u.Since = time.Unix(since, 0)
+		u.LastSeen = time.Unix(seen, 0)
+		units[u.DevID] = append(units[u.DevID], u)
+	}
+
+	return units, nil
+} // func (db *Database) FailedUnitGetActive() (map[int64][]*model.FailedUnit, error)
+
+// FailedUnitUpdateLastSeen records that a service is still failed. We also
+// update the description, in case it has changed.
+func (db *Database) FailedUnitUpdateLastSeen(u *model.FailedUnit, t time.Time) error {
+	const qid query.ID = query.FailedUnitUpdateLastSeen
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+EXEC_QUERY:
+	if _, err = stmt.Exec(t.Unix(), u.Description, u.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot update LastSeen timestamp of failed unit %s (%d): %w",
+			u.Name,
+			u.ID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	u.LastSeen = t
+	return nil
+} // func (db *Database) FailedUnitUpdateLastSeen(u *model.FailedUnit, t time.Time) error
+
+// FailedUnitClear records that a service is no longer failed.
+func (db *Database) FailedUnitClear(u *model.FailedUnit, t time.Time) error {
+	const qid query.ID = query.FailedUnitClear
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+EXEC_QUERY:
+	if _, err = stmt.Exec(t.Unix(), u.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot clear failed unit %s (%d): %w",
+			u.Name,
+			u.ID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	u.Cleared = t
+	return nil
+} // func (db *Database) FailedUnitClear(u *model.FailedUnit, t time.Time) error
+
+// InstalledPackageSet records the version of a package installed on a
+// Device, replacing any other version of it we know of.
+func (db *Database) InstalledPackageSet(pkg *model.InstalledPackage) error {
+	const qid query.ID = query.InstalledPackageSet
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(
+		pkg.DevID,
+		pkg.Name,
+		pkg.Version,
+		pkg.Arch,
+		pkg.Since.Unix()); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot set package %s %s for Device %d: %w",
+			pkg.Name,
+			pkg.Version,
+			pkg.DevID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	if !rows.Next() {
+		// CANTHAPPEN
+		db.log.Printf("[ERROR] Query %s did not return a value\n",
+			qid)
+		return fmt.Errorf("Query %s did not return a value", qid)
+	} else if err = rows.Scan(&pkg.ID); err != nil {
+		var ex = fmt.Errorf("Failed to get ID of installed package: %w",
+			err)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return ex
+	}
+
+	return nil
+} // func (db *Database) InstalledPackageSet(pkg *model.InstalledPackage) error
+
+// InstalledPackageDelete removes a package that is no longer installed.
+func (db *Database) InstalledPackageDelete(pkg *model.InstalledPackage) error {
+	const qid query.ID = query.InstalledPackageDelete
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+EXEC_QUERY:
+	if _, err = stmt.Exec(pkg.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot delete package %s (%d): %w",
+			pkg.Name,
+			pkg.ID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	return nil
+} // func (db *Database) InstalledPackageDelete(pkg *model.InstalledPackage) error
+
+// InstalledPackageGetByDevice returns the packages installed on the given Device.
+func (db *Database) InstalledPackageGetByDevice(d *model.Device) ([]*model.InstalledPackage, error) {
+	const qid query.ID = query.InstalledPackageGetByDevice
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(d.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var pkgs = make([]*model.InstalledPackage, 0, 256)
+
+	for rows.Next() {
+		var (
+			stamp int64
+			pkg   = &model.InstalledPackage{DevID: d.ID}
+		)
+
+		if err = rows.Scan(&pkg.ID, &pkg.Name, &pkg.Version, &pkg.Arch, &stamp); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		pkg.Since = time.Unix(stamp, 0)
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+} // func (db *Database) InstalledPackageGetByDevice(d *model.Device) ([]*model.InstalledPackage, error)
+
+// InstalledPackageSearch returns the packages installed on any Device whose
+// names match the given pattern. The pattern is passed to SQL's LIKE
+// operator, but we also accept * as a wildcard.
+func (db *Database) InstalledPackageSearch(pattern string) ([]*model.InstalledPackage, error) {
+	const qid query.ID = query.InstalledPackageSearch
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	pattern = strings.ReplaceAll(pattern, "*", "%")
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(pattern); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var pkgs = make([]*model.InstalledPackage, 0)
+
+	for rows.Next() {
+		var (
+			stamp int64
+			pkg   = new(model.InstalledPackage)
+		)
+
+		if err = rows.Scan(&pkg.ID, &pkg.DevID, &pkg.Name, &pkg.Version, &pkg.Arch, &stamp); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		pkg.Since = time.Unix(stamp, 0)
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+} // func (db *Database) InstalledPackageSearch(pattern string) ([]*model.InstalledPackage, error)
+
+// PackageChangeAdd records a change to the packages installed on a Device.
+func (db *Database) PackageChangeAdd(c *model.PackageChange) error {
+	const qid query.ID = query.PackageChangeAdd
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(
+		c.DevID,
+		c.Timestamp.Unix(),
+		c.Name,
+		c.Arch,
+		c.OldVersion,
+		c.NewVersion); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot add change of package %s for Device %d: %w",
+			c.Name,
+			c.DevID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	if !rows.Next() {
+		// CANTHAPPEN
+		db.log.Printf("[ERROR] Query %s did not return a value\n",
+			qid)
+		return fmt.Errorf("Query %s did not return a value", qid)
+	} else if err = rows.Scan(&c.ID); err != nil {
+		var ex = fmt.Errorf("Failed to get ID for newly added package change: %w",
+			err)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return ex
+	}
+
+	return nil
+} // func (db *Database) PackageChangeAdd(c *model.PackageChange) error
+
+// PackageChangeGetByDevice returns up to max changes to the packages
+// installed on the given Device, the most recent first.
+func (db *Database) PackageChangeGetByDevice(d *model.Device, max int64) ([]*model.PackageChange, error) {
+	const qid query.ID = query.PackageChangeGetByDevice
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(d.ID, max); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var changes = make([]*model.PackageChange, 0)
+
+	for rows.Next() {
+		var (
+			stamp int64
+			c     = &model.PackageChange{DevID: d.ID}
+		)
+
+		if err = rows.Scan(&c.ID, &stamp, &c.Name, &c.Arch, &c.OldVersion, &c.NewVersion); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		c.Timestamp = time.Unix(stamp, 0)
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+} // func (db *Database) PackageChangeGetByDevice(d *model.Device, max int64) ([]*model.PackageChange, error)
+
+// JobAdd records the start of a Job.
+func (db *Database) JobAdd(j *model.Job) error {
+	const qid query.ID = query.JobAdd
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(
+		j.DevID,
+		j.Kind,
+		j.Started.Unix()); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot add %s job for Device %d: %w",
+			j.Kind,
+			j.DevID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	if !rows.Next() {
+		// CANTHAPPEN
+		db.log.Printf("[ERROR] Query %s did not return a value\n",
+			qid)
+		return fmt.Errorf("Query %s did not return a value", qid)
+	} else if err = rows.Scan(&j.ID); err != nil {
+		var ex = fmt.Errorf("Failed to get ID for newly added job: %w",
+			err)
+		db.log.Printf("[ERROR] %s\n", ex.Error())
+		return ex
+	}
+
+	return nil
+} // func (db *Database) JobAdd(j *model.Job) error
+
+// JobFinish records the outcome of a Job.
+func (db *Database) JobFinish(j *model.Job) error {
+	const qid query.ID = query.JobFinish
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+EXEC_QUERY:
+	if _, err = stmt.Exec(
+		j.Finished.Unix(),
+		j.Status,
+		j.Error,
+		j.Output,
+		j.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		err = fmt.Errorf("Cannot finish job %d: %w",
+			j.ID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	return nil
+} // func (db *Database) JobFinish(j *model.Job) error
+
+// JobGetByDevice returns up to max Jobs run on the given Device, the most
+// recent first.
+func (db *Database) JobGetByDevice(d *model.Device, max int64) ([]*model.Job, error) {
+	const qid query.ID = query.JobGetByDevice
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(d.ID, max); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	var jobs = make([]*model.Job, 0)
+
+	for rows.Next() {
+		var (
+			started, finished int64
+			j                 = &model.Job{DevID: d.ID}
+		)
+
+		if err = rows.Scan(&j.ID, &j.Kind, &started, &finished, &j.Status, &j.Error, &j.Output); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		j.Started = time.Unix(started, 0)
+
+		if finished != 0 {
+			j.Finished = time.Unix(finished, 0)
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	return jobs, nil
+} // func (db *Database) JobGetByDevice(d *model.Device, max int64) ([]*model.Job, error)
+
+// HostKeyAdd adds an SSH host key to the Database.
+func (db *Database) HostKeyAdd(k *model.HostKey) error {
+	const qid query.ID = query.HostKeyAdd
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(
+		k.DevID,
+		k.KeyType,
+		k.Fingerprint,
+		k.Key,
+		k.FirstSeen.Unix(),
+		k.LastSeen.Unix(),
+		k.Trusted); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		} else {
+			err = fmt.Errorf("Cannot add host key %s for Device %d: %w",
+				k.Fingerprint,
+				k.DevID,
+				err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+	} else {
+		var id int64
+
+		defer rows.Close()
+
+		if !rows.Next() {
+			// CANTHAPPEN
+			db.log.Printf("[ERROR] Query %s did not return a value\n",
+				qid)
+			return fmt.Errorf("Query %s did not return a value", qid)
+		} else if err = rows.Scan(&id); err != nil {
+			var ex = fmt.Errorf("Failed to get ID for newly added host key: %w",
+				err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return ex
+		}
+
+		k.ID = id
+		return nil
+	}
+} // func (db *Database) HostKeyAdd(k *model.HostKey) error
+
+// HostKeyGetByDevice loads all host keys recorded for the given Device.
+func (db *Database) HostKeyGetByDevice(d *model.Device) ([]*model.HostKey, error) {
+	const qid query.ID = query.HostKeyGetByDevice
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(d.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+	var keys = make([]*model.HostKey, 0, 2)
+
+	for rows.Next() {
+		var (
+			first, last int64
+			k           = &model.HostKey{DevID: d.ID}
+		)
+
+		if err = rows.Scan(
+			&k.ID,
+			&k.KeyType,
+			&k.Fingerprint,
+			&k.Key,
+			&first,
+			&last,
+			&k.Trusted); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		k.FirstSeen = time.Unix(first, 0)
+		k.LastSeen = time.Unix(last, 0)
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+} // func (db *Database) HostKeyGetByDevice(d *model.Device) ([]*model.HostKey, error)
+
+// HostKeyGetByID loads a host key by its ID, if it exists.
+func (db *Database) HostKeyGetByID(id int64) (*model.HostKey, error) {
+	const qid query.ID = query.HostKeyGetByID
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(id); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	if rows.Next() {
+		var (
+			first, last int64
+			k           = &model.HostKey{ID: id}
+		)
+
+		if err = rows.Scan(
+			&k.DevID,
+			&k.KeyType,
+			&k.Fingerprint,
+			&k.Key,
+			&first,
+			&last,
+			&k.Trusted); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		k.FirstSeen = time.Unix(first, 0)
+		k.LastSeen = time.Unix(last, 0)
+		return k, nil
+	}
+
+	return nil, nil
+} // func (db *Database) HostKeyGetByID(id int64) (*model.HostKey, error)
+
+// HostKeyUpdateLastSeen sets the timestamp a host key was last presented to us.
+func (db *Database) HostKeyUpdateLastSeen(k *model.HostKey, t time.Time) error {
+	const qid query.ID = query.HostKeyUpdateLastSeen
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var (
+		res         sql.Result
+		numAffected int64
+	)
+
+EXEC_QUERY:
+	if res, err = stmt.Exec(t.Unix(), k.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		} else {
+			err = fmt.Errorf("Cannot update LastSeen timestamp of host key %s (%d): %w",
+				k.Fingerprint,
+				k.ID,
+				err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+	} else if numAffected, err = res.RowsAffected(); err != nil {
+		err = fmt.Errorf("Failed to query query result for number of affected rows: %w",
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if numAffected != 1 {
+		db.log.Printf("[ERROR] Update LastSeen timestamp of host key %s (%d) affected 0 rows\n",
+			k.Fingerprint,
+			k.ID)
+	} else {
+		k.LastSeen = t
+	}
+
+	return nil
+} // func (db *Database) HostKeyUpdateLastSeen(k *model.HostKey, t time.Time) error
+
+// HostKeySetTrusted sets the trusted flag of a host key.
+func (db *Database) HostKeySetTrusted(k *model.HostKey, trusted bool) error {
+	const qid query.ID = query.HostKeySetTrusted
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var (
+		res         sql.Result
+		numAffected int64
+	)
+
+EXEC_QUERY:
+	if res, err = stmt.Exec(trusted, k.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		} else {
+			err = fmt.Errorf("Cannot update trusted flag of host key %s (%d): %w",
+				k.Fingerprint,
+				k.ID,
+				err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+	} else if numAffected, err = res.RowsAffected(); err != nil {
+		err = fmt.Errorf("Failed to query query result for number of affected rows: %w",
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if numAffected != 1 {
+		db.log.Printf("[ERROR] Update trusted flag of host key %s (%d) affected 0 rows\n",
+			k.Fingerprint,
+			k.ID)
+	} else {
+		k.Trusted = trusted
+	}
+
+	return nil
+} // func (db *Database) HostKeySetTrusted(k *model.HostKey, trusted bool) error
+
+// HostKeyDeleteOther removes all host keys of a Device except for the given one.
+func (db *Database) HostKeyDeleteOther(k *model.HostKey) error {
+	const qid query.ID = query.HostKeyDeleteOther
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+EXEC_QUERY:
+	if _, err = stmt.Exec(k.DevID, k.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		} else {
+			err = fmt.Errorf("Cannot delete host keys other than %s for Device %d: %w",
+				k.Fingerprint,
+				k.DevID,
+				err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+	}
+
+	return nil
+} // func (db *Database) HostKeyDeleteOther(k *model.HostKey) error
+
+// SSHProfileSet stores the SSH connection profile of a Device, replacing
+// the previous one, if any.
+func (db *Database) SSHProfileSet(prof *model.SSHProfile) error {
+	const qid query.ID = query.SSHProfileSet
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Failed to prepare query %s: %s\n",
+			qid,
+			err.Error())
+		panic(err)
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(
+		prof.DevID,
+		prof.User,
+		prof.Port,
+		prof.KeyFile,
+		prof.ProxyJump,
+		prof.Escalate); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		} else {
+			err = fmt.Errorf("Cannot store SSH profile for Device %d: %w",
+				prof.DevID,
+				err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+	} else {
+		var id int64
+
+		defer rows.Close()
+
+		if !rows.Next() {
+			// CANTHAPPEN
+			db.log.Printf("[ERROR] Query %s did not return a value\n",
+				qid)
+			return fmt.Errorf("Query %s did not return a value", qid)
+		} else if err = rows.Scan(&id); err != nil {
+			var ex = fmt.Errorf("Failed to get ID for SSH profile: %w",
+				err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return ex
+		}
+
+		prof.ID = id
+		return nil
+	}
+} // func (db *Database) SSHProfileSet(prof *model.SSHProfile) error
+
+// SSHProfileGetByDevice loads the SSH connection profile for the given Device.
+// If no profile has been stored, it returns nil.
+func (db *Database) SSHProfileGetByDevice(d *model.Device) (*model.SSHProfile, error) {
+	const qid query.ID = query.SSHProfileGetByDevice
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getQuery(qid); err != nil {
+		db.log.Printf("[ERROR] Cannot prepare query %s: %s\n",
+			qid,
+			err.Error())
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.Stmt(stmt)
+	}
+
+	var rows *sql.Rows
+
+EXEC_QUERY:
+	if rows, err = stmt.Query(d.ID); err != nil {
+		if worthARetry(err) {
+			waitForRetry()
+			goto EXEC_QUERY
+		}
+
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck,gosec
+
+	if rows.Next() {
+		var prof = &model.SSHProfile{DevID: d.ID}
+
+		if err = rows.Scan(
+			&prof.ID,
+			&prof.User,
+			&prof.Port,
+			&prof.KeyFile,
+			&prof.ProxyJump,
+			&prof.Escalate); err != nil {
+			var ex = fmt.Errorf("Failed to scan row: %w", err)
+			db.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+
+		return prof, nil
+	}
+
+	return nil, nil
+} // func (db *Database) SSHProfileGetByDevice(d *model.Device) (*model.SSHProfile, error)
@@ -0,0 +1,482 @@
+// /home/krylon/go/src/github.com/blicero/carebear/database/migrate.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:19:29 krylon>
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// qinit only applies to fresh databases. When the schema changes, existing
+// databases need to be brought up to date, which is what migrations are for.
+// We keep track of the migrations a database has seen in SQLite's
+// user_version. A fresh database starts out with all of them applied.
+//
+// Databases created by development versions of carebear may have some of
+// the changes already, so migrations need to check before they act.
+
+// migration updates the schema of a database created by an earlier
+// version of carebear.
+type migration struct {
+	desc string
+	run  func(tx *sql.Tx) error
+}
+
+var migrations = []migration{
+	{
+		desc: "Add OS ID and family to device",
+		run: func(tx *sql.Tx) error {
+			return addColumns(tx, "device",
+				"os_id TEXT NOT NULL DEFAULT ''",
+				"os_like TEXT NOT NULL DEFAULT ''")
+		},
+	},
+	{
+		desc: "Store pending updates as rows in package_update",
+		run:  migratePackageUpdates,
+	},
+	{
+		desc: "Add host_key",
+		run: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`
+CREATE TABLE IF NOT EXISTS host_key (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    key_type TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    key TEXT NOT NULL,
+    first_seen INTEGER NOT NULL,
+    last_seen INTEGER NOT NULL,
+    trusted INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (dev_id, fingerprint),
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+				"CREATE INDEX IF NOT EXISTS hk_dev_idx ON host_key (dev_id)")
+		},
+	},
+	{
+		desc: "Add ssh_profile",
+		run: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`
+CREATE TABLE IF NOT EXISTS ssh_profile (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER UNIQUE NOT NULL,
+    user TEXT NOT NULL DEFAULT '',
+    port INTEGER NOT NULL DEFAULT 0,
+    key_file TEXT NOT NULL DEFAULT '',
+    proxy_jump TEXT NOT NULL DEFAULT '',
+    CHECK (port BETWEEN 0 AND 65535),
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`)
+		},
+	},
+	{
+		desc: "Add event",
+		run: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`
+CREATE TABLE IF NOT EXISTS event (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    timestamp INTEGER NOT NULL,
+    kind INTEGER NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+				"CREATE INDEX IF NOT EXISTS ev_dev_idx ON event (dev_id)",
+				"CREATE INDEX IF NOT EXISTS ev_time_idx ON event (timestamp)")
+		},
+	},
+	{
+		desc: "Add smart",
+		run: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`
+CREATE TABLE IF NOT EXISTS smart (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    timestamp INTEGER NOT NULL,
+    disk TEXT NOT NULL,
+    model TEXT NOT NULL DEFAULT '',
+    serial TEXT NOT NULL DEFAULT '',
+    passed INTEGER NOT NULL,
+    reallocated INTEGER NOT NULL DEFAULT -1,
+    power_on_hours INTEGER NOT NULL DEFAULT -1,
+    temperature INTEGER NOT NULL DEFAULT -1,
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+				"CREATE INDEX IF NOT EXISTS smart_dev_idx ON smart (dev_id)",
+				"CREATE INDEX IF NOT EXISTS smart_time_idx ON smart (timestamp)")
+		},
+	},
+	{
+		desc: "Add failed_unit",
+		run: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`
+CREATE TABLE IF NOT EXISTS failed_unit (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    since INTEGER NOT NULL,
+    last_seen INTEGER NOT NULL,
+    cleared INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+				"CREATE INDEX IF NOT EXISTS fu_dev_idx ON failed_unit (dev_id, cleared)")
+		},
+	},
+	{
+		desc: "Add installed_package and package_change",
+		run: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`
+CREATE TABLE IF NOT EXISTS installed_package (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    arch TEXT NOT NULL DEFAULT '',
+    since INTEGER NOT NULL,
+    UNIQUE (dev_id, name, arch, version),
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+				"CREATE INDEX IF NOT EXISTS ip_name_idx ON installed_package (name)",
+				`
+CREATE TABLE IF NOT EXISTS package_change (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    timestamp INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    arch TEXT NOT NULL DEFAULT '',
+    old_version TEXT NOT NULL DEFAULT '',
+    new_version TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+				"CREATE INDEX IF NOT EXISTS pc_dev_idx ON package_change (dev_id, timestamp)")
+		},
+	},
+	{
+		desc: "Add job",
+		run: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`
+CREATE TABLE IF NOT EXISTS job (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    kind INTEGER NOT NULL,
+    started INTEGER NOT NULL,
+    finished INTEGER,
+    status INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    output TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+				"CREATE INDEX IF NOT EXISTS job_dev_idx ON job (dev_id, started)")
+		},
+	},
+	{
+		desc: "Add critical to device",
+		run: func(tx *sql.Tx) error {
+			return addColumns(tx, "device",
+				"critical INTEGER NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		desc: "Add info_type",
+		run: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`
+CREATE TABLE IF NOT EXISTS info_type (
+    id INTEGER PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL
+) STRICT
+`)
+		},
+	},
+	{
+		desc: "Add escalate to ssh_profile",
+		run: func(tx *sql.Tx) error {
+			return addColumns(tx, "ssh_profile",
+				"escalate TEXT NOT NULL DEFAULT '' CHECK (escalate IN ('', 'none', 'sudo', 'doas'))")
+		},
+	},
+	{
+		desc: "Add source to package_update",
+		run: func(tx *sql.Tx) error {
+			return addColumns(tx, "package_update",
+				"source TEXT NOT NULL DEFAULT ''")
+		},
+	},
+	{
+		desc: "Allow several versions of a package in installed_package",
+		run:  migrateInstalledPackageVersions,
+	},
+}
+
+// migrate applies the migrations the database has not seen, yet, each one
+// in a transaction of its own.
+func (db *Database) migrate() error {
+	var (
+		err     error
+		version int
+	)
+
+	if err = db.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.log.Printf("[ERROR] Cannot query schema version of %s: %s\n",
+			db.path,
+			err.Error())
+		return err
+	}
+
+	for version < len(migrations) {
+		var (
+			tx *sql.Tx
+			m  = &migrations[version]
+		)
+
+		version++
+
+		db.log.Printf("[INFO] Migrate database %s to version %d: %s\n",
+			db.path,
+			version,
+			m.desc)
+
+		if tx, err = db.db.Begin(); err != nil {
+			db.log.Printf("[ERROR] Cannot begin transaction: %s\n",
+				err.Error())
+			return err
+		} else if err = m.run(tx); err != nil {
+			db.log.Printf("[ERROR] Migration to version %d failed: %s\n",
+				version,
+				err.Error())
+			if rbErr := tx.Rollback(); rbErr != nil {
+				db.log.Printf("[CANTHAPPEN] Cannot rollback transaction: %s\n",
+					rbErr.Error())
+				return rbErr
+			}
+			return err
+		} else if err = setVersion(tx, version); err != nil {
+			db.log.Printf("[ERROR] Cannot set schema version to %d: %s\n",
+				version,
+				err.Error())
+			if rbErr := tx.Rollback(); rbErr != nil {
+				db.log.Printf("[CANTHAPPEN] Cannot rollback transaction: %s\n",
+					rbErr.Error())
+				return rbErr
+			}
+			return err
+		} else if err = tx.Commit(); err != nil {
+			db.log.Printf("[ERROR] Failed to commit migration to version %d: %s\n",
+				version,
+				err.Error())
+			return err
+		}
+	}
+
+	return nil
+} // func (db *Database) migrate() error
+
+// migratePackageUpdates moves the pending updates, which we used to store
+// as a JSON array of package names, into the package_update table.
+//
+// SQLite refuses to drop a column that a CHECK constraint refers to, so we
+// have to rebuild the updates table without it. Dropping the old table
+// takes its indices and trigger with it. package_update refers to updates,
+// so we only create it once the new updates table is in place, otherwise
+// dropping the old one would cascade to it.
+func migratePackageUpdates(tx *sql.Tx) error {
+	var (
+		err    error
+		exists bool
+	)
+
+	if exists, err = columnExists(tx, "updates", "updates"); err != nil {
+		return err
+	} else if exists {
+		if err = execAll(tx,
+			`
+CREATE TEMP TABLE old_package_update AS
+SELECT u.id AS upd_id, j.value AS name
+FROM updates u, json_each(u.updates) j
+`,
+			`
+CREATE TABLE updates_new (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    timestamp INTEGER NOT NULL,
+    UNIQUE (dev_id, timestamp),
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+      ON UPDATE RESTRICT
+      ON DELETE CASCADE
+) STRICT
+`,
+			"INSERT INTO updates_new (id, dev_id, timestamp) SELECT id, dev_id, timestamp FROM updates",
+			"DROP TABLE updates",
+			"ALTER TABLE updates_new RENAME TO updates",
+			"CREATE INDEX upd_dev_idx ON updates (dev_id)",
+			"CREATE INDEX upd_time_idx ON updates (timestamp)",
+			`
+CREATE TRIGGER upd_host_contact_tr
+AFTER INSERT ON updates
+BEGIN
+    UPDATE device
+    SET last_seen = NEW.timestamp
+    WHERE id = NEW.dev_id;
+END
+`); err != nil {
+			return err
+		}
+	}
+
+	if err = execAll(tx,
+		`
+CREATE TABLE IF NOT EXISTS package_update (
+    id INTEGER PRIMARY KEY,
+    upd_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    cur_version TEXT NOT NULL DEFAULT '',
+    new_version TEXT NOT NULL DEFAULT '',
+    repo TEXT NOT NULL DEFAULT '',
+    arch TEXT NOT NULL DEFAULT '',
+    security INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (upd_id) REFERENCES updates (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+		"CREATE INDEX IF NOT EXISTS pkgup_upd_idx ON package_update (upd_id)",
+		"CREATE INDEX IF NOT EXISTS pkgup_name_idx ON package_update (name)"); err != nil {
+		return err
+	} else if !exists {
+		return nil
+	}
+
+	return execAll(tx,
+		"INSERT INTO package_update (upd_id, name) SELECT upd_id, name FROM old_package_update",
+		"DROP TABLE old_package_update")
+} // func migratePackageUpdates(tx *sql.Tx) error
+
+// migrateInstalledPackageVersions rebuilds installed_package so its rows are
+// identified by version as well, like in qinit. SQLite cannot change the
+// constraints of a table in place.
+func migrateInstalledPackageVersions(tx *sql.Tx) error {
+	return execAll(tx,
+		`
+CREATE TABLE installed_package_new (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    arch TEXT NOT NULL DEFAULT '',
+    since INTEGER NOT NULL,
+    UNIQUE (dev_id, name, arch, version),
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+		`
+INSERT INTO installed_package_new (id, dev_id, name, version, arch, since)
+SELECT id, dev_id, name, version, arch, since FROM installed_package
+`,
+		"DROP TABLE installed_package",
+		"ALTER TABLE installed_package_new RENAME TO installed_package",
+		"CREATE INDEX ip_name_idx ON installed_package (name)")
+} // func migrateInstalledPackageVersions(tx *sql.Tx) error
+
+// setVersion stores the schema version in the database. PRAGMAs do not
+// take parameters, so we have to format the statement ourselves.
+func setVersion(tx *sql.Tx, version int) error {
+	var _, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version))
+
+	return err
+} // func setVersion(tx *sql.Tx, version int) error
+
+// columnExists returns true if the given table has a column by the given name.
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	var (
+		err error
+		cnt int
+	)
+
+	if err = tx.QueryRow(
+		"SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?",
+		table,
+		column).Scan(&cnt); err != nil {
+		return false, err
+	}
+
+	return cnt > 0, nil
+} // func columnExists(tx *sql.Tx, table, column string) (bool, error)
+
+// addColumns adds the given columns to a table, unless it has them already.
+// Each column is given as it would appear in CREATE TABLE, the name first.
+func addColumns(tx *sql.Tx, table string, columns ...string) error {
+	for _, c := range columns {
+		var (
+			err    error
+			exists bool
+			name   = strings.Fields(c)[0]
+		)
+
+		if exists, err = columnExists(tx, table, name); err != nil {
+			return err
+		} else if exists {
+			continue
+		} else if _, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, c)); err != nil {
+			return fmt.Errorf("Cannot add column %s to %s: %w",
+				name,
+				table,
+				err)
+		}
+	}
+
+	return nil
+} // func addColumns(tx *sql.Tx, table string, columns ...string) error
+
+// execAll executes the given queries in order. Queries that create tables,
+// indices or triggers should say IF NOT EXISTS.
+func execAll(tx *sql.Tx, queries ...string) error {
+	for _, q := range queries {
+		if _, err := tx.Exec(q); err != nil {
+			return fmt.Errorf("Cannot execute migration query: %w\n%s",
+				err,
+				q)
+		}
+	}
+
+	return nil
+} // func execAll(tx *sql.Tx, queries ...string) error
@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 04. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-09-06 15:23:25 krylon>
+// Time-stamp: <2026-10-16 18:25:55 krylon>
 
 package database
 
@@ -51,7 +51,8 @@ INSERT INTO device (name, net_id, addr, bighead)
 RETURNING id
 `,
 	query.DeviceUpdateLastSeen: "UPDATE device SET last_seen = ? WHERE id = ?",
-	query.DeviceUpdateOS:       "UPDATE device SET os = ? WHERE id = ?",
+	query.DeviceUpdateOS:       "UPDATE device SET os = ?, os_id = ?, os_like = ? WHERE id = ?",
+	query.DeviceSetCritical:    "UPDATE device SET critical = ? WHERE id = ?",
 	query.DeviceGetAll: `
 SELECT
     id,
@@ -59,7 +60,10 @@ SELECT
     name,
     addr,
     os,
+    os_id,
+    os_like,
     bighead,
+    critical,
     last_seen
 FROM device
 ORDER BY name
@@ -70,7 +74,10 @@ SELECT
     name,
     addr,
     os,
+    os_id,
+    os_like,
     bighead,
+    critical,
     last_seen
 FROM device
 WHERE id = ?
@@ -81,7 +88,10 @@ SELECT
     net_id,
     addr,
     os,
+    os_id,
+    os_like,
     bighead,
+    critical,
     last_seen
 FROM device
 WHERE name = ?
@@ -92,7 +102,10 @@ SELECT
     name,
     addr,
     os,
+    os_id,
+    os_like,
     bighead,
+    critical,
     last_seen
 FROM device
 WHERE net_id = ?
@@ -117,6 +130,7 @@ WHERE id = ?
 SELECT
     id,
     timestamp,
+    uptime,
     load1,
     load5,
     load15
@@ -139,15 +153,14 @@ WHERE timestamp BETWEEN ? AND ?
 ORDER BY timestamp DESC
 `,
 	query.UpdatesAdd: `
-INSERT INTO updates (dev_id, timestamp, updates)
-             VALUES (     ?,         ?,       ?)
+INSERT INTO updates (dev_id, timestamp)
+             VALUES (     ?,         ?)
 RETURNING id
 `,
 	query.UpdatesGetByDevice: `
 SELECT
     id,
-    timestamp,
-    updates
+    timestamp
 FROM updates
 WHERE dev_id = ?
 ORDER BY timestamp DESC
@@ -159,7 +172,6 @@ WITH recent AS (
         id,
         dev_id,
         timestamp,
-        updates,
         ROW_NUMBER() OVER (PARTITION BY dev_id ORDER BY timestamp DESC) AS update_no
     FROM updates
 )
@@ -167,10 +179,53 @@ WITH recent AS (
 SELECT
     id,
     dev_id,
-    timestamp,
-    updates
+    timestamp
 FROM recent WHERE update_no = 1
 ORDER BY timestamp DESC
+`,
+	query.PackageUpdateAdd: `
+INSERT INTO package_update (upd_id, name, cur_version, new_version, repo, arch, security, source)
+                    VALUES (     ?,    ?,           ?,           ?,    ?,    ?,        ?,      ?)
+RETURNING id
+`,
+	query.PackageUpdateGetBySet: `
+SELECT
+    id,
+    name,
+    cur_version,
+    new_version,
+    repo,
+    arch,
+    security,
+    source
+FROM package_update
+WHERE upd_id = ?
+ORDER BY source, name
+`,
+	query.PackageUpdateGetByName: `
+WITH recent AS (
+    SELECT
+        id,
+        dev_id,
+        ROW_NUMBER() OVER (PARTITION BY dev_id ORDER BY timestamp DESC) AS update_no
+    FROM updates
+)
+
+SELECT
+    p.id,
+    p.upd_id,
+    r.dev_id,
+    p.name,
+    p.cur_version,
+    p.new_version,
+    p.repo,
+    p.arch,
+    p.security,
+    p.source
+FROM package_update p
+INNER JOIN recent r ON p.upd_id = r.id
+WHERE r.update_no = 1 AND p.name = ?
+ORDER BY r.dev_id
 `,
 	query.InfoAdd: `
 INSERT INTO info (dev_id, timestamp, info_type, data)
@@ -195,5 +250,244 @@ SELECT
     data
 FROM recent
 WHERE info_no = 1 AND info_type = ?
+`,
+	query.InfoGetByDevice: `
+SELECT
+    id,
+    timestamp,
+    data
+FROM info
+WHERE dev_id = ? AND info_type = ?
+ORDER BY timestamp DESC
+LIMIT ?
+`,
+	query.InfoTypeRegister: `
+INSERT INTO info_type (name) VALUES (?)
+ON CONFLICT (name) DO UPDATE SET name = excluded.name
+RETURNING id
+`,
+	query.EventAdd: `
+INSERT INTO event (dev_id, timestamp, kind, message)
+           VALUES (     ?,         ?,    ?,       ?)
+RETURNING id
+`,
+	query.EventGetByDevice: `
+SELECT
+    id,
+    timestamp,
+    kind,
+    message
+FROM event
+WHERE dev_id = ?
+ORDER BY timestamp DESC
+LIMIT ?
+`,
+	query.SmartAdd: `
+INSERT INTO smart (dev_id, timestamp, disk, model, serial, passed, reallocated, power_on_hours, temperature)
+           VALUES (     ?,         ?,    ?,     ?,      ?,      ?,           ?,              ?,           ?)
+RETURNING id
+`,
+	query.SmartGetByDevice: `
+WITH latest AS (
+    SELECT
+        dev_id,
+        MAX(timestamp) AS timestamp
+    FROM smart
+    WHERE dev_id = ?
+    GROUP BY dev_id
+)
+
+SELECT
+    s.id,
+    s.timestamp,
+    s.disk,
+    s.model,
+    s.serial,
+    s.passed,
+    s.reallocated,
+    s.power_on_hours,
+    s.temperature
+FROM smart s
+INNER JOIN latest l ON s.dev_id = l.dev_id AND s.timestamp = l.timestamp
+ORDER BY s.disk
+`,
+	query.SmartGetFailing: `
+WITH latest AS (
+    SELECT
+        dev_id,
+        MAX(timestamp) AS timestamp
+    FROM smart
+    GROUP BY dev_id
+)
+
+SELECT
+    s.id,
+    s.dev_id,
+    s.timestamp,
+    s.disk,
+    s.model,
+    s.serial,
+    s.passed,
+    s.reallocated,
+    s.power_on_hours,
+    s.temperature
+FROM smart s
+INNER JOIN latest l ON s.dev_id = l.dev_id AND s.timestamp = l.timestamp
+WHERE s.passed = 0
+ORDER BY s.dev_id, s.disk
+`,
+	query.FailedUnitAdd: `
+INSERT INTO failed_unit (dev_id, name, description, since, last_seen)
+                 VALUES (     ?,    ?,           ?,     ?,         ?)
+RETURNING id
+`,
+	query.FailedUnitGetByDevice: `
+SELECT
+    id,
+    name,
+    description,
+    since,
+    last_seen
+FROM failed_unit
+WHERE dev_id = ? AND cleared = 0
+ORDER BY since
+`,
+	query.FailedUnitGetActive: `
+SELECT
+    id,
+    dev_id,
+    name,
+    description,
+    since,
+    last_seen
+FROM failed_unit
+WHERE cleared = 0
+ORDER BY dev_id, since
+`,
+	query.FailedUnitUpdateLastSeen: "UPDATE failed_unit SET last_seen = ?, description = ? WHERE id = ?",
+	query.FailedUnitClear:          "UPDATE failed_unit SET cleared = ? WHERE id = ?",
+	query.InstalledPackageSet: `
+INSERT INTO installed_package (dev_id, name, version, arch, since)
+                       VALUES (     ?,    ?,       ?,    ?,     ?)
+ON CONFLICT (dev_id, name, arch, version) DO UPDATE
+    SET since = installed_package.since
+RETURNING id
+`,
+	query.InstalledPackageDelete: "DELETE FROM installed_package WHERE id = ?",
+	query.InstalledPackageGetByDevice: `
+SELECT
+    id,
+    name,
+    version,
+    arch,
+    since
+FROM installed_package
+WHERE dev_id = ?
+ORDER BY name, arch, version
+`,
+	query.InstalledPackageSearch: `
+SELECT
+    id,
+    dev_id,
+    name,
+    version,
+    arch,
+    since
+FROM installed_package
+WHERE name LIKE ?
+ORDER BY name, dev_id
+`,
+	query.PackageChangeAdd: `
+INSERT INTO package_change (dev_id, timestamp, name, arch, old_version, new_version)
+                    VALUES (     ?,         ?,    ?,    ?,           ?,           ?)
+RETURNING id
+`,
+	query.PackageChangeGetByDevice: `
+SELECT
+    id,
+    timestamp,
+    name,
+    arch,
+    old_version,
+    new_version
+FROM package_change
+WHERE dev_id = ?
+ORDER BY timestamp DESC, name
+LIMIT ?
+`,
+	query.JobAdd: `
+INSERT INTO job (dev_id, kind, started)
+         VALUES (     ?,    ?,       ?)
+RETURNING id
+`,
+	query.JobFinish: "UPDATE job SET finished = ?, status = ?, error = ?, output = ? WHERE id = ?",
+	query.JobGetByDevice: `
+SELECT
+    id,
+    kind,
+    started,
+    COALESCE(finished, 0),
+    status,
+    error,
+    output
+FROM job
+WHERE dev_id = ?
+ORDER BY started DESC
+LIMIT ?
+`,
+	query.HostKeyAdd: `
+INSERT INTO host_key (dev_id, key_type, fingerprint, key, first_seen, last_seen, trusted)
+              VALUES (     ?,        ?,           ?,   ?,          ?,         ?,       ?)
+RETURNING id
+`,
+	query.HostKeyGetByDevice: `
+SELECT
+    id,
+    key_type,
+    fingerprint,
+    key,
+    first_seen,
+    last_seen,
+    trusted
+FROM host_key
+WHERE dev_id = ?
+ORDER BY first_seen
+`,
+	query.HostKeyGetByID: `
+SELECT
+    dev_id,
+    key_type,
+    fingerprint,
+    key,
+    first_seen,
+    last_seen,
+    trusted
+FROM host_key
+WHERE id = ?
+`,
+	query.HostKeyUpdateLastSeen: "UPDATE host_key SET last_seen = ? WHERE id = ?",
+	query.HostKeySetTrusted:     "UPDATE host_key SET trusted = ? WHERE id = ?",
+	query.HostKeyDeleteOther:    "DELETE FROM host_key WHERE dev_id = ? AND id <> ?",
+	query.SSHProfileSet: `
+INSERT INTO ssh_profile (dev_id, user, port, key_file, proxy_jump, escalate)
+                 VALUES (     ?,    ?,    ?,        ?,          ?,        ?)
+ON CONFLICT (dev_id) DO UPDATE
+    SET user = excluded.user,
+        port = excluded.port,
+        key_file = excluded.key_file,
+        proxy_jump = excluded.proxy_jump,
+        escalate = excluded.escalate
+RETURNING id
+`,
+	query.SSHProfileGetByDevice: `
+SELECT
+    id,
+    user,
+    port,
+    key_file,
+    proxy_jump,
+    escalate
+FROM ssh_profile
+WHERE dev_id = ?
 `,
 }
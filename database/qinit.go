@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 03. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-09-05 20:01:48 krylon>
+// Time-stamp: <2026-10-16 18:19:29 krylon>
 
 package database
 
@@ -26,7 +26,10 @@ CREATE TABLE device (
     name	TEXT UNIQUE NOT NULL,
     addr        TEXT NOT NULL DEFAULT '[]',
     os          TEXT NOT NULL DEFAULT '',
+    os_id       TEXT NOT NULL DEFAULT '',
+    os_like     TEXT NOT NULL DEFAULT '',
     bighead     INTEGER NOT NULL DEFAULT 1,
+    critical    INTEGER NOT NULL DEFAULT 0,
     last_seen   INTEGER NOT NULL DEFAULT 0,
     CHECK (json_valid(addr)),
     FOREIGN KEY (net_id) REFERENCES network (id)
@@ -68,9 +71,7 @@ CREATE TABLE updates (
     id INTEGER PRIMARY KEY,
     dev_id INTEGER NOT NULL,
     timestamp INTEGER NOT NULL,
-    updates TEXT NOT NULL DEFAULT '[]',
     UNIQUE (dev_id, timestamp),
-    CHECK (json_valid(updates)),
     FOREIGN KEY (dev_id) REFERENCES device (id)
       ON UPDATE RESTRICT
       ON DELETE CASCADE
@@ -88,6 +89,24 @@ BEGIN
 END
 `,
 	`
+CREATE TABLE package_update (
+    id INTEGER PRIMARY KEY,
+    upd_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    cur_version TEXT NOT NULL DEFAULT '',
+    new_version TEXT NOT NULL DEFAULT '',
+    repo TEXT NOT NULL DEFAULT '',
+    arch TEXT NOT NULL DEFAULT '',
+    security INTEGER NOT NULL DEFAULT 0,
+    source TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (upd_id) REFERENCES updates (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+	"CREATE INDEX pkgup_upd_idx ON package_update (upd_id)",
+	"CREATE INDEX pkgup_name_idx ON package_update (name)",
+	`
 CREATE TABLE info (
     id INTEGER PRIMARY KEY,
     dev_id INTEGER NOT NULL,
@@ -105,6 +124,12 @@ CREATE TABLE info (
 	"CREATE INDEX info_time_idx ON info (timestamp)",
 	"CREATE INDEX info_type_idx ON info (info_type)",
 	`
+CREATE TABLE info_type (
+    id INTEGER PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL
+) STRICT
+`,
+	`
 CREATE TRIGGER info_host_tr
 AFTER INSERT ON info
 BEGIN
@@ -112,5 +137,132 @@ BEGIN
     SET last_seen = NEW.timestamp
     WHERE id = NEW.dev_id;
 END
+`,
+	`
+CREATE TABLE event (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    timestamp INTEGER NOT NULL,
+    kind INTEGER NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+	"CREATE INDEX ev_dev_idx ON event (dev_id)",
+	"CREATE INDEX ev_time_idx ON event (timestamp)",
+	`
+CREATE TABLE host_key (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    key_type TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    key TEXT NOT NULL,
+    first_seen INTEGER NOT NULL,
+    last_seen INTEGER NOT NULL,
+    trusted INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (dev_id, fingerprint),
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+	"CREATE INDEX hk_dev_idx ON host_key (dev_id)",
+	`
+CREATE TABLE smart (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    timestamp INTEGER NOT NULL,
+    disk TEXT NOT NULL,
+    model TEXT NOT NULL DEFAULT '',
+    serial TEXT NOT NULL DEFAULT '',
+    passed INTEGER NOT NULL,
+    reallocated INTEGER NOT NULL DEFAULT -1,
+    power_on_hours INTEGER NOT NULL DEFAULT -1,
+    temperature INTEGER NOT NULL DEFAULT -1,
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+	"CREATE INDEX smart_dev_idx ON smart (dev_id)",
+	"CREATE INDEX smart_time_idx ON smart (timestamp)",
+	`
+CREATE TABLE failed_unit (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    since INTEGER NOT NULL,
+    last_seen INTEGER NOT NULL,
+    cleared INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+	"CREATE INDEX fu_dev_idx ON failed_unit (dev_id, cleared)",
+	`
+CREATE TABLE installed_package (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    arch TEXT NOT NULL DEFAULT '',
+    since INTEGER NOT NULL,
+    UNIQUE (dev_id, name, arch, version),
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+	"CREATE INDEX ip_name_idx ON installed_package (name)",
+	`
+CREATE TABLE package_change (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    timestamp INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    arch TEXT NOT NULL DEFAULT '',
+    old_version TEXT NOT NULL DEFAULT '',
+    new_version TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+	"CREATE INDEX pc_dev_idx ON package_change (dev_id, timestamp)",
+	`
+CREATE TABLE job (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER NOT NULL,
+    kind INTEGER NOT NULL,
+    started INTEGER NOT NULL,
+    finished INTEGER,
+    status INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    output TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
+`,
+	"CREATE INDEX job_dev_idx ON job (dev_id, started)",
+	`
+CREATE TABLE ssh_profile (
+    id INTEGER PRIMARY KEY,
+    dev_id INTEGER UNIQUE NOT NULL,
+    user TEXT NOT NULL DEFAULT '',
+    port INTEGER NOT NULL DEFAULT 0,
+    key_file TEXT NOT NULL DEFAULT '',
+    proxy_jump TEXT NOT NULL DEFAULT '',
+    escalate TEXT NOT NULL DEFAULT '',
+    CHECK (port BETWEEN 0 AND 65535),
+    CHECK (escalate IN ('', 'none', 'sudo', 'doas')),
+    FOREIGN KEY (dev_id) REFERENCES device (id)
+        ON UPDATE RESTRICT
+        ON DELETE CASCADE
+) STRICT
 `,
 }
@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 03. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-09-05 20:08:42 krylon>
+// Time-stamp: <2026-10-16 17:33:43 krylon>
 
 // Package query provides symbolic constants to identifiy database queries.
 package query
@@ -23,6 +23,7 @@ const (
 	DeviceAdd
 	DeviceUpdateLastSeen
 	DeviceUpdateOS
+	DeviceSetCritical
 	DeviceGetAll
 	DeviceGetByID
 	DeviceGetByName
@@ -36,6 +37,38 @@ const (
 	UpdatesAdd
 	UpdatesGetByDevice
 	UpdatesGetRecent
+	PackageUpdateAdd
+	PackageUpdateGetBySet
+	PackageUpdateGetByName
 	InfoAdd
 	InfoGetRecent
+	InfoGetByDevice
+	InfoTypeRegister
+	EventAdd
+	EventGetByDevice
+	SmartAdd
+	SmartGetByDevice
+	SmartGetFailing
+	FailedUnitAdd
+	FailedUnitGetByDevice
+	FailedUnitGetActive
+	FailedUnitUpdateLastSeen
+	FailedUnitClear
+	InstalledPackageSet
+	InstalledPackageDelete
+	InstalledPackageGetByDevice
+	InstalledPackageSearch
+	PackageChangeAdd
+	PackageChangeGetByDevice
+	JobAdd
+	JobFinish
+	JobGetByDevice
+	HostKeyAdd
+	HostKeyGetByDevice
+	HostKeyGetByID
+	HostKeyUpdateLastSeen
+	HostKeySetTrusted
+	HostKeyDeleteOther
+	SSHProfileSet
+	SSHProfileGetByDevice
 )
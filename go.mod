@@ -10,13 +10,13 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.28
 	github.com/mborgerson/GoTruncateHtml v0.0.0-20150507032438-125d9154cd1e
 	github.com/odeke-em/go-uuid v0.0.0-20151221120446-b211d769a9aa
+	github.com/pelletier/go-toml v1.9.5
 	github.com/prometheus-community/pro-bing v0.7.0
+	golang.org/x/crypto v0.40.0
 )
 
 require (
 	github.com/google/uuid v1.6.0 // indirect
-	github.com/pelletier/go-toml v1.9.5 // indirect
-	golang.org/x/crypto v0.40.0 // indirect
 	golang.org/x/net v0.41.0 // indirect
 	golang.org/x/sync v0.13.0 // indirect
 	golang.org/x/sys v0.34.0 // indirect
@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 03. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-09-05 18:30:31 krylon>
+// Time-stamp: <2026-10-16 16:53:46 krylon>
 
 package main
 
@@ -84,7 +84,7 @@ func main() {
 		addr = fmt.Sprintf("[::1]:%d", common.DefaultPort)
 	}
 
-	if sched, err = scheduler.Create(scan); err != nil {
+	if sched, err = scheduler.Create(scan, username, port); err != nil {
 		fmt.Fprintf(
 			os.Stderr,
 			"Error creating Scheduler: %s\n",
@@ -0,0 +1,22 @@
+// /home/krylon/go/src/github.com/blicero/carebear/model/event/event.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:02:19 krylon>
+
+// Package event provides symbolic constants to identify the kinds of events
+// that show up on a Device's timeline.
+package event
+
+//go:generate stringer -type=Kind
+
+// Kind represents a type of event that happened on a Device
+type Kind uint8
+
+const (
+	Reboot Kind = iota
+	PortOpened
+	Downtime
+	Shutdown
+	Timeout
+)
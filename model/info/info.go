@@ -2,12 +2,14 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 05. 09. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-09-05 19:44:35 krylon>
+// Time-stamp: <2026-10-16 17:46:10 krylon>
 
 // Package info provides symbolic constants to identify the types of information
 // queried on remote Devices.
 package info
 
+import "sync"
+
 //go:generate stringer -type=ID
 
 // ID represents a type of information gathered from a Device
@@ -18,4 +20,54 @@ const (
 	Temperature
 	NeedReboot
 	LoadAvg
+	Memory
+	Pools
+	Inventory
+	Ports
+	Guests
+)
+
+// CustomBase is the first ID we hand out to custom probes. Their IDs are
+// assigned at runtime, when the probes are registered with the database.
+const CustomBase ID = 128
+
+var (
+	lock   sync.RWMutex
+	byName = make(map[string]ID)
+	byID   = make(map[ID]string)
 )
+
+// Register remembers the ID assigned to the custom probe of the given name.
+func Register(name string, id ID) {
+	lock.Lock()
+	byName[name] = id
+	byID[id] = name
+	lock.Unlock()
+} // func Register(name string, id ID)
+
+// Lookup returns the ID of the custom probe of the given name, if it has
+// been registered.
+func Lookup(name string) (ID, bool) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	var id, ok = byName[name]
+	return id, ok
+} // func Lookup(name string) (ID, bool)
+
+// Name returns a human-readable name for the given ID, which also covers
+// custom probes.
+func Name(id ID) string {
+	if id < CustomBase {
+		return id.String()
+	}
+
+	lock.RLock()
+	defer lock.RUnlock()
+
+	if name, ok := byID[id]; ok {
+		return name
+	}
+
+	return id.String()
+} // func Name(id ID) string
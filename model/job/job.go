@@ -0,0 +1,20 @@
+// /home/krylon/go/src/github.com/blicero/carebear/model/job/job.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:29:11 krylon>
+
+// Package job provides symbolic constants to identify the kinds of actions
+// we run on Devices on behalf of the user.
+package job
+
+//go:generate stringer -type=Kind
+
+// Kind represents a type of action run on a Device
+type Kind uint8
+
+const (
+	Upgrade Kind = iota
+	Reboot
+	Shutdown
+)
@@ -2,16 +2,21 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 03. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-09-05 19:47:54 krylon>
+// Time-stamp: <2026-10-16 18:19:29 krylon>
 
 // Package model provides data types used throughout the application.
 package model
 
 import (
+	"fmt"
 	"net"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/blicero/carebear/model/event"
+	"github.com/blicero/carebear/model/job"
 	"github.com/blicero/carebear/settings"
 	"github.com/korylprince/ipnetgen"
 )
@@ -64,13 +69,23 @@ func (n *Network) Enumerate(q chan<- net.IP) error {
 // It has zero or more IP addresses, a name, and is considered a BigHead if it is a *REAL* computer,
 // which by my definition is one you can do some coding on (i.e. smartphones, tablets, smart TVs, etc.
 // are NOT BigHeads).
+//
+// OSID and OSLike hold the ID and ID_LIKE fields from /etc/os-release, which
+// we use to determine how to talk to the Device. On systems that do not have
+// /etc/os-release, OSID is derived from the kernel name.
+//
+// Critical Devices, e.g. the router or the file server, cannot be rebooted
+// or shut down from the web interface without an explicit override.
 type Device struct {
 	ID       int64
 	NetID    int64
 	Name     string
 	OS       string
+	OSID     string
+	OSLike   string
 	Addr     []net.Addr
 	BigHead  bool
+	Critical bool
 	LastSeen time.Time
 }
 
@@ -105,6 +120,156 @@ func (d *Device) DefaultAddr() string {
 	return d.Addr[0].String()
 } // func (d *Device) DefaultAddr() string
 
+// OSRelease identifies the operating system a Device runs.
+type OSRelease struct {
+	Name   string
+	ID     string
+	IDLike string
+}
+
+// Package is a software package installed on a Device.
+type Package struct {
+	Name    string
+	Version string
+	Arch    string
+}
+
+// InstalledPackage is a Package as we store it in the database. Since is the
+// time we first saw this version of it installed.
+type InstalledPackage struct {
+	Package
+	ID    int64
+	DevID int64
+	Since time.Time
+}
+
+// PackageChange records a change to the set of installed packages on a
+// Device. For newly installed packages, OldVersion is empty, for packages
+// that have been removed, NewVersion is.
+type PackageChange struct {
+	ID         int64
+	DevID      int64
+	Timestamp  time.Time
+	Name       string
+	Arch       string
+	OldVersion string
+	NewVersion string
+}
+
+// Key returns a string that identifies the Package on a Device. Some
+// systems have several versions of a package installed at once, e.g.
+// kernels on RPM-based systems or slots on Gentoo, so the version is part
+// of it.
+func (p *Package) Key() string {
+	return p.Name + "\t" + p.Arch + "\t" + p.Version
+} // func (p *Package) Key() string
+
+// DiffPackages compares the packages installed on a Device to the ones we
+// have on record. It returns the packages that are new, the ones that are
+// gone, and the changes that amounts to. If one version of a package took
+// the place of another, that is a single change from the old version to
+// the new one.
+func DiffPackages(devID int64, now time.Time, prev []*InstalledPackage, cur []*Package) ([]*Package, []*InstalledPackage, []*PackageChange) {
+	var (
+		known   = make(map[string]*InstalledPackage, len(prev))
+		seen    = make(map[string]bool, len(cur))
+		gone    = make(map[string][]*InstalledPackage)
+		paired  = make(map[*InstalledPackage]bool)
+		added   = make([]*Package, 0)
+		removed = make([]*InstalledPackage, 0)
+		changes = make([]*PackageChange, 0)
+	)
+
+	for _, p := range prev {
+		known[p.Key()] = p
+	}
+
+	for _, p := range cur {
+		if !seen[p.Key()] && known[p.Key()] == nil {
+			added = append(added, p)
+		}
+
+		seen[p.Key()] = true
+	}
+
+	for _, p := range prev {
+		if !seen[p.Key()] {
+			removed = append(removed, p)
+			gone[p.Name+"\t"+p.Arch] = append(gone[p.Name+"\t"+p.Arch], p)
+		}
+	}
+
+	for _, p := range added {
+		var (
+			key = p.Name + "\t" + p.Arch
+			c   = &PackageChange{
+				DevID:      devID,
+				Timestamp:  now,
+				Name:       p.Name,
+				Arch:       p.Arch,
+				NewVersion: p.Version,
+			}
+		)
+
+		if old := gone[key]; len(old) > 0 {
+			c.OldVersion = old[0].Version
+			paired[old[0]] = true
+			gone[key] = old[1:]
+		}
+
+		changes = append(changes, c)
+	}
+
+	for _, p := range removed {
+		if paired[p] {
+			continue
+		}
+
+		changes = append(changes, &PackageChange{
+			DevID:      devID,
+			Timestamp:  now,
+			Name:       p.Name,
+			Arch:       p.Arch,
+			OldVersion: p.Version,
+		})
+	}
+
+	return added, removed, changes
+} // func DiffPackages(devID int64, now time.Time, prev []*InstalledPackage, cur []*Package) ([]*Package, []*InstalledPackage, []*PackageChange)
+
+// Job is an action we run on a Device on behalf of the user, e.g. installing
+// pending updates. Status is the exit status of the command, Error holds
+// the reason if we could not run it at all.
+type Job struct {
+	ID       int64
+	DevID    int64
+	Kind     job.Kind
+	Started  time.Time
+	Finished time.Time
+	Status   int
+	Error    string
+	Output   string
+}
+
+// Running returns true if the Job has not finished, yet.
+func (j *Job) Running() bool {
+	return j.Finished.IsZero()
+} // func (j *Job) Running() bool
+
+// Success returns true if the Job has finished without an error.
+func (j *Job) Success() bool {
+	return !j.Running() && j.Error == "" && j.Status == 0
+} // func (j *Job) Success() bool
+
+// Duration returns how long the Job took.
+func (j *Job) Duration() time.Duration {
+	if j.Running() {
+		return time.Since(j.Started)
+	}
+
+	return j.Finished.Sub(j.Started)
+} // func (j *Job) Duration() time.Duration
+
 // Uptime captures the time a Device has been running since last reboot/power-on
 // as well as the current system load average.
 type Uptime struct {
@@ -115,12 +280,65 @@ type Uptime struct {
 	Load      [3]float64
 }
 
+// BootTime returns the point in time the Device was booted, or the zero
+// time if we do not know its uptime.
+func (u *Uptime) BootTime() time.Time {
+	if u.Uptime <= 0 {
+		return time.Time{}
+	}
+
+	return u.Timestamp.Add(-u.Uptime).Truncate(time.Second)
+} // func (u *Uptime) BootTime() time.Time
+
+// RebootedSince returns true if the Device has been rebooted since the
+// previous measurement was taken.
+//
+// We compare the boot times of both measurements rather than the uptimes,
+// because the boot time is supposed to remain constant. It does wobble a
+// little, though, depending on how exactly the OS reports it and when the
+// clock is adjusted, so small differences are ignored.
+func (u *Uptime) RebootedSince(prev *Uptime) bool {
+	const tolerance = 2 * time.Minute
+
+	if prev == nil || u.Uptime <= 0 || prev.Uptime <= 0 {
+		return false
+	}
+
+	return u.BootTime().Sub(prev.BootTime()) > tolerance
+} // func (u *Uptime) RebootedSince(prev *Uptime) bool
+
+// Event is something noteworthy that happened on a Device, e.g. a reboot.
+type Event struct {
+	ID        int64
+	DevID     int64
+	Timestamp time.Time
+	Kind      event.Kind
+	Message   string
+}
+
 // Updates is a set of available Updates on a given Device at a certain point in time.
 type Updates struct {
 	ID               int64
 	DevID            int64
 	Timestamp        time.Time
-	AvailableUpdates []string
+	AvailableUpdates []*PackageUpdate
+}
+
+// PackageUpdate is a single pending update for a package.
+// Depending on the package manager, some fields may be empty.
+// Source is the package manager that reported the update, e.g. the OS's
+// own or Flatpak.
+type PackageUpdate struct {
+	ID             int64
+	UpdID          int64
+	DevID          int64
+	Name           string
+	CurrentVersion string
+	NewVersion     string
+	Repo           string
+	Arch           string
+	Security       bool
+	Source         string
 }
 
 // UpdatesPending returns true if the list of AvailableUpdates contains at
@@ -129,6 +347,52 @@ func (up *Updates) UpdatesPending() bool {
 	return (up != nil) && (len(up.AvailableUpdates) > 0)
 } // func (up *Updates) UpdatesPending() bool
 
+// SecurityPending returns the number of pending updates that fix security issues.
+func (up *Updates) SecurityPending() int {
+	var cnt int
+
+	if up == nil {
+		return 0
+	}
+
+	for _, u := range up.AvailableUpdates {
+		if u.Security {
+			cnt++
+		}
+	}
+
+	return cnt
+} // func (up *Updates) SecurityPending() int
+
+// UpdateGroup is the part of a set of Updates that comes from the same source.
+type UpdateGroup struct {
+	Source  string
+	Updates []*PackageUpdate
+}
+
+// BySource groups the available updates by their source, in the order the
+// sources first appear.
+func (up *Updates) BySource() []UpdateGroup {
+	var groups []UpdateGroup
+
+	if up == nil {
+		return nil
+	}
+
+	for _, u := range up.AvailableUpdates {
+		var idx = slices.IndexFunc(groups, func(g UpdateGroup) bool { return g.Source == u.Source })
+
+		if idx == -1 {
+			groups = append(groups, UpdateGroup{Source: u.Source})
+			idx = len(groups) - 1
+		}
+
+		groups[idx].Updates = append(groups[idx].Updates, u)
+	}
+
+	return groups
+} // func (up *Updates) BySource() []UpdateGroup
+
 // For posterity, I leave this commented out without removing it:
 // http://play.golang.org/p/m8TNTtygK0
 // func inc(ip net.IP) {
@@ -140,11 +404,424 @@ func (up *Updates) UpdatesPending() bool {
 // 	}
 // }
 
-// DiskFree captures the percentage of free disk space on the root
-// filesystem of a Device.
+// DiskFree captures the usage of the filesystems mounted on a Device.
+// PercentFree is the percentage of free space on the fullest of them.
 type DiskFree struct {
 	ID          int64
 	DevID       int64
 	Timestamp   time.Time
 	PercentFree int64
+	Mounts      []*Filesystem
+}
+
+// Filesystem describes the usage of a single mounted filesystem.
+// Sizes are in bytes.
+type Filesystem struct {
+	Mountpoint string
+	Device     string
+	FSType     string
+	Size       int64
+	Used       int64
+	Avail      int64
+	Inodes     int64
+	InodesUsed int64
+}
+
+// PercentUsed returns the percentage of used space the way df(1) computes
+// it, i.e. relative to the space available to unprivileged users, rounded up.
+func (fs *Filesystem) PercentUsed() int64 {
+	var total = fs.Used + fs.Avail
+
+	if total <= 0 {
+		return 0
+	}
+
+	return (fs.Used*100 + total - 1) / total
+} // func (fs *Filesystem) PercentUsed() int64
+
+// PercentFree returns the percentage of free space.
+func (fs *Filesystem) PercentFree() int64 {
+	return 100 - fs.PercentUsed()
+} // func (fs *Filesystem) PercentFree() int64
+
+// InodesPercentUsed returns the percentage of inodes in use.
+func (fs *Filesystem) InodesPercentUsed() int64 {
+	if fs.Inodes <= 0 {
+		return 0
+	}
+
+	return fs.InodesUsed * 100 / fs.Inodes
+} // func (fs *Filesystem) InodesPercentUsed() int64
+
+// Sensor is a single reading from a temperature sensor.
+type Sensor struct {
+	Name    string
+	Celsius float64
+}
+
+// Temperature is the set of temperature readings taken from a Device at a
+// certain point in time.
+type Temperature struct {
+	ID        int64
+	DevID     int64
+	Timestamp time.Time
+	Sensors   []Sensor
+}
+
+// Max returns the highest reading of all sensors.
+func (t *Temperature) Max() float64 {
+	var max float64
+
+	for i, s := range t.Sensors {
+		if i == 0 || s.Celsius > max {
+			max = s.Celsius
+		}
+	}
+
+	return max
+} // func (t *Temperature) Max() float64
+
+// Memory captures the usage of RAM and swap space on a Device.
+// All values are in bytes.
+type Memory struct {
+	ID        int64
+	DevID     int64
+	Timestamp time.Time
+	Total     int64
+	Available int64
+	SwapTotal int64
+	SwapUsed  int64
+}
+
+// Used returns the amount of RAM that is not available to applications.
+func (m *Memory) Used() int64 {
+	return m.Total - m.Available
+} // func (m *Memory) Used() int64
+
+// PercentUsed returns the percentage of RAM in use.
+func (m *Memory) PercentUsed() float64 {
+	if m.Total <= 0 {
+		return 0
+	}
+
+	return float64(m.Used()) * 100 / float64(m.Total)
+} // func (m *Memory) PercentUsed() float64
+
+// SwapPercentUsed returns the percentage of swap space in use.
+func (m *Memory) SwapPercentUsed() float64 {
+	if m.SwapTotal <= 0 {
+		return 0
+	}
+
+	return float64(m.SwapUsed) * 100 / float64(m.SwapTotal)
+} // func (m *Memory) SwapPercentUsed() float64
+
+// SmartInfo is the SMART health status of a single disk in a Device.
+// Disks that do not report a value leave the corresponding field at -1.
+type SmartInfo struct {
+	ID           int64
+	DevID        int64
+	Timestamp    time.Time
+	Disk         string
+	Model        string
+	Serial       string
+	Passed       bool
+	Reallocated  int64
+	PowerOnHours int64
+	Temperature  int64
+}
+
+// PoolStatus captures the state of the ZFS pools and software RAID arrays
+// on a Device.
+type PoolStatus struct {
+	ID        int64
+	DevID     int64
+	Timestamp time.Time
+	Pools     []*Pool
+}
+
+// Degraded returns the pools that are not healthy.
+func (ps *PoolStatus) Degraded() []*Pool {
+	var pools = make([]*Pool, 0)
+
+	for _, p := range ps.Pools {
+		if !p.Healthy() {
+			pools = append(pools, p)
+		}
+	}
+
+	return pools
+} // func (ps *PoolStatus) Degraded() []*Pool
+
+// Pool is a ZFS pool or an mdraid array.
+// Type is "zfs" for ZFS pools and the RAID level for mdraid arrays.
+// State uses the terminology of ZFS, i.e. a healthy pool is ONLINE.
+// Errors is the total number of read, write, checksum and data errors
+// reported by ZFS, or the number of failed disks in an mdraid array.
+// Sizes are in bytes, mdraid does not tell us how much space is used.
+type Pool struct {
+	Name      string
+	Type      string
+	State     string
+	Size      int64
+	Alloc     int64
+	Errors    int64
+	LastScrub time.Time
+	Progress  string
+}
+
+// Healthy returns true if the pool is ONLINE and has not seen any errors.
+func (p *Pool) Healthy() bool {
+	return p.State == "ONLINE" && p.Errors == 0
+} // func (p *Pool) Healthy() bool
+
+// PercentUsed returns the percentage of the pool's capacity in use.
+func (p *Pool) PercentUsed() int64 {
+	if p.Size <= 0 {
+		return 0
+	}
+
+	return p.Alloc * 100 / p.Size
+} // func (p *Pool) PercentUsed() int64
+
+// FailedUnit is a service that has failed on a Device, a systemd unit or an
+// rc script. Since is the time we first noticed the failure, LastSeen the
+// most recent time. Once the service recovers, we set Cleared.
+type FailedUnit struct {
+	ID          int64
+	DevID       int64
+	Name        string
+	Description string
+	Since       time.Time
+	LastSeen    time.Time
+	Cleared     time.Time
+}
+
+// Inventory describes the hardware and software of a Device. We only store
+// a new Inventory when something has changed, so each one is a version,
+// and Timestamp is the time we first saw it.
+type Inventory struct {
+	ID        int64
+	DevID     int64
+	Timestamp time.Time
+	Kernel    string
+	OSVersion string
+	Arch      string
+	CPUModel  string
+	CPUCores  int64
+	RAM       int64
+	Virt      string
+	Vendor    string
+	Product   string
+}
+
+// InventoryItem is a single named value of an Inventory.
+type InventoryItem struct {
+	Name  string
+	Value string
+}
+
+// InventoryChange is a value that differs between two Inventories.
+type InventoryChange struct {
+	Name string
+	Old  string
+	New  string
+}
+
+// Items returns the values of the Inventory in a fixed order, formatted for
+// display.
+func (inv *Inventory) Items() []InventoryItem {
+	return []InventoryItem{
+		{"Kernel", inv.Kernel},
+		{"OS version", inv.OSVersion},
+		{"Architecture", inv.Arch},
+		{"CPU", inv.CPUModel},
+		{"CPU cores", strconv.FormatInt(inv.CPUCores, 10)},
+		{"RAM", strconv.FormatInt(inv.RAM/(1024*1024), 10) + " MiB"},
+		{"Virtualization", inv.Virt},
+		{"Vendor", inv.Vendor},
+		{"Product", inv.Product},
+	}
+} // func (inv *Inventory) Items() []InventoryItem
+
+// Diff returns the values that have changed since the previous Inventory.
+func (inv *Inventory) Diff(prev *Inventory) []InventoryChange {
+	var (
+		cur     = inv.Items()
+		old     = prev.Items()
+		changes = make([]InventoryChange, 0)
+	)
+
+	for i, item := range cur {
+		if item.Value != old[i].Value {
+			changes = append(changes, InventoryChange{
+				Name: item.Name,
+				Old:  old[i].Value,
+				New:  item.Value,
+			})
+		}
+	}
+
+	return changes
+} // func (inv *Inventory) Diff(prev *Inventory) []InventoryChange
+
+// Ports is the set of network ports a Device listens on.
+type Ports struct {
+	ID        int64
+	DevID     int64
+	Timestamp time.Time
+	Listeners []Listener
+}
+
+// Listener is a socket listening for connections or datagrams. Process is
+// the name of the program that owns the socket, if we can tell.
+type Listener struct {
+	Proto   string
+	Addr    string
+	Port    int64
+	Process string
+}
+
+// Key returns a string that identifies the Listener, regardless of the
+// process that owns it.
+func (l *Listener) Key() string {
+	return fmt.Sprintf("%s %s:%d", l.Proto, l.Addr, l.Port)
+} // func (l *Listener) Key() string
+
+// Opened returns the Listeners that were not there in the previous set.
+func (p *Ports) Opened(prev *Ports) []Listener {
+	var (
+		known  = make(map[string]bool, len(prev.Listeners))
+		opened = make([]Listener, 0)
+	)
+
+	for _, l := range prev.Listeners {
+		known[l.Key()] = true
+	}
+
+	for _, l := range p.Listeners {
+		if !known[l.Key()] {
+			opened = append(opened, l)
+		}
+	}
+
+	return opened
+} // func (p *Ports) Opened(prev *Ports) []Listener
+
+// Guests is the set of containers, virtual machines and jails running on a
+// Device, or at least known to it.
+type Guests struct {
+	ID        int64
+	DevID     int64
+	Timestamp time.Time
+	Guests    []Guest
+}
+
+// Guest is a container, virtual machine or jail. Kind is the tool we found
+// it with, e.g. docker or libvirt. Started is zero if the guest is not
+// running, or if the tool does not tell us. If the guest is also a Device we
+// know about, DevID refers to that Device.
+type Guest struct {
+	Kind    string
+	Name    string
+	Image   string
+	State   string
+	Started time.Time
+	DevID   int64
+}
+
+// The kinds of Guests we know about, and how we spell the State of a
+// running Guest.
+const (
+	GuestDocker       = "docker"
+	GuestPodman       = "podman"
+	GuestLibvirt      = "libvirt"
+	GuestJail         = "jail"
+	GuestStateRunning = "running"
+)
+
+// Running returns true if the Guest is running.
+func (g *Guest) Running() bool {
+	return g.State == GuestStateRunning
+} // func (g *Guest) Running() bool
+
+// Find returns the Guest that is the Device with the given ID, or nil.
+func (g *Guests) Find(devID int64) *Guest {
+	for i := range g.Guests {
+		if g.Guests[i].DevID == devID {
+			return &g.Guests[i]
+		}
+	}
+
+	return nil
+} // func (g *Guests) Find(devID int64) *Guest
+
+// CustomValue is the result of running a custom probe on a Device. Values
+// maps the names the probe's parser extracted from the output to their
+// values.
+type CustomValue struct {
+	ID        int64
+	DevID     int64
+	Timestamp time.Time
+	Probe     string
+	Values    map[string]string
+}
+
+// Number returns the value with the given name as a number. ok is false
+// if there is no such value, or it is not a number.
+func (v *CustomValue) Number(key string) (float64, bool) {
+	var (
+		err error
+		str string
+		num float64
+		ok  bool
+	)
+
+	if str, ok = v.Values[key]; !ok {
+		return 0, false
+	} else if num, err = strconv.ParseFloat(strings.TrimSpace(str), 64); err != nil {
+		return 0, false
+	}
+
+	return num, true
+} // func (v *CustomValue) Number(key string) (float64, bool)
+
+// NeedReboot records whether a Device needs to be rebooted, e.g. to run
+// a freshly installed kernel.
+type NeedReboot struct {
+	ID        int64
+	DevID     int64
+	Timestamp time.Time
+	Required  bool
+}
+
+// HostKey is an SSH host key presented by a Device.
+// The first key we see for a Device is trusted automatically, any key that
+// differs from it later on is recorded, but not trusted until the user
+// accepts it explicitly.
+type HostKey struct {
+	ID          int64
+	DevID       int64
+	KeyType     string
+	Fingerprint string
+	Key         string
+	FirstSeen   time.Time
+	LastSeen    time.Time
+	Trusted     bool
+}
+
+// SSHProfile holds the parameters used to connect to a Device via SSH.
+// Fields that are left empty (or zero) fall back to the defaults
+// passed on the command line.
+//
+// Escalate is how we run commands that need root privileges on the Device,
+// if empty, we use the method configured for its OS, or the global one.
+type SSHProfile struct {
+	ID        int64
+	DevID     int64
+	User      string
+	Port      int
+	KeyFile   string
+	ProxyJump string
+	Escalate  string
 }
@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 10. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-07-11 15:17:27 krylon>
+// Time-stamp: <2026-10-16 18:19:29 krylon>
 
 package model
 
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net"
 	"testing"
+	"time"
 )
 
 const taddr = "192.168.42.0/24"
@@ -67,3 +68,152 @@ func TestEnumerate(t *testing.T) {
 		}
 	}
 }
+
+func TestRebootedSince(t *testing.T) {
+	var (
+		now  = time.Now()
+		prev = &Uptime{Timestamp: now.Add(-time.Minute), Uptime: 48 * time.Hour}
+		same = &Uptime{Timestamp: now, Uptime: 48*time.Hour + time.Minute + 3*time.Second}
+		boot = &Uptime{Timestamp: now, Uptime: 5 * time.Minute}
+		none = &Uptime{Timestamp: now}
+	)
+
+	if same.RebootedSince(prev) {
+		t.Error("Slightly different boot times should not count as a reboot")
+	} else if !boot.RebootedSince(prev) {
+		t.Error("Failed to detect reboot")
+	} else if none.RebootedSince(prev) || boot.RebootedSince(none) {
+		t.Error("Cannot detect reboots without knowing the uptime")
+	}
+} // func TestRebootedSince(t *testing.T)
+
+func TestInventoryDiff(t *testing.T) {
+	var (
+		prev = &Inventory{
+			Kernel:    "6.8.0-44-generic",
+			OSVersion: "24.04",
+			Arch:      "x86_64",
+			CPUCores:  4,
+			RAM:       8 << 30,
+		}
+		cur = &Inventory{
+			Kernel:    "6.8.0-45-generic",
+			OSVersion: "24.04",
+			Arch:      "x86_64",
+			CPUCores:  4,
+			RAM:       16 << 30,
+		}
+		changes = cur.Diff(prev)
+	)
+
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %d: %#v", len(changes), changes)
+	} else if changes[0].Name != "Kernel" || changes[0].Old != "6.8.0-44-generic" {
+		t.Errorf("Unexpected change: %#v", changes[0])
+	} else if changes[1].Name != "RAM" || changes[1].New != "16384 MiB" {
+		t.Errorf("Unexpected change: %#v", changes[1])
+	} else if changes = cur.Diff(cur); len(changes) != 0 {
+		t.Errorf("Inventory should not differ from itself: %#v", changes)
+	}
+} // func TestInventoryDiff(t *testing.T)
+
+func TestDiffPackages(t *testing.T) {
+	var (
+		now  = time.Now()
+		prev = []*InstalledPackage{
+			{ID: 1, Package: Package{Name: "bash", Version: "5.2.26-3.fc40", Arch: "x86_64"}},
+			{ID: 2, Package: Package{Name: "kernel", Version: "6.10.3-200.fc40", Arch: "x86_64"}},
+			{ID: 3, Package: Package{Name: "kernel", Version: "6.10.6-200.fc40", Arch: "x86_64"}},
+			{ID: 4, Package: Package{Name: "nano", Version: "7.2-7.fc40", Arch: "x86_64"}},
+		}
+		// Two kernels installed, as before.
+		same = []*Package{
+			{Name: "bash", Version: "5.2.26-3.fc40", Arch: "x86_64"},
+			{Name: "kernel", Version: "6.10.3-200.fc40", Arch: "x86_64"},
+			{Name: "kernel", Version: "6.10.6-200.fc40", Arch: "x86_64"},
+			{Name: "nano", Version: "7.2-7.fc40", Arch: "x86_64"},
+		}
+		// dnf installed a new kernel and removed the oldest one.
+		cur = []*Package{
+			{Name: "bash", Version: "5.2.26-3.fc40", Arch: "x86_64"},
+			{Name: "kernel", Version: "6.10.6-200.fc40", Arch: "x86_64"},
+			{Name: "kernel", Version: "6.10.10-200.fc40", Arch: "x86_64"},
+			{Name: "kernel", Version: "6.10.10-200.fc40", Arch: "x86_64"},
+		}
+	)
+
+	if added, removed, changes := DiffPackages(1, now, prev, same); len(added) != 0 || len(removed) != 0 || len(changes) != 0 {
+		t.Fatalf("Unexpected changes with two kernels installed: %v %v %v", added, removed, changes)
+	}
+
+	var added, removed, changes = DiffPackages(1, now, prev, cur)
+
+	if len(added) != 1 || added[0].Version != "6.10.10-200.fc40" {
+		t.Errorf("Unexpected new packages: %v", added)
+	} else if len(removed) != 2 || removed[0].ID != 2 || removed[1].ID != 4 {
+		t.Errorf("Unexpected removed packages: %v", removed)
+	} else if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %d: %v", len(changes), changes)
+	} else if c := changes[0]; c.Name != "kernel" || c.OldVersion != "6.10.3-200.fc40" || c.NewVersion != "6.10.10-200.fc40" {
+		t.Errorf("Unexpected change: %#v", c)
+	} else if c = changes[1]; c.Name != "nano" || c.OldVersion != "7.2-7.fc40" || c.NewVersion != "" {
+		t.Errorf("Unexpected change: %#v", c)
+	}
+} // func TestDiffPackages(t *testing.T)
+
+func TestCompareVersions(t *testing.T) {
+	type testCase struct {
+		a, b string
+		cmp  int
+	}
+
+	var cases = []testCase{
+		{"3.0.13-0ubuntu3.4", "3.0.13-0ubuntu3.4", 0},
+		{"3.0.13-0ubuntu3.4", "3.0.13-0ubuntu3.10", -1},
+		{"3.0.2", "3.0.13", -1},
+		{"1:1.0", "2.0", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.1.1k-5.el8", "1.1.1w-1.el8", -1},
+		{"3.0.15_1,1", "3.0.15,1", 1},
+		{"3.3.2p0", "3.3.2", 1},
+		{"1.0a", "1.0+", -1},
+		{"00010", "9", 1},
+	}
+
+	for _, c := range cases {
+		var res = CompareVersions(c.a, c.b)
+
+		if (res < 0 && c.cmp >= 0) || (res > 0 && c.cmp <= 0) || (res == 0 && c.cmp != 0) {
+			t.Errorf("CompareVersions(%q, %q) = %d, expected %d",
+				c.a,
+				c.b,
+				res,
+				c.cmp)
+		}
+	}
+} // func TestCompareVersions(t *testing.T)
+
+func TestUpdatesBySource(t *testing.T) {
+	var (
+		up = &Updates{
+			AvailableUpdates: []*PackageUpdate{
+				{Name: "curl", Source: "pkg"},
+				{Name: "base", Source: "freebsd"},
+				{Name: "vim", Source: "pkg"},
+			},
+		}
+		groups = up.BySource()
+	)
+
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(groups))
+	} else if groups[0].Source != "pkg" || len(groups[0].Updates) != 2 {
+		t.Errorf("Unexpected first group: %s with %d updates",
+			groups[0].Source,
+			len(groups[0].Updates))
+	} else if groups[1].Source != "freebsd" || len(groups[1].Updates) != 1 {
+		t.Errorf("Unexpected second group: %s with %d updates",
+			groups[1].Source,
+			len(groups[1].Updates))
+	}
+} // func TestUpdatesBySource(t *testing.T)
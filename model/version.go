@@ -0,0 +1,128 @@
+// /home/krylon/go/src/github.com/blicero/carebear/model/version.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:20:56 krylon>
+
+package model
+
+import (
+	"strings"
+)
+
+// CompareVersions compares two package versions the way dpkg does, which
+// gives sensible results for the version strings of rpm, pacman and the
+// BSD package managers, too. It returns a negative number if a is older
+// than b, a positive number if it is newer, and 0 if they are equal.
+//
+// A version consists of an optional numeric epoch, followed by a colon,
+// the upstream version, and an optional revision after the last hyphen.
+func CompareVersions(a, b string) int {
+	var (
+		epochA, upA, revA = splitVersion(a)
+		epochB, upB, revB = splitVersion(b)
+	)
+
+	if c := compareDigits(epochA, epochB); c != 0 {
+		return c
+	} else if c = compareFragment(upA, upB); c != 0 {
+		return c
+	}
+
+	return compareFragment(revA, revB)
+} // func CompareVersions(a, b string) int
+
+func splitVersion(v string) (epoch, upstream, revision string) {
+	epoch = "0"
+
+	if e, rest, ok := strings.Cut(v, ":"); ok && e != "" && strings.Trim(e, "0123456789") == "" {
+		epoch = e
+		v = rest
+	}
+
+	if idx := strings.LastIndexByte(v, '-'); idx >= 0 {
+		return epoch, v[:idx], v[idx+1:]
+	}
+
+	return epoch, v, ""
+} // func splitVersion(v string) (epoch, upstream, revision string)
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+} // func isDigit(c byte) bool
+
+// charOrder returns the sort weight of a character in the non-numeric part
+// of a version. A tilde sorts before anything, even the end of the string,
+// so 1.0~rc1 is older than 1.0. Letters sort before other characters.
+func charOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case isDigit(c):
+		return 0
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+} // func charOrder(c byte) int
+
+// compareDigits compares two strings of digits numerically, without
+// converting them, so there is no risk of overflow.
+func compareDigits(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+
+	return strings.Compare(a, b)
+} // func compareDigits(a, b string) int
+
+// compareFragment compares the upstream version or the revision of two
+// versions, alternating between non-numeric and numeric parts.
+func compareFragment(a, b string) int {
+	for a != "" || b != "" {
+		// The end of the string and digits both weigh 0, anything
+		// else does not, so if the weights are equal, we are looking
+		// at the same non-numeric character in both strings.
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			var ca, cb int
+
+			if a != "" {
+				ca = charOrder(a[0])
+			}
+
+			if b != "" {
+				cb = charOrder(b[0])
+			}
+
+			if ca != cb {
+				return ca - cb
+			}
+
+			a = a[1:]
+			b = b[1:]
+		}
+
+		var i, j int
+
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+
+		if c := compareDigits(a[:i], b[:j]); c != 0 {
+			return c
+		}
+
+		a = a[i:]
+		b = b[j:]
+	}
+
+	return 0
+} // func compareFragment(a, b string) int
@@ -0,0 +1,84 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/agent.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 16:54:33 krylon>
+
+package probe
+
+import (
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// agentSigners returns the keys held by the ssh-agent listening on
+// SSH_AUTH_SOCK, if there is one.
+//
+// Like the HostKeyCallback, this is invoked while we hold the Probe's lock,
+// so we do not need to guard the connection to the agent separately.
+// If the agent has gone away in the meantime, e.g. because the user logged
+// out and back in, we try to reconnect once.
+func (p *Probe) agentSigners() []ssh.Signer {
+	var sock = os.Getenv("SSH_AUTH_SOCK")
+
+	if sock == "" {
+		return nil
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		var (
+			err     error
+			signers []ssh.Signer
+		)
+
+		if p.agentConn == nil {
+			var conn net.Conn
+
+			if conn, err = net.Dial("unix", sock); err != nil {
+				p.log.Printf("[WARN] Cannot connect to ssh-agent at %s: %s\n",
+					sock,
+					err.Error())
+				return nil
+			}
+
+			p.agentConn = conn
+			p.agent = agent.NewClient(conn)
+		}
+
+		if signers, err = p.agent.Signers(); err == nil {
+			return signers
+		}
+
+		p.log.Printf("[WARN] Failed to get keys from ssh-agent: %s\n",
+			err.Error())
+		p.agentConn.Close() // nolint: errcheck
+		p.agentConn = nil
+		p.agent = nil
+	}
+
+	return nil
+} // func (p *Probe) agentSigners() []ssh.Signer
+
+// authMethod returns an AuthMethod that offers the given keys first,
+// followed by those held by ssh-agent, followed by the keys we loaded
+// from disk.
+//
+// The ssh package only attempts each kind of AuthMethod once, so all
+// public keys need to be offered through a single AuthMethod.
+func (p *Probe) authMethod(extra ...ssh.Signer) ssh.AuthMethod {
+	return ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		var (
+			agentKeys = p.agentSigners()
+			signers   = make([]ssh.Signer, 0, len(extra)+len(agentKeys)+len(p.keys))
+		)
+
+		signers = append(signers, extra...)
+		signers = append(signers, agentKeys...)
+		signers = append(signers, p.keys...)
+
+		return signers, nil
+	})
+} // func (p *Probe) authMethod(extra ...ssh.Signer) ssh.AuthMethod
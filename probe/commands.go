@@ -2,11 +2,13 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 23. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-09-06 15:16:44 krylon>
+// Time-stamp: <2026-10-16 18:25:29 krylon>
 
 package probe
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -14,307 +16,459 @@ import (
 	"time"
 
 	"github.com/blicero/carebear/model"
+	"github.com/blicero/carebear/settings"
 	"golang.org/x/crypto/ssh"
 )
 
-// This should suffice for now, but in the long run, it might be nice to reuse the ssh.Client
+// defaultCommandTimeout is used if no timeout has been configured.
+const defaultCommandTimeout = time.Minute * 2
 
-const pkgSep = "\t"
+type cmdResult struct {
+	output []byte
+	err    error
+}
 
-func (p *Probe) executeCommand(d *model.Device, port int, cmd string) ([]string, error) {
+// commandTimeout returns the maximum amount of time we allow a single
+// command to run on a Device.
+func (p *Probe) commandTimeout() time.Duration {
+	if settings.Settings == nil || settings.Settings.CommandTimeout <= 0 {
+		return defaultCommandTimeout
+	}
+
+	return settings.Settings.CommandTimeout
+} // func (p *Probe) commandTimeout() time.Duration
+
+// rootPrefix marks commands that need root privileges. Right before we run
+// such a command, runCommand replaces the marker with sudo or doas, as
+// configured for the Device. Until then, the marker makes it easy to tell
+// in log messages which commands run as root.
+const rootPrefix = "[root] "
+
+// asRoot marks the given command as one that needs root privileges.
+func asRoot(cmd string) string {
+	return rootPrefix + cmd
+} // func asRoot(cmd string) string
+
+// Sample output:
+// sudo: a password is required
+// sudo: a terminal is required to read the password; either use the -S option to read from standard input or configure an askpass helper
+// doas: Authentication required
+// doas: Operation not permitted
+// ksh: doas: not found
+var patEscalationFailed = regexp.MustCompile(
+	`(?i)(?:^|: )(?:sudo|doas): (?:.*(?:password is required|terminal is required|not in the sudoers|is not allowed|authentication required|not permitted)|(?:command )?not found)`)
+
+// escalationMethod returns how to run commands as root on the given Device.
+// The Device's SSH profile takes precedence over the method configured for
+// its OS, which takes precedence over the global one.
+func escalationMethod(d *model.Device, prof *model.SSHProfile, cfg *settings.Options) string {
+	if prof != nil && prof.Escalate != "" {
+		return prof.Escalate
+	} else if cfg == nil {
+		return settings.EscalateNone
+	}
+
+	var ids = append([]string{strings.ToLower(d.OSID)}, strings.Fields(strings.ToLower(d.OSLike))...)
+
+	for _, id := range ids {
+		if method := cfg.EscalateOS[id]; method != "" {
+			return method
+		}
+	}
+
+	if cfg.Escalate != "" {
+		return cfg.Escalate
+	}
+
+	return settings.EscalateNone
+} // func escalationMethod(d *model.Device, prof *model.SSHProfile, cfg *settings.Options) string
+
+// escalateCmd turns a command marked by asRoot into one we can run using
+// the given method. We always run sudo and doas non-interactively, so a
+// missing rule makes the command fail rather than wait for a password.
+// Commands that are not marked are returned unchanged.
+func escalateCmd(method, cmd string) string {
+	var (
+		ok   bool
+		bare string
+	)
+
+	if bare, ok = strings.CutPrefix(cmd, rootPrefix); !ok {
+		return cmd
+	}
+
+	switch method {
+	case settings.EscalateSudo:
+		return "sudo -n " + bare
+	case settings.EscalateDoas:
+		return "doas -n " + bare
+	default:
+		return bare
+	}
+} // func escalateCmd(method, cmd string) string
+
+// escalationFailed looks for a complaint from sudo or doas in the output of
+// a command, and returns it, or an empty string if there is none.
+func escalationFailed(output []string) string {
+	for _, l := range output {
+		if patEscalationFailed.MatchString(l) {
+			return strings.TrimSpace(l)
+		}
+	}
+
+	return ""
+} // func escalationFailed(output []string) string
+
+// escalation returns how to run commands as root on the given Device. We
+// remember the Device's SSH profile when we connect, so this should be
+// called after getSession.
+func (p *Probe) escalation(d *model.Device) string {
+	p.lock.RLock()
+	var prof = p.profiles[d.ID]
+	p.lock.RUnlock()
+
+	return escalationMethod(d, prof, settings.Settings)
+} // func (p *Probe) escalation(d *model.Device) string
+
+// runCommand runs a command on the given Device and returns its output and
+// exit status. A non-zero exit status is not considered an error here, it is
+// up to the caller to decide what it means.
+//
+// Commands marked by asRoot are run via sudo or doas. If that fails, we
+// return an EscalationError, no matter what the caller thinks of the exit
+// status.
+func (p *Probe) runCommand(ctx context.Context, d *model.Device, cmd string) ([]string, int, error) {
 	var (
 		err     error
 		session *ssh.Session
+		cancel  context.CancelFunc
+		resQ    = make(chan cmdResult, 1)
+		res     cmdResult
+		status  int
+		method  string
 	)
 
+	ctx, cancel = context.WithTimeout(ctx, p.commandTimeout())
+	defer cancel()
+
 	// 05. 08. 2025
 	// I get a panic originating in NewSession when connecting to a Device that is offline.
-	if session, err = p.getSession(d, port); err != nil {
+	if session, err = p.getSession(ctx, d); err != nil {
 		if err == ErrPingOffline {
-			return nil, err
+			return nil, 0, err
 		}
 		var ex = fmt.Errorf("Failed to create SSH session for %s: %w",
 			d.Name,
 			err)
 		p.log.Printf("[ERROR] %s\n", ex.Error())
-		return nil, ex
+		return nil, 0, ex
 	}
 
 	defer session.Close()
 
-	var rawOutput []byte
-
-	if rawOutput, err = session.CombinedOutput(cmd); err != nil {
-		if strings.Contains(cmd, "dnf") && strings.HasPrefix(err.Error(), "Process exited with status 100") {
-			// dnf check-upgrade exits with status 100 if there are updates available.
-		} else if strings.Contains(cmd, "checkupdates") && strings.HasPrefix(err.Error(), "Process exited with status 2") {
-			// checkupdates on Arch exits with status 2 if no updates are available.
-			return nil, nil
-		} else if d.OS == "FreeBSD" && strings.HasPrefix(err.Error(), "Process exited with status 2") {
-			// On FreeBSD, "freebsd-update updatesready" exits with status 2 if no updates are available.
-			return nil, nil
-		} else {
+	if strings.HasPrefix(cmd, rootPrefix) {
+		method = p.escalation(d)
+		cmd = escalateCmd(method, cmd)
+	}
+
+	go func() {
+		var r cmdResult
+		r.output, r.err = session.CombinedOutput(cmd)
+		resQ <- r
+	}()
+
+	select {
+	case res = <-resQ:
+	case <-ctx.Done():
+		// Closing the session makes CombinedOutput return, so the
+		// goroutine above does not linger. The connection itself may
+		// well be wedged, too, so we do not want to reuse it.
+		session.Close() // nolint: errcheck
+		_ = p.disconnect(d)
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			var ex = &TimeoutError{
+				Device:  d.Name,
+				Command: cmd,
+				Timeout: p.commandTimeout(),
+			}
+			p.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, 0, ex
+		}
+
+		return nil, 0, fmt.Errorf("Command %q on %s was cancelled: %w",
+			cmd,
+			d.Name,
+			ctx.Err())
+	}
+
+	if res.err != nil {
+		var xerr *ssh.ExitError
+
+		if !errors.As(res.err, &xerr) {
 			var ex = fmt.Errorf("Failed to execute command on %s: %w\n>>> Command: %s",
 				d.Name,
-				err,
+				res.err,
 				cmd)
 			p.log.Printf("[ERROR] %s\n", ex.Error())
-			return nil, ex
+			return nil, 0, ex
 		}
+
+		status = xerr.ExitStatus()
 	}
 
-	var lines = strings.Split(string(rawOutput), "\n")
+	var lines = strings.Split(string(res.output), "\n")
 
-	return lines, nil
-} // func (p *Probe) executeCommand(d *model.Device, port int, cmd string) ([]string, error)
+	if status != 0 && method != "" && method != settings.EscalateNone {
+		if msg := escalationFailed(lines); msg != "" {
+			var ex = &EscalationError{
+				Device:  d.Name,
+				Command: cmd,
+				Method:  method,
+				Message: msg,
+			}
+			p.log.Printf("[ERROR] %s\n", ex.Error())
+			return lines, status, ex
+		}
+	}
 
-var patUpdateDebian = regexp.MustCompile(`^([^/]+)/(\S+)\s+(\S+)\s+(\S+)`)
+	return lines, status, nil
+} // func (p *Probe) runCommand(ctx context.Context, d *model.Device, cmd string) ([]string, int, error)
 
-// QueryUpdatesDebian asks a Debian-ish system for a list of available updates.
-func (p *Probe) QueryUpdatesDebian(d *model.Device, port int) ([]string, error) {
-	const cmd = "/usr/bin/apt list --upgradable"
+// executeCommand runs a command on the given Device and returns its output.
+// Any exit status other than 0 is treated as an error.
+func (p *Probe) executeCommand(ctx context.Context, d *model.Device, cmd string) ([]string, error) {
 	var (
-		err     error
-		output  []string
-		match   []string
-		updates []string
+		err    error
+		status int
+		output []string
 	)
 
-	if output, err = p.executeCommand(d, port, cmd); err != nil {
-		if err == ErrPingOffline {
-			return nil, err
-		}
-		_ = p.disconnect(d)
-		p.log.Printf("[ERROR] Failed to execute command %q on %s: %s\n",
-			cmd,
-			d.Name,
-			err.Error())
+	if output, status, err = p.runCommand(ctx, d, cmd); err != nil {
 		return nil, err
+	} else if status != 0 {
+		var ex = fmt.Errorf("Command on %s exited with status %d\n>>> Command: %s\n%s",
+			d.Name,
+			status,
+			cmd,
+			strings.Join(output, "\n"))
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
 	}
 
-	updates = make([]string, 0)
+	return output, nil
+} // func (p *Probe) executeCommand(ctx context.Context, d *model.Device, cmd string) ([]string, error)
 
-	for _, l := range output {
-		if match = patUpdateDebian.FindStringSubmatch(l); len(match) > 0 {
-			var upd = strings.Join(match[1:], pkgSep)
-			updates = append(updates, upd)
+// driverCommand runs one of an OSDriver's commands on the given Device and
+// lets the driver decide what the exit status means.
+// If the driver says there is nothing to report, the output is nil.
+func (p *Probe) driverCommand(ctx context.Context, d *model.Device, drv OSDriver, cmd string) ([]string, int, error) {
+	var (
+		err    error
+		status int
+		output []string
+	)
+
+	if output, status, err = p.runCommand(ctx, d, cmd); err != nil {
+		var eerr *EscalationError
+
+		// There is nothing wrong with the connection if sudo or doas
+		// refused to run the command.
+		if err != ErrPingOffline && !errors.As(err, &eerr) {
+			_ = p.disconnect(d)
 		}
+		return nil, 0, err
 	}
 
-	return updates, nil
-} // func (p *Probe) QueryUpdatesDebian(d *model.Device, port int) ([]string, error)
-
-var patUpdateSuse = regexp.MustCompile(`\s+\|\s+`)
+	switch drv.ExitStatus(cmd, status) {
+	case CmdOK:
+		return output, status, nil
+	case CmdEmpty:
+		return nil, status, nil
+	default:
+		var ex = fmt.Errorf("Command on %s (%s) exited with status %d\n>>> Command: %s\n%s",
+			d.Name,
+			drv.Family(),
+			status,
+			cmd,
+			strings.Join(output, "\n"))
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, status, ex
+	}
+} // func (p *Probe) driverCommand(ctx context.Context, d *model.Device, drv OSDriver, cmd string) ([]string, int, error)
 
-// QueryUpdatesSuse asks an openSuse system for a list of available updates.
-func (p *Probe) QueryUpdatesSuse(d *model.Device, port int) ([]string, error) {
-	const cmd = "zypper lu"
+// QueryUpdates attempts to query the given Device for available updates.
+// Besides the driver's package manager, we ask any additional update
+// sources that apply to the Device, e.g. Flatpak. If the Device's driver
+// needs a separate command to find out which of the updates fix security
+// issues, we run that, too.
+//
+// Each update is tagged with its source, for updates found by the driver,
+// that is the name of the OS family, unless the driver says otherwise.
+func (p *Probe) QueryUpdates(ctx context.Context, d *model.Device) ([]*model.PackageUpdate, error) {
 	var (
 		err     error
+		drv     OSDriver
+		sec     SecurityDriver
+		ok      bool
 		output  []string
-		updates []string
+		updates []*model.PackageUpdate
 	)
 
-	if output, err = p.executeCommand(d, port, cmd); err != nil {
-		if err == ErrPingOffline {
-			return nil, err
-		}
-		_ = p.disconnect(d)
-		p.log.Printf("[ERROR] Failed to execute command %q on %s: %s\n",
-			cmd,
+	if drv = DriverFor(d); drv == nil {
+		p.log.Printf("[TRACE] Don't know how to query %s (running %s) for updates\n",
 			d.Name,
-			err.Error())
+			d.OS)
+		return nil, ErrUnsupported
+	} else if output, _, err = p.driverCommand(ctx, d, drv, drv.UpdateCmd()); err != nil {
 		return nil, err
 	}
 
-	updates = make([]string, 0)
+	updates = tagSource(drv.ParseUpdates(output), drv.Family())
+
+	for i := range updateSources {
+		var (
+			src  = &updateSources[i]
+			more []*model.PackageUpdate
+		)
 
-	for _, l := range output[4:] {
-		l = strings.Trim(l, " \t\n")
-		var pieces = patUpdateSuse.Split(l, -1)
-		if len(pieces) > 0 {
-			var upd = strings.Join(pieces[1:], pkgSep)
-			updates = append(updates, upd)
+		if !src.Applies(d) {
+			continue
+		} else if more, err = p.querySource(ctx, d, src); err != nil {
+			p.log.Printf("[ERROR] Failed to query %s for updates from %s: %s\n",
+				d.Name,
+				src.Name,
+				err.Error())
+			continue
 		}
+
+		updates = append(updates, more...)
 	}
 
-	return updates, nil
-} // func (p *Probe) QueryUpdatesSuse(d *model.Device, port int) ([]string, error)
+	if sec, ok = drv.(SecurityDriver); !ok {
+		return updates, nil
+	} else if output, _, err = p.driverCommand(ctx, d, drv, sec.SecurityCmd()); err != nil {
+		p.log.Printf("[ERROR] Failed to query %s for security updates: %s\n",
+			d.Name,
+			err.Error())
+		return updates, nil
+	}
 
-var patUpdateDNF = regexp.MustCompile(`\s+`)
+	return mergeSecurity(updates, tagSource(sec.ParseSecurity(output), drv.Family())), nil
+} // func (p *Probe) QueryUpdates(ctx context.Context, d *model.Device) ([]*model.PackageUpdate, error)
 
-// QueryUpdatesFedora asks a Fedora system for a list of available updates.
-// Or any other system based the dnf package manager.
-func (p *Probe) QueryUpdatesFedora(d *model.Device, port int) ([]string, error) {
-	const cmd = "env DNF5_FORCE_INTERACTIVE=0 dnf check-upgrade"
+// QueryNeedReboot asks the given Device if it needs to be rebooted, e.g. to
+// activate a freshly installed kernel.
+func (p *Probe) QueryNeedReboot(ctx context.Context, d *model.Device) (bool, error) {
 	var (
-		err             error
-		output, updates []string
+		err    error
+		status int
+		drv    OSDriver
+		output []string
 	)
 
-	if output, err = p.executeCommand(d, port, cmd); err != nil {
-		if err == ErrPingOffline {
-			return nil, err
-		}
-		_ = p.disconnect(d)
-		return nil, err
-	}
-
-	updates = make([]string, 0)
-
-	for _, l := range output {
-		var pieces = patUpdateDNF.Split(l, -1)
-		if len(pieces) == 3 {
-			var upd = strings.Join(pieces, pkgSep)
-			updates = append(updates, upd)
-		}
+	if drv = DriverFor(d); drv == nil || drv.RebootCmd() == "" {
+		return false, ErrUnsupported
+	} else if output, status, err = p.driverCommand(ctx, d, drv, drv.RebootCmd()); err != nil {
+		return false, err
 	}
 
-	return updates, nil
-} // func (p *Probe) QueryUpdatesFedora(d *model.Device, port int) ([]string, error)
-
-var patUpdateArch = regexp.MustCompile(`^(\S+)\s+(\S+)\s+->\s+(\S+)$`)
+	return drv.ParseReboot(output, status), nil
+} // func (p *Probe) QueryNeedReboot(ctx context.Context, d *model.Device) (bool, error)
 
-// QueryUpdatesArch asks an Arch Linux system for a list of pending updates.
-func (p *Probe) QueryUpdatesArch(d *model.Device, port int) ([]string, error) {
-	const cmd = "checkupdates"
+// QueryPackages asks the given Device for the list of installed packages.
+func (p *Probe) QueryPackages(ctx context.Context, d *model.Device) ([]*model.Package, error) {
 	var (
-		err             error
-		output, updates []string
+		err    error
+		drv    OSDriver
+		output []string
 	)
 
-	if output, err = p.executeCommand(d, port, cmd); err != nil {
-		if err == ErrPingOffline {
-			return nil, err
-		}
-		_ = p.disconnect(d)
+	if drv = DriverFor(d); drv == nil || drv.PackageCmd() == "" {
+		return nil, ErrUnsupported
+	} else if output, _, err = p.driverCommand(ctx, d, drv, drv.PackageCmd()); err != nil {
 		return nil, err
 	}
 
-	updates = make([]string, 0)
-
-	for _, l := range output {
-		var match []string
-		if match = patUpdateArch.FindStringSubmatch(l); len(match) > 0 {
-			var upd = strings.Join(match[1:], pkgSep)
-			updates = append(updates, upd)
-		}
-	}
+	return drv.ParsePackages(output), nil
+} // func (p *Probe) QueryPackages(ctx context.Context, d *model.Device) ([]*model.Package, error)
+
+// On Linux, /proc/uptime holds the uptime in seconds, followed by the
+// idle time, /proc/loadavg starts with the three load averages.
+// On the BSDs, kern.boottime holds the boot time, FreeBSD formats it as a
+// struct, OpenBSD as plain seconds since the epoch.
+//
+// If neither works, we fall back to parsing the output of uptime(1), with
+// the locale forced to C, so we get English and decimal points.
+const (
+	uptimeCmdLinux    = "cat /proc/uptime /proc/loadavg"
+	uptimeCmdBSD      = "sysctl -n kern.boottime vm.loadavg"
+	uptimeCmdFallback = "env LC_ALL=C uptime"
+)
 
-	return updates, nil
-} // func (p *Probe) QueryUpdatesArch(d *model.Device, port int) ([]string, error)
+// Sample output:
+// 18:01:18  2 Tage  0:22 an,  2 Benutzer,  Durchschnittslast: 1,08, 0,98, 0,94
+// 6:02PM  up 56 days,  5:16, 4 users, load averages: 0.00, 0.01, 0.00
 
-var patUpdateOpenBSD = regexp.MustCompile(`\w+`)
+var (
+	uptimePat      = regexp.MustCompile(`:\s+(\d+[,.]\d+),?\s+(\d+[,.]\d+),?\s+(\d+[,.]\d+)$`)
+	uptimeDaysPat  = regexp.MustCompile(`\bup\s+(\d+)\s+days?`)
+	uptimeClockPat = regexp.MustCompile(`\bup\s+(?:\d+\s+days?,\s+)?(\d+):(\d+),`)
+	uptimeHrsPat   = regexp.MustCompile(`\bup\s+(?:\d+\s+days?,\s+)?(\d+)\s+hrs?\b`)
+	uptimeMinsPat  = regexp.MustCompile(`\bup\s+(?:\d+\s+days?,\s+)?(?:\d+\s+hrs?,\s+)?(\d+)\s+mins?\b`)
+	boottimeSecPat = regexp.MustCompile(`^(?:\{\s*sec\s*=\s*)?(\d+)`)
+	loadavgPat     = regexp.MustCompile(`^\{?\s*(\d+[.,]\d+)\s+(\d+[.,]\d+)\s+(\d+[.,]\d+)`)
+)
 
-// QueryUpdatesOpenBSD checks for available updates on OpenBSD.
-func (p *Probe) QueryUpdatesOpenBSD(d *model.Device, port int) ([]string, error) {
-	const cmd = "doas syspatch -c"
+// QueryUptime asks the given Device for its uptime and system load average.
+func (p *Probe) QueryUptime(ctx context.Context, d *model.Device) (*model.Uptime, error) {
 	var (
-		err             error
-		output, updates []string
-	)
-
-	if output, err = p.executeCommand(d, port, cmd); err != nil {
-		if err == ErrPingOffline {
-			return nil, err
-		}
-		_ = p.disconnect(d)
-		return nil, err
-	}
-
-	updates = make([]string, 0, len(output))
-
-	for _, l := range output {
-		if patUpdateOpenBSD.MatchString(l) {
-			updates = append(updates, l)
+		err    error
+		cmd    string
+		output []string
+		up     = &model.Uptime{
+			DevID: d.ID,
 		}
-	}
+	)
 
-	if len(updates) == 0 {
-		return nil, nil
+	if isBSD(d) {
+		cmd = uptimeCmdBSD
+	} else {
+		cmd = uptimeCmdLinux
 	}
 
-	return updates, nil
-} // func (p *Probe) QueryUpdatesOpenBSD(d *model.Device, port int) ([]string, error)
-
-func (p *Probe) QueryUpdatesFreeBSD(d *model.Device, port int) ([]string, error) {
-	const cmd = "doas freebsd-update updatesready"
-	var (
-		err             error
-		output, updates []string
-	)
+	up.Timestamp = time.Now()
 
-	if output, err = p.executeCommand(d, port, cmd); err != nil {
+	if output, err = p.executeCommand(ctx, d, cmd); err != nil {
 		if err == ErrPingOffline {
 			return nil, err
 		}
-		_ = p.disconnect(d)
-		return nil, err
-	}
 
-	updates = make([]string, 0, len(output))
+		p.log.Printf("[DEBUG] Failed to read uptime from %s, falling back to uptime(1): %s\n",
+			d.Name,
+			err.Error())
+	} else {
+		var uptime time.Duration
 
-	for _, l := range output {
-		if patUpdateOpenBSD.MatchString(l) {
-			updates = append(updates, l)
+		if cmd == uptimeCmdLinux {
+			uptime, up.Load, err = parseUptimeLinux(output)
+		} else if uptime, up.Load, err = parseUptimeBSD(output, up.Timestamp); err == nil && uptime <= 0 {
+			err = fmt.Errorf("boot time lies in the future: %q", output)
 		}
-	}
-
-	if len(updates) == 0 {
-		return nil, nil
-	}
 
-	return updates, nil
-} // func (p *Probe) QueryUpdatesFreeBSD(d *model.Device, port int) ([]string, error)
+		if err == nil {
+			up.Uptime = uptime
+			return up, nil
+		}
 
-// QueryUpdates attempts to query the given Device for available updates.
-func (p *Probe) QueryUpdates(d *model.Device, port int) ([]string, error) {
-	switch d.OS {
-	case "Debian GNU/Linux":
-		fallthrough
-	case "Raspbian GNU/Linux":
-		return p.QueryUpdatesDebian(d, port)
-	case "openSUSE Tumbleweed":
-		fallthrough
-	case "openSUSE Leap":
-		return p.QueryUpdatesSuse(d, port)
-	case "Fedora Linux":
-		return p.QueryUpdatesFedora(d, port)
-	case "Arch Linux":
-		return p.QueryUpdatesArch(d, port)
-	case "OpenBSD":
-		return p.QueryUpdatesOpenBSD(d, port)
-	case "FreeBSD":
-		return p.QueryUpdatesFreeBSD(d, port)
-	default:
-		p.log.Printf("[TRACE] Don't know how to query %s (running %s) for updates\n",
+		p.log.Printf("[DEBUG] Cannot parse uptime of %s, falling back to uptime(1): %s\n",
 			d.Name,
-			d.OS)
-		return nil, nil
+			err.Error())
 	}
-} // func (p *Probe) QueryUpdates(d *model.Device, port int) ([]string, error)
-
-// Sample output:
-// 18:01:18  2 Tage  0:22 an,  2 Benutzer,  Durchschnittslast: 1,08, 0,98, 0,94
-// 6:02PM  up 56 days,  5:16, 4 users, load averages: 0.00, 0.01, 0.00
 
-var uptimePat = regexp.MustCompile(
-	`:\s+(\d+[,.]\d+),?\s+(\d+[,.]\d+),?\s+(\d+[,.]\d+)$`)
+	up.Timestamp = time.Now()
 
-// QueryUptime attempts to extract the system load average from the given Device.
-func (p *Probe) QueryUptime(d *model.Device, port int) (*model.Uptime, error) {
-	const cmd = "/usr/bin/uptime"
-	var (
-		err   error
-		res   []string
-		match []string
-		up    = &model.Uptime{
-			DevID:     d.ID,
-			Timestamp: time.Now(),
-		}
-	)
-
-	if res, err = p.executeCommand(d, port, cmd); err != nil {
+	if output, err = p.executeCommand(ctx, d, uptimeCmdFallback); err != nil {
 		if err == ErrPingOffline {
 			return nil, err
 		}
@@ -323,78 +477,133 @@ func (p *Probe) QueryUptime(d *model.Device, port int) (*model.Uptime, error) {
 			err)
 		p.log.Printf("[ERROR] %s\n", ex.Error())
 		return nil, ex
-	} else if match = uptimePat.FindStringSubmatch(res[0]); match == nil {
-		var ex = fmt.Errorf("Cannot parse the output of uptime(1) from %s: %q",
+	} else if len(output) == 0 {
+		var ex = fmt.Errorf("uptime(1) on %s did not print anything", d.Name)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	} else if up.Uptime, up.Load, err = parseUptime(output[0]); err != nil {
+		var ex = fmt.Errorf("Cannot parse the output of uptime(1) from %s: %w",
 			d.Name,
-			res[0])
+			err)
 		p.log.Printf("[ERROR] %s\n", ex.Error())
 		return nil, ex
-	} else if len(match) > 0 {
-		for idx, val := range match[1:] {
-			var (
-				s = strings.ReplaceAll(val, ",", ".")
-				f float64
-			)
-
-			if f, err = strconv.ParseFloat(s, 64); err != nil {
-				var ex = fmt.Errorf("Cannot parse load avg %q: %w",
-					s,
-					err)
-				p.log.Printf("[ERROR] %s\n", ex.Error())
-				return nil, ex
-			}
+	}
+
+	return up, nil
+} // func (p *Probe) QueryUptime(ctx context.Context, d *model.Device) (*model.Uptime, error)
 
-			up.Load[idx] = f
+// parseLoad parses the three load averages, accepting both decimal points
+// and commas.
+func parseLoad(vals []string) ([3]float64, error) {
+	var (
+		err  error
+		load [3]float64
+	)
+
+	for idx, val := range vals[:3] {
+		var s = strings.ReplaceAll(val, ",", ".")
+
+		if load[idx], err = strconv.ParseFloat(s, 64); err != nil {
+			return load, fmt.Errorf("Cannot parse load avg %q: %w", s, err)
 		}
 	}
 
-	// ...
+	return load, nil
+} // func parseLoad(vals []string) ([3]float64, error)
 
-	return up, nil
-} // func (p *Probe) QueryLoadAvg(d *model.Device, port int) ([3]float64, error)
+// parseUptimeLinux parses the contents of /proc/uptime and /proc/loadavg.
+func parseUptimeLinux(output []string) (time.Duration, [3]float64, error) {
+	var (
+		err    error
+		secs   float64
+		load   [3]float64
+		lines  = nonEmpty(output)
+		fields []string
+	)
+
+	if len(lines) < 2 {
+		return 0, load, fmt.Errorf("Expected 2 lines, got %d", len(lines))
+	} else if fields = strings.Fields(lines[0]); len(fields) < 1 {
+		return 0, load, fmt.Errorf("Cannot parse /proc/uptime: %q", lines[0])
+	} else if secs, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		return 0, load, fmt.Errorf("Cannot parse /proc/uptime: %w", err)
+	} else if fields = strings.Fields(lines[1]); len(fields) < 3 {
+		return 0, load, fmt.Errorf("Cannot parse /proc/loadavg: %q", lines[1])
+	} else if load, err = parseLoad(fields); err != nil {
+		return 0, load, err
+	}
 
-var dfPat = regexp.MustCompile(`(\d+)%`)
+	return time.Duration(secs * float64(time.Second)), load, nil
+} // func parseUptimeLinux(output []string) (time.Duration, [3]float64, error)
 
-// QueryDiskFree queries a Device for the free disk space on its root filesystem.
-func (p *Probe) QueryDiskFree(d *model.Device, port int) (int64, error) {
-	const cmd = "env LC_ALL=en_EN.UTF-8 df -h /"
+// parseUptimeBSD parses the values of kern.boottime and vm.loadavg.
+// now is the time at which we queried the Device.
+func parseUptimeBSD(output []string, now time.Time) (time.Duration, [3]float64, error) {
 	var (
-		err        error
-		res, match []string
-		used, free int64
+		err   error
+		boot  int64
+		load  [3]float64
+		match []string
+		lines = nonEmpty(output)
 	)
 
-	if res, err = p.executeCommand(d, port, cmd); err != nil {
-		if err == ErrPingOffline {
-			return 0, err
-		}
-		var ex = fmt.Errorf("Failed to query free disk space on %s: %w",
-			d.Name,
-			err)
-		p.log.Printf("[ERROR] %s\n", ex.Error())
-		return 0, ex
-	} else if len(res) < 2 {
-		var ex = fmt.Errorf("Cannot parse output of \"df -h\" on %s: %s\n",
-			d.Name,
-			strings.Join(res, "\n"))
-		p.log.Printf("[ERROR] %s\n", ex.Error())
-		return 0, ex
-	} else if match = dfPat.FindStringSubmatch(res[1]); match == nil {
-		var ex = fmt.Errorf("Cannot parse output of \"df -h\" on %s: %s\n",
-			d.Name,
-			strings.Join(res, "\n"))
-		p.log.Printf("[ERROR] %s\n", ex.Error())
-		return 0, ex
-	} else if used, err = strconv.ParseInt(match[1], 10, 64); err != nil {
-		var ex = fmt.Errorf("Cannot parse free disk space on %s: %q - %w\n",
-			d.Name,
-			match[1],
-			err)
-		p.log.Printf("[ERROR] %s\n", ex.Error())
-		return 0, ex
+	if len(lines) < 2 {
+		return 0, load, fmt.Errorf("Expected 2 lines, got %d", len(lines))
+	} else if match = boottimeSecPat.FindStringSubmatch(lines[0]); match == nil {
+		return 0, load, fmt.Errorf("Cannot parse kern.boottime: %q", lines[0])
+	} else if boot, err = strconv.ParseInt(match[1], 10, 64); err != nil {
+		return 0, load, fmt.Errorf("Cannot parse kern.boottime: %w", err)
+	} else if match = loadavgPat.FindStringSubmatch(lines[1]); match == nil {
+		return 0, load, fmt.Errorf("Cannot parse vm.loadavg: %q", lines[1])
+	} else if load, err = parseLoad(match[1:]); err != nil {
+		return 0, load, err
+	}
+
+	return now.Sub(time.Unix(boot, 0)), load, nil
+} // func parseUptimeBSD(output []string, now time.Time) (time.Duration, [3]float64, error)
+
+// parseUptime parses the output of uptime(1). It understands the various
+// ways Linux and the BSDs format the uptime, e.g. "up 56 days,  5:16,",
+// "up 3 days, 2 hrs," or "up 14 mins,". If it cannot make sense of the
+// uptime, it still returns the load averages.
+func parseUptime(line string) (time.Duration, [3]float64, error) {
+	var (
+		err    error
+		uptime time.Duration
+		load   [3]float64
+		match  []string
+	)
+
+	line = strings.TrimSpace(line)
+
+	if match = uptimePat.FindStringSubmatch(line); match == nil {
+		return 0, load, fmt.Errorf("Cannot find load average in %q", line)
+	} else if load, err = parseLoad(match[1:]); err != nil {
+		return 0, load, err
 	}
 
-	free = 100 - used
+	if match = uptimeDaysPat.FindStringSubmatch(line); match != nil {
+		var days, _ = strconv.Atoi(match[1])
+		uptime += time.Duration(days) * 24 * time.Hour
+	}
+
+	if match = uptimeClockPat.FindStringSubmatch(line); match != nil {
+		var (
+			hours, _ = strconv.Atoi(match[1])
+			mins, _  = strconv.Atoi(match[2])
+		)
+		uptime += time.Duration(hours)*time.Hour + time.Duration(mins)*time.Minute
+	} else {
+		if match = uptimeHrsPat.FindStringSubmatch(line); match != nil {
+			var hours, _ = strconv.Atoi(match[1])
+			uptime += time.Duration(hours) * time.Hour
+		}
+
+		if match = uptimeMinsPat.FindStringSubmatch(line); match != nil {
+			var mins, _ = strconv.Atoi(match[1])
+			uptime += time.Duration(mins) * time.Minute
+		}
+	}
 
-	return free, nil
-} // func (p *Probe) QueryDiskFree(d *model.Device, port int) (int64, error)
+	return uptime, load, nil
+} // func parseUptime(line string) (time.Duration, [3]float64, error)
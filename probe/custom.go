@@ -0,0 +1,206 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/custom.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:33:43 krylon>
+
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/carebear/model"
+	"github.com/blicero/carebear/settings"
+)
+
+// customValueKey is the name under which we store the result of custom
+// probes that extract a single value, i.e. those using the number or json
+// parser.
+const customValueKey = "value"
+
+// Custom is a probe defined by the user in the configuration file, ready
+// to run.
+type Custom struct {
+	settings.CustomProbe
+	re   *regexp.Regexp
+	path []string
+}
+
+// NewCustom prepares the given custom probe for running. It fails if the
+// probe's pattern does not make sense for its parser.
+func NewCustom(cfg settings.CustomProbe) (*Custom, error) {
+	var (
+		err error
+		c   = &Custom{CustomProbe: cfg}
+	)
+
+	switch cfg.Parser {
+	case settings.ParserNumber:
+	case settings.ParserRegex:
+		if c.re, err = regexp.Compile(cfg.Pattern); err != nil {
+			return nil, fmt.Errorf("Cannot compile pattern of custom probe %s: %w",
+				cfg.Name,
+				err)
+		} else if !slices.ContainsFunc(c.re.SubexpNames(), nonEmptyString) {
+			return nil, fmt.Errorf("Pattern of custom probe %s has no named groups",
+				cfg.Name)
+		}
+	case settings.ParserJSON:
+		c.path = strings.Split(strings.Trim(cfg.Pattern, "."), ".")
+	default:
+		return nil, fmt.Errorf("Custom probe %s has unknown parser %q",
+			cfg.Name,
+			cfg.Parser)
+	}
+
+	return c, nil
+} // func NewCustom(cfg settings.CustomProbe) (*Custom, error)
+
+func nonEmptyString(s string) bool {
+	return s != ""
+} // func nonEmptyString(s string) bool
+
+// Applies returns true if the probe should run on the given Device. The OS
+// filter matches the Device's OS ID or any of the IDs it is like, "linux"
+// matches all Devices that are not BSD, and vice versa.
+func (c *Custom) Applies(d *model.Device) bool {
+	if len(c.OS) == 0 {
+		return true
+	}
+
+	var ids = append(strings.Fields(strings.ToLower(d.OSLike)), strings.ToLower(d.OSID))
+
+	if isBSD(d) {
+		ids = append(ids, "bsd")
+	} else {
+		ids = append(ids, "linux")
+	}
+
+	for _, want := range c.OS {
+		if slices.Contains(ids, strings.ToLower(want)) {
+			return true
+		}
+	}
+
+	return false
+} // func (c *Custom) Applies(d *model.Device) bool
+
+// Parse extracts the values from the output of the probe's command.
+func (c *Custom) Parse(output []string) (map[string]string, error) {
+	var text = strings.TrimSpace(strings.Join(output, "\n"))
+
+	switch c.Parser {
+	case settings.ParserRegex:
+		var match = c.re.FindStringSubmatch(text)
+
+		if match == nil {
+			return nil, errors.New("Pattern did not match the output")
+		}
+
+		var values = make(map[string]string)
+
+		for i, name := range c.re.SubexpNames() {
+			if name != "" {
+				values[name] = match[i]
+			}
+		}
+
+		return values, nil
+	case settings.ParserJSON:
+		var (
+			err error
+			val any
+			str string
+		)
+
+		if err = json.Unmarshal([]byte(text), &val); err != nil {
+			return nil, fmt.Errorf("Cannot parse output as JSON: %w", err)
+		} else if str, err = jsonPath(val, c.path); err != nil {
+			return nil, err
+		}
+
+		return map[string]string{customValueKey: str}, nil
+	default:
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return nil, fmt.Errorf("Output is not a number: %q", text)
+		}
+
+		return map[string]string{customValueKey: text}, nil
+	}
+} // func (c *Custom) Parse(output []string) (map[string]string, error)
+
+// jsonPath follows the given path of object keys and array indices into a
+// deserialized JSON document and returns the value it ends at as a string.
+func jsonPath(val any, path []string) (string, error) {
+	for i, key := range path {
+		switch node := val.(type) {
+		case map[string]any:
+			var ok bool
+			if val, ok = node[key]; !ok {
+				return "", fmt.Errorf("No key %q at %s",
+					key,
+					strings.Join(path[:i], "."))
+			}
+		case []any:
+			var idx, err = strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return "", fmt.Errorf("Invalid index %q at %s",
+					key,
+					strings.Join(path[:i], "."))
+			}
+			val = node[idx]
+		default:
+			return "", fmt.Errorf("Cannot look up %q in a scalar at %s",
+				key,
+				strings.Join(path[:i], "."))
+		}
+	}
+
+	switch v := val.(type) {
+	case nil:
+		return "", errors.New("Value is null")
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		var buf, err = json.Marshal(v)
+		return string(buf), err
+	}
+} // func jsonPath(val any, path []string) (string, error)
+
+// QueryCustom runs the given custom probe on a Device.
+func (p *Probe) QueryCustom(ctx context.Context, d *model.Device, c *Custom) (*model.CustomValue, error) {
+	var (
+		err    error
+		output []string
+		v      = &model.CustomValue{
+			DevID:     d.ID,
+			Timestamp: time.Now(),
+			Probe:     c.Name,
+		}
+	)
+
+	if output, err = p.executeCommand(ctx, d, c.Command); err != nil {
+		return nil, err
+	} else if v.Values, err = c.Parse(output); err != nil {
+		var ex = fmt.Errorf("Cannot parse output of custom probe %s on %s: %w",
+			c.Name,
+			d.Name,
+			err)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	return v, nil
+} // func (p *Probe) QueryCustom(ctx context.Context, d *model.Device, c *Custom) (*model.CustomValue, error)
@@ -0,0 +1,282 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/disk.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:22:22 krylon>
+
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/carebear/model"
+	"github.com/blicero/carebear/settings"
+)
+
+// GNU df can tell us everything we want in one go. It refuses -P alongside
+// --output, but with --output, it never wraps lines anyway. The BSD
+// versions of df do not print the type of filesystem (OpenBSD) or not in a
+// way that is easy to parse alongside the inodes (FreeBSD), so we get it
+// from mount(8) and separate the two outputs by a marker line.
+const (
+	dfCmdLinux = "env LC_ALL=C df -B1 --output=source,fstype,size,used,avail,itotal,iused,target"
+	dfCmdBSD   = "env LC_ALL=C df -ki && echo '%%' && mount"
+	dfMarker   = "%%"
+)
+
+// Sample output of mount(8):
+// /dev/ada0p2 on / (ufs, local, journaled soft-updates)
+// zroot/ROOT/default on / (zfs, local, noatime, nfsv4acls)
+// /dev/sd0a on / type ffs (local)
+
+var patMountBSD = regexp.MustCompile(`^(.+?) on (.+?) (?:type (\S+) \(|\((\w+)[,)])`)
+
+// QueryDiskFree asks the given Device for the usage of all the filesystems
+// it has mounted. Pseudo filesystems and the like are filtered out according
+// to the configuration.
+//
+// GNU df exits with status 1 if it cannot stat one of the mount points, e.g.
+// a FUSE mount of another user, but still reports on the others. We take
+// what we get in that case.
+func (p *Probe) QueryDiskFree(ctx context.Context, d *model.Device) (*model.DiskFree, error) {
+	var (
+		err    error
+		cmd    string
+		status int
+		output []string
+		mounts []*model.Filesystem
+		free   = &model.DiskFree{
+			DevID:       d.ID,
+			Timestamp:   time.Now(),
+			PercentFree: 100,
+		}
+	)
+
+	if isBSD(d) {
+		cmd = dfCmdBSD
+	} else {
+		cmd = dfCmdLinux
+	}
+
+	if output, status, err = p.runCommand(ctx, d, cmd); err != nil {
+		if err == ErrPingOffline {
+			return nil, err
+		}
+		var ex = fmt.Errorf("Failed to query free disk space on %s: %w",
+			d.Name,
+			err)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	} else if status != 0 && status != 1 {
+		var ex = fmt.Errorf("Failed to query free disk space on %s: df(1) exited with status %d\n%s",
+			d.Name,
+			status,
+			strings.Join(output, "\n"))
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	if cmd == dfCmdLinux {
+		mounts, err = parseDfLinux(output)
+	} else {
+		mounts, err = parseDfBSD(output)
+	}
+
+	if err == nil && status != 0 {
+		if len(mounts) == 0 {
+			err = fmt.Errorf("exited with status %d\n%s",
+				status,
+				strings.Join(output, "\n"))
+		} else {
+			p.log.Printf("[WARN] df(1) on %s exited with status %d, some filesystems may be missing:\n%s\n",
+				d.Name,
+				status,
+				strings.Join(dfComplaints(output), "\n"))
+		}
+	}
+
+	if err != nil {
+		var ex = fmt.Errorf("Cannot parse output of df(1) on %s: %w",
+			d.Name,
+			err)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	free.Mounts = filterMounts(mounts)
+
+	for _, fs := range free.Mounts {
+		if pct := fs.PercentFree(); pct < free.PercentFree {
+			free.PercentFree = pct
+		}
+	}
+
+	return free, nil
+} // func (p *Probe) QueryDiskFree(ctx context.Context, d *model.Device) (*model.DiskFree, error)
+
+// filterMounts removes the filesystems we are not interested in: Those
+// whose type or mount point the configuration tells us to exclude, and
+// those that have no size at all, which are pseudo filesystems, too.
+// Mount points are matched as shell patterns, e.g. "/var/lib/docker/*".
+func filterMounts(mounts []*model.Filesystem) []*model.Filesystem {
+	var (
+		types    []string
+		patterns []string
+		result   = make([]*model.Filesystem, 0, len(mounts))
+	)
+
+	if settings.Settings != nil {
+		types = settings.Settings.DiskExcludeTypes
+		patterns = settings.Settings.DiskExcludeMounts
+	}
+
+MOUNT:
+	for _, fs := range mounts {
+		if fs.Size <= 0 {
+			continue
+		}
+
+		for _, t := range types {
+			if fs.FSType == t {
+				continue MOUNT
+			}
+		}
+
+		for _, pat := range patterns {
+			if ok, _ := path.Match(pat, fs.Mountpoint); ok {
+				continue MOUNT
+			}
+		}
+
+		result = append(result, fs)
+	}
+
+	return result
+} // func filterMounts(mounts []*model.Filesystem) []*model.Filesystem
+
+// dfComplaints returns the lines df wrote to stderr, which end up in the
+// same output as the table.
+func dfComplaints(output []string) []string {
+	var lines = make([]string, 0)
+
+	for _, l := range output {
+		if strings.HasPrefix(l, "df: ") {
+			lines = append(lines, l)
+		}
+	}
+
+	return lines
+} // func dfComplaints(output []string) []string
+
+// parseDfLinux parses the output of GNU df. Sizes are in bytes already.
+// Filesystems that do not have inodes report "-" for them. Complaints
+// from df about mount points it cannot stat are skipped.
+func parseDfLinux(output []string) ([]*model.Filesystem, error) {
+	var (
+		lines  = slices.DeleteFunc(nonEmpty(output), func(l string) bool { return strings.HasPrefix(l, "df: ") })
+		mounts = make([]*model.Filesystem, 0, len(lines))
+	)
+
+	if len(lines) == 0 {
+		return nil, errors.New("no output")
+	}
+
+	for _, l := range lines[1:] {
+		var fields = strings.Fields(l)
+
+		if len(fields) < 8 {
+			continue
+		}
+
+		var fs = &model.Filesystem{
+			Device:     fields[0],
+			FSType:     fields[1],
+			Size:       dfNumber(fields[2]),
+			Used:       dfNumber(fields[3]),
+			Avail:      dfNumber(fields[4]),
+			Inodes:     dfNumber(fields[5]),
+			InodesUsed: dfNumber(fields[6]),
+			Mountpoint: strings.Join(fields[7:], " "),
+		}
+
+		mounts = append(mounts, fs)
+	}
+
+	return mounts, nil
+} // func parseDfLinux(output []string) ([]*model.Filesystem, error)
+
+// parseDfBSD parses the output of "df -ki" followed by that of mount(8).
+// The columns of df are device, size, used, available, capacity, inodes
+// used, inodes free, percentage of inodes used and the mount point.
+func parseDfBSD(output []string) ([]*model.Filesystem, error) {
+	var (
+		lines  = nonEmpty(output)
+		mounts = make([]*model.Filesystem, 0, len(lines))
+		types  = make(map[string]string)
+		idx    = slices.Index(lines, dfMarker)
+	)
+
+	if idx < 1 {
+		return nil, errors.New("unexpected output")
+	}
+
+	for _, l := range lines[idx+1:] {
+		var match []string
+
+		if match = patMountBSD.FindStringSubmatch(l); match == nil {
+			continue
+		} else if match[3] != "" {
+			types[match[2]] = match[3]
+		} else {
+			types[match[2]] = match[4]
+		}
+	}
+
+	for _, l := range lines[1:idx] {
+		var fields = strings.Fields(l)
+
+		if len(fields) < 9 {
+			continue
+		}
+
+		var (
+			ifree = dfNumber(fields[6])
+			fs    = &model.Filesystem{
+				Device:     fields[0],
+				Size:       dfNumber(fields[1]) * 1024,
+				Used:       dfNumber(fields[2]) * 1024,
+				Avail:      dfNumber(fields[3]) * 1024,
+				InodesUsed: dfNumber(fields[5]),
+				Mountpoint: strings.Join(fields[8:], " "),
+			}
+		)
+
+		fs.Inodes = fs.InodesUsed + ifree
+		fs.FSType = types[fs.Mountpoint]
+		mounts = append(mounts, fs)
+	}
+
+	return mounts, nil
+} // func parseDfBSD(output []string) ([]*model.Filesystem, error)
+
+// dfNumber parses a number from the output of df. Some filesystems report
+// "-" for values that do not apply to them, which we treat as zero. The
+// available space can become negative on the BSDs, when root has eaten into
+// the reserved space.
+func dfNumber(s string) int64 {
+	var n, err = strconv.ParseInt(s, 10, 64)
+
+	if err != nil {
+		return 0
+	}
+
+	return n
+} // func dfNumber(s string) int64
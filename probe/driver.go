@@ -0,0 +1,218 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/driver.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:43:03 krylon>
+
+package probe
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/blicero/carebear/model"
+)
+
+// statusNotFound is the exit status the shell reports for a command it
+// cannot find.
+const statusNotFound = 127
+
+// CmdStatus tells us how to interpret the exit status of a command.
+type CmdStatus uint8
+
+// A command's exit status may mean it failed, it succeeded, or it succeeded
+// but there is nothing to report (e.g. no updates are available).
+const (
+	CmdFailed CmdStatus = iota
+	CmdOK
+	CmdEmpty
+)
+
+// OSDriver knows how to query a family of operating systems.
+//
+// A driver only deals in commands and their output, running the commands
+// on a Device is up to the Probe. That way, the parsers can be tested
+// without a Device to talk to.
+type OSDriver interface {
+	// Family returns the name of the OS family the driver handles.
+	Family() string
+	// UpdateCmd returns the command to list pending updates.
+	UpdateCmd() string
+	// UpgradeCmd returns the command to install pending updates. It needs
+	// to run as root and must not ask any questions.
+	UpgradeCmd() string
+	// ParseUpdates extracts the list of pending updates from the output of UpdateCmd.
+	ParseUpdates(output []string) []*model.PackageUpdate
+	// ExitStatus interprets the exit status of one of the driver's commands.
+	ExitStatus(cmd string, status int) CmdStatus
+	// RebootCmd returns the command to check if the Device needs to be
+	// rebooted, or an empty string if we have no way to tell.
+	RebootCmd() string
+	// ParseReboot interprets the output and exit status of RebootCmd.
+	ParseReboot(output []string, status int) bool
+	// PackageCmd returns the command to list installed packages.
+	PackageCmd() string
+	// ParsePackages extracts the list of installed packages from the output of PackageCmd.
+	ParsePackages(output []string) []*model.Package
+}
+
+// SecurityDriver is implemented by drivers that need to run a separate
+// command to find out which pending updates fix security issues.
+// Drivers that can tell from the output of UpdateCmd alone set the
+// Security flag in ParseUpdates.
+type SecurityDriver interface {
+	// SecurityCmd returns the command to list pending security updates.
+	SecurityCmd() string
+	// ParseSecurity extracts the pending security updates from the output of SecurityCmd.
+	ParseSecurity(output []string) []*model.PackageUpdate
+}
+
+var (
+	driverLock sync.RWMutex
+	drivers    = make(map[string]OSDriver)
+)
+
+// RegisterDriver makes the given OSDriver available for Devices whose
+// os-release ID or ID_LIKE contains one of the given IDs.
+func RegisterDriver(drv OSDriver, ids ...string) {
+	driverLock.Lock()
+	defer driverLock.Unlock()
+
+	for _, id := range ids {
+		drivers[id] = drv
+	}
+} // func RegisterDriver(drv OSDriver, ids ...string)
+
+// DriverFor returns the OSDriver for the given Device, or nil if there is none.
+//
+// The Device's own ID takes precedence, so a driver registered for e.g.
+// "ubuntu" can override the one for "debian". Failing that, we go through
+// ID_LIKE in order, which lists the most closely related systems first.
+func DriverFor(d *model.Device) OSDriver {
+	driverLock.RLock()
+	defer driverLock.RUnlock()
+
+	var ids = make([]string, 0, 4)
+
+	if d.OSID != "" {
+		ids = append(ids, strings.ToLower(d.OSID))
+	}
+
+	for _, id := range strings.Fields(d.OSLike) {
+		ids = append(ids, strings.ToLower(id))
+	}
+
+	for _, id := range ids {
+		if drv, ok := drivers[id]; ok {
+			return drv
+		}
+	}
+
+	return nil
+} // func DriverFor(d *model.Device) OSDriver
+
+// mergeSecurity flags the updates that also appear in the list of security
+// updates. Security updates we do not know about yet, e.g. vulnerable
+// packages reported by pkg audit, are appended to the list.
+func mergeSecurity(updates, security []*model.PackageUpdate) []*model.PackageUpdate {
+	for _, s := range security {
+		var found bool
+
+		for _, u := range updates {
+			if u.Name == s.Name && (u.Arch == "" || s.Arch == "" || u.Arch == s.Arch) {
+				u.Security = true
+				found = true
+			}
+		}
+
+		if !found {
+			s.Security = true
+			updates = append(updates, s)
+		}
+	}
+
+	return updates
+} // func mergeSecurity(updates, security []*model.PackageUpdate) []*model.PackageUpdate
+
+// isBSD returns true if the given Device runs one of the BSDs.
+// Commands that do not depend on the package manager usually differ only
+// between Linux and BSD.
+func isBSD(d *model.Device) bool {
+	switch strings.ToLower(d.OSID) {
+	case "freebsd", "openbsd", "netbsd", "dragonfly":
+		return true
+	default:
+		return false
+	}
+} // func isBSD(d *model.Device) bool
+
+// exitOK is a helper for drivers whose commands only ever succeed with
+// exit status 0.
+func exitOK(status int) CmdStatus {
+	if status == 0 {
+		return CmdOK
+	}
+
+	return CmdFailed
+} // func exitOK(status int) CmdStatus
+
+// nonEmpty strips whitespace from the lines of a command's output and
+// drops the ones that are empty.
+func nonEmpty(output []string) []string {
+	var lines = make([]string, 0, len(output))
+
+	for _, l := range output {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	return lines
+} // func nonEmpty(output []string) []string
+
+// parsePackagesTab parses lines of the form name<TAB>version[<TAB>arch],
+// which is what we ask dpkg-query, rpm and pkg to emit.
+func parsePackagesTab(output []string) []*model.Package {
+	var pkgs = make([]*model.Package, 0, len(output))
+
+	for _, l := range nonEmpty(output) {
+		var pieces = strings.Split(l, "\t")
+
+		if len(pieces) < 2 {
+			continue
+		}
+
+		var pkg = &model.Package{
+			Name:    pieces[0],
+			Version: pieces[1],
+		}
+
+		if len(pieces) > 2 {
+			pkg.Arch = pieces[2]
+		}
+
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs
+} // func parsePackagesTab(output []string) []*model.Package
+
+// parsePackagesDash parses lines of the form name-version, which is what
+// pkg_info, apk and others emit. The version is the first part following
+// a dash that starts with a digit, e.g. "py3-setuptools-69.5.1v0" or
+// "vim-9.1.0707-no_x11".
+func parsePackagesDash(output []string) []*model.Package {
+	var pkgs = make([]*model.Package, 0, len(output))
+
+	for _, l := range nonEmpty(output) {
+		var match []string
+
+		if match = patPkgVersion.FindStringSubmatch(l); match == nil {
+			continue
+		}
+
+		pkgs = append(pkgs, &model.Package{Name: match[1], Version: match[2]})
+	}
+
+	return pkgs
+} // func parsePackagesDash(output []string) []*model.Package
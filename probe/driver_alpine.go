@@ -0,0 +1,75 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/driver_alpine.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:39:19 krylon>
+
+package probe
+
+import (
+	"regexp"
+
+	"github.com/blicero/carebear/model"
+)
+
+const (
+	alpineUpdateCmd  = "apk version -l '<'"
+	alpineUpgradeCmd = "apk upgrade -U"
+	alpinePackageCmd = "apk info -v"
+)
+
+// Sample output:
+// Installed:                                Available:
+// busybox-1.36.1-r15                      < 1.36.1-r16
+
+var patUpdateAlpine = regexp.MustCompile(`^(\S+)\s+<\s+(\S+)`)
+
+// alpineDriver handles Alpine Linux.
+type alpineDriver struct{}
+
+func init() {
+	RegisterDriver(alpineDriver{}, "alpine")
+}
+
+func (alpineDriver) Family() string     { return "alpine" }
+func (alpineDriver) UpdateCmd() string  { return alpineUpdateCmd }
+func (alpineDriver) UpgradeCmd() string { return alpineUpgradeCmd }
+func (alpineDriver) RebootCmd() string  { return "" }
+func (alpineDriver) PackageCmd() string { return alpinePackageCmd }
+
+// ParseUpdates splits the installed package, e.g. "busybox-1.36.1-r15",
+// into name and version. apk only compares against the cached index, it
+// is up to the Device to refresh it now and then.
+func (alpineDriver) ParseUpdates(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0)
+
+	for _, l := range nonEmpty(output) {
+		var match, pkg []string
+
+		if match = patUpdateAlpine.FindStringSubmatch(l); match == nil {
+			continue
+		} else if pkg = patPkgVersion.FindStringSubmatch(match[1]); pkg == nil {
+			continue
+		}
+
+		updates = append(updates, &model.PackageUpdate{
+			Name:           pkg[1],
+			CurrentVersion: pkg[2],
+			NewVersion:     match[2],
+		})
+	}
+
+	return updates
+} // func (alpineDriver) ParseUpdates(output []string) []*model.PackageUpdate
+
+func (alpineDriver) ExitStatus(_ string, status int) CmdStatus {
+	return exitOK(status)
+} // func (alpineDriver) ExitStatus(_ string, status int) CmdStatus
+
+func (alpineDriver) ParseReboot(_ []string, _ int) bool {
+	return false
+} // func (alpineDriver) ParseReboot(_ []string, _ int) bool
+
+func (alpineDriver) ParsePackages(output []string) []*model.Package {
+	return parsePackagesDash(output)
+} // func (alpineDriver) ParsePackages(output []string) []*model.Package
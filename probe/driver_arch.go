@@ -0,0 +1,110 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/driver_arch.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:26:44 krylon>
+
+package probe
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/blicero/carebear/model"
+)
+
+const (
+	archUpdateCmd  = "checkupdates"
+	archUpgradeCmd = "pacman -Syu --noconfirm"
+	archRebootCmd  = "uname -r && pacman -Q linux"
+	archPackageCmd = "pacman -Q"
+)
+
+var patUpdateArch = regexp.MustCompile(`^(\S+)\s+(\S+)\s+->\s+(\S+)$`)
+
+// archDriver handles Arch Linux and derivatives like Manjaro.
+type archDriver struct{}
+
+func init() {
+	RegisterDriver(archDriver{}, "arch")
+}
+
+func (archDriver) Family() string     { return "arch" }
+func (archDriver) UpdateCmd() string  { return archUpdateCmd }
+func (archDriver) UpgradeCmd() string { return archUpgradeCmd }
+func (archDriver) RebootCmd() string  { return archRebootCmd }
+func (archDriver) PackageCmd() string { return archPackageCmd }
+
+func (archDriver) ParseUpdates(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0)
+
+	for _, l := range output {
+		var match []string
+		if match = patUpdateArch.FindStringSubmatch(l); len(match) > 0 {
+			var upd = &model.PackageUpdate{
+				Name:           match[1],
+				CurrentVersion: match[2],
+				NewVersion:     match[3],
+			}
+			updates = append(updates, upd)
+		}
+	}
+
+	return updates
+} // func (archDriver) ParseUpdates(output []string) []*model.PackageUpdate
+
+func (archDriver) ExitStatus(cmd string, status int) CmdStatus {
+	switch cmd {
+	case archUpdateCmd:
+		// checkupdates exits with status 2 if no updates are available.
+		if status == 2 {
+			return CmdEmpty
+		}
+	case archRebootCmd:
+		// pacman -Q exits with status 1 if the stock kernel is not
+		// installed, in which case we cannot tell.
+		if status == 1 {
+			return CmdOK
+		}
+	}
+
+	return exitOK(status)
+} // func (archDriver) ExitStatus(cmd string, status int) CmdStatus
+
+// ParseReboot compares the running kernel to the installed one.
+// uname(1) reports e.g. "6.16.4-arch1-1", while pacman says
+// "linux 6.16.4.arch1-1".
+func (archDriver) ParseReboot(output []string, status int) bool {
+	var lines = nonEmpty(output)
+
+	if status != 0 || len(lines) < 2 {
+		return false
+	}
+
+	var (
+		running   = strings.Replace(lines[0], "-arch", ".arch", 1)
+		installed = strings.Fields(lines[1])
+	)
+
+	if len(installed) != 2 || installed[0] != "linux" {
+		return false
+	}
+
+	return running != installed[1]
+} // func (archDriver) ParseReboot(output []string, status int) bool
+
+func (archDriver) ParsePackages(output []string) []*model.Package {
+	var pkgs = make([]*model.Package, 0, len(output))
+
+	for _, l := range nonEmpty(output) {
+		var pieces = strings.Fields(l)
+
+		if len(pieces) != 2 {
+			continue
+		}
+
+		pkgs = append(pkgs, &model.Package{Name: pieces[0], Version: pieces[1]})
+	}
+
+	return pkgs
+} // func (archDriver) ParsePackages(output []string) []*model.Package
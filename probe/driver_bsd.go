@@ -0,0 +1,154 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/driver_bsd.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:39:19 krylon>
+
+package probe
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/blicero/carebear/model"
+)
+
+// freebsd-update, pkg audit -F and syspatch need root privileges even to
+// look for updates.
+const (
+	freebsdUpdateCmd  = rootPrefix + "freebsd-update updatesready"
+	freebsdUpgradeCmd = "freebsd-update install"
+	freebsdRebootCmd  = "freebsd-version -kr"
+	freebsdPackageCmd = "pkg query '%n\\t%v\\t%q'"
+	freebsdAuditCmd   = rootPrefix + "pkg audit -Fq"
+	openbsdUpdateCmd  = rootPrefix + "syspatch -c"
+	openbsdUpgradeCmd = "syspatch"
+	openbsdPackageCmd = "pkg_info -q"
+)
+
+var (
+	patUpdateOpenBSD = regexp.MustCompile(`\w+`)
+	patPkgVersion    = regexp.MustCompile(`^(.+?)-(\d[^-]*(?:-.+)?)$`)
+)
+
+// freebsdDriver handles FreeBSD.
+type freebsdDriver struct{}
+
+// openbsdDriver handles OpenBSD.
+type openbsdDriver struct{}
+
+func init() {
+	RegisterDriver(freebsdDriver{}, "freebsd")
+	RegisterDriver(openbsdDriver{}, "openbsd")
+}
+
+func (freebsdDriver) Family() string      { return "freebsd" }
+func (freebsdDriver) UpdateCmd() string   { return freebsdUpdateCmd }
+func (freebsdDriver) UpgradeCmd() string  { return freebsdUpgradeCmd }
+func (freebsdDriver) RebootCmd() string   { return freebsdRebootCmd }
+func (freebsdDriver) PackageCmd() string  { return freebsdPackageCmd }
+func (freebsdDriver) SecurityCmd() string { return freebsdAuditCmd }
+
+// ParseUpdates reports a single update for the base system if
+// freebsd-update says there are any, it does not tell us anything more
+// specific.
+func (freebsdDriver) ParseUpdates(output []string) []*model.PackageUpdate {
+	for _, l := range output {
+		if patUpdateOpenBSD.MatchString(l) {
+			return []*model.PackageUpdate{
+				{Name: "base", Repo: "freebsd-update"},
+			}
+		}
+	}
+
+	return nil
+} // func (freebsdDriver) ParseUpdates(output []string) []*model.PackageUpdate
+
+// ParseSecurity reports the packages pkg audit considers vulnerable, e.g.
+// "curl-8.9.0". pkg audit does not know if a fixed version is available.
+func (freebsdDriver) ParseSecurity(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0)
+
+	for _, l := range nonEmpty(output) {
+		var match []string
+
+		if match = patPkgVersion.FindStringSubmatch(l); match == nil {
+			continue
+		}
+
+		updates = append(updates, &model.PackageUpdate{
+			Name:           match[1],
+			CurrentVersion: match[2],
+			Repo:           "pkg audit",
+		})
+	}
+
+	return updates
+} // func (freebsdDriver) ParseSecurity(output []string) []*model.PackageUpdate
+
+func (freebsdDriver) ExitStatus(cmd string, status int) CmdStatus {
+	switch {
+	case cmd == freebsdUpdateCmd && status == 2:
+		// "freebsd-update updatesready" exits with status 2 if no updates are available.
+		return CmdEmpty
+	case cmd == freebsdAuditCmd && status == 1:
+		// pkg audit exits with status 1 if it found vulnerable packages.
+		return CmdOK
+	}
+
+	return exitOK(status)
+} // func (freebsdDriver) ExitStatus(cmd string, status int) CmdStatus
+
+// ParseReboot compares the installed kernel to the running one.
+// freebsd-version prints the former first.
+func (freebsdDriver) ParseReboot(output []string, status int) bool {
+	var lines = nonEmpty(output)
+
+	if status != 0 || len(lines) < 2 {
+		return false
+	}
+
+	return lines[0] != lines[1]
+} // func (freebsdDriver) ParseReboot(output []string, status int) bool
+
+func (freebsdDriver) ParsePackages(output []string) []*model.Package {
+	return parsePackagesTab(output)
+} // func (freebsdDriver) ParsePackages(output []string) []*model.Package
+
+func (openbsdDriver) Family() string     { return "openbsd" }
+func (openbsdDriver) UpdateCmd() string  { return openbsdUpdateCmd }
+func (openbsdDriver) UpgradeCmd() string { return openbsdUpgradeCmd }
+func (openbsdDriver) RebootCmd() string  { return "" }
+func (openbsdDriver) PackageCmd() string { return openbsdPackageCmd }
+
+// ParseUpdates returns one update per patch syspatch lists.
+func (openbsdDriver) ParseUpdates(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0, len(output))
+
+	for _, l := range output {
+		if patUpdateOpenBSD.MatchString(l) {
+			updates = append(updates, &model.PackageUpdate{
+				Name: strings.TrimSpace(l),
+				Repo: "syspatch",
+			})
+		}
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	return updates
+} // func (openbsdDriver) ParseUpdates(output []string) []*model.PackageUpdate
+
+func (openbsdDriver) ExitStatus(_ string, status int) CmdStatus {
+	return exitOK(status)
+} // func (openbsdDriver) ExitStatus(_ string, status int) CmdStatus
+
+func (openbsdDriver) ParseReboot(_ []string, _ int) bool {
+	return false
+} // func (openbsdDriver) ParseReboot(_ []string, _ int) bool
+
+func (openbsdDriver) ParsePackages(output []string) []*model.Package {
+	return parsePackagesDash(output)
+} // func (openbsdDriver) ParsePackages(output []string) []*model.Package
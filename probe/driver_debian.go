@@ -0,0 +1,112 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/driver_debian.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:26:19 krylon>
+
+package probe
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/blicero/carebear/model"
+)
+
+const (
+	debianUpdateCmd  = "/usr/bin/apt list --upgradable"
+	debianRebootCmd  = "test -e /var/run/reboot-required"
+	debianUpgradeCmd = "env DEBIAN_FRONTEND=noninteractive apt-get -y upgrade"
+	ubuntuUpgradeCmd = "env DEBIAN_FRONTEND=noninteractive apt-get -y --with-new-pkgs upgrade"
+	debianPackageCmd = "dpkg-query -W -f='${Package}\\t${Version}\\t${Architecture}\\n'"
+)
+
+// Sample output:
+// openssl/stable-security 3.0.15-1~deb12u1 amd64 [upgradable from: 3.0.14-1~deb12u2]
+
+var (
+	patUpdateDebian    = regexp.MustCompile(`^([^/]+)/(\S+)\s+(\S+)\s+(\S+)`)
+	patUpdateDebianCur = regexp.MustCompile(`\[upgradable from: ([^\]]+)\]`)
+)
+
+// debianDriver handles Debian and its many derivatives.
+type debianDriver struct{}
+
+// ubuntuDriver handles Ubuntu and its derivatives. New kernels on Ubuntu
+// come in new packages, which apt-get upgrade keeps back unless we allow
+// it to install new packages.
+type ubuntuDriver struct {
+	debianDriver
+}
+
+func init() {
+	RegisterDriver(debianDriver{}, "debian", "raspbian")
+	RegisterDriver(ubuntuDriver{}, "ubuntu")
+}
+
+func (debianDriver) Family() string     { return "debian" }
+func (debianDriver) UpdateCmd() string  { return debianUpdateCmd }
+func (debianDriver) UpgradeCmd() string { return debianUpgradeCmd }
+func (debianDriver) RebootCmd() string  { return debianRebootCmd }
+func (debianDriver) PackageCmd() string { return debianPackageCmd }
+
+func (ubuntuDriver) Family() string     { return "ubuntu" }
+func (ubuntuDriver) UpgradeCmd() string { return ubuntuUpgradeCmd }
+
+// ParseUpdates flags updates from the security suite, e.g.
+// "bookworm-security" or "stable-security", as security updates. If a
+// version is available from several suites, apt lists all of them,
+// separated by commas, e.g. "stable-security,stable-updates".
+func (debianDriver) ParseUpdates(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0)
+
+	for _, l := range output {
+		var match []string
+		if match = patUpdateDebian.FindStringSubmatch(l); len(match) > 0 {
+			var upd = &model.PackageUpdate{
+				Name:       match[1],
+				Repo:       match[2],
+				NewVersion: match[3],
+				Arch:       match[4],
+				Security:   debianSecuritySuite(match[2]),
+			}
+
+			if cur := patUpdateDebianCur.FindStringSubmatch(l); cur != nil {
+				upd.CurrentVersion = cur[1]
+			}
+
+			updates = append(updates, upd)
+		}
+	}
+
+	return updates
+} // func (debianDriver) ParseUpdates(output []string) []*model.PackageUpdate
+
+// debianSecuritySuite returns true if any of the comma-separated suites
+// is a security suite.
+func debianSecuritySuite(suites string) bool {
+	for _, s := range strings.Split(suites, ",") {
+		if strings.HasSuffix(s, "-security") {
+			return true
+		}
+	}
+
+	return false
+} // func debianSecuritySuite(suites string) bool
+
+func (debianDriver) ExitStatus(cmd string, status int) CmdStatus {
+	if cmd == debianRebootCmd && status == 1 {
+		// test(1) exits with status 1 if the file does not exist.
+		return CmdOK
+	}
+
+	return exitOK(status)
+} // func (debianDriver) ExitStatus(cmd string, status int) CmdStatus
+
+func (debianDriver) ParseReboot(_ []string, status int) bool {
+	return status == 0
+} // func (debianDriver) ParseReboot(_ []string, status int) bool
+
+func (debianDriver) ParsePackages(output []string) []*model.Package {
+	return parsePackagesTab(output)
+} // func (debianDriver) ParsePackages(output []string) []*model.Package
@@ -0,0 +1,81 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/driver_gentoo.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:39:19 krylon>
+
+package probe
+
+import (
+	"regexp"
+
+	"github.com/blicero/carebear/model"
+)
+
+// We read the installed packages straight from the package database, so we
+// do not depend on portage-utils or gentoolkit being installed.
+const (
+	gentooUpdateCmd  = "emerge -puDN @world"
+	gentooUpgradeCmd = "emerge --ask=n -uDN @world"
+	gentooPackageCmd = "cd /var/db/pkg && ls -d */*"
+)
+
+// Sample output:
+// [ebuild     U  ] sys-libs/glibc-2.39-r6:2.2::gentoo [2.39-r5:2.2::gentoo] USE="multiarch ssp"
+// [ebuild  N     ] dev-libs/libfoo-1.0
+// [binary     U  ] app-editors/vim-9.1.0707 [9.1.0600]
+
+var patUpdateGentoo = regexp.MustCompile(
+	`^\[(?:ebuild|binary)\s*([^\]]*)\]\s+([^\s:]+)(?::[^:\s]+)?(?:::(\S+))?(?:\s+\[([^\]:]+))?`)
+
+// gentooDriver handles Gentoo.
+type gentooDriver struct{}
+
+func init() {
+	RegisterDriver(gentooDriver{}, "gentoo")
+}
+
+func (gentooDriver) Family() string     { return "gentoo" }
+func (gentooDriver) UpdateCmd() string  { return gentooUpdateCmd }
+func (gentooDriver) UpgradeCmd() string { return gentooUpgradeCmd }
+func (gentooDriver) RebootCmd() string  { return "" }
+func (gentooDriver) PackageCmd() string { return gentooPackageCmd }
+
+// ParseUpdates lists every package emerge would merge, which includes new
+// dependencies and rebuilds due to changed USE flags, not only upgrades.
+func (gentooDriver) ParseUpdates(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0)
+
+	for _, l := range nonEmpty(output) {
+		var match, pkg []string
+
+		if match = patUpdateGentoo.FindStringSubmatch(l); match == nil {
+			continue
+		} else if pkg = patPkgVersion.FindStringSubmatch(match[2]); pkg == nil {
+			continue
+		}
+
+		updates = append(updates, &model.PackageUpdate{
+			Name:           pkg[1],
+			NewVersion:     pkg[2],
+			CurrentVersion: match[4],
+			Repo:           match[3],
+		})
+	}
+
+	return updates
+} // func (gentooDriver) ParseUpdates(output []string) []*model.PackageUpdate
+
+func (gentooDriver) ExitStatus(_ string, status int) CmdStatus {
+	return exitOK(status)
+} // func (gentooDriver) ExitStatus(_ string, status int) CmdStatus
+
+func (gentooDriver) ParseReboot(_ []string, _ int) bool {
+	return false
+} // func (gentooDriver) ParseReboot(_ []string, _ int) bool
+
+// ParsePackages splits the entries of the package database, e.g.
+// "sys-libs/glibc-2.39-r6", into name and version.
+func (gentooDriver) ParsePackages(output []string) []*model.Package {
+	return parsePackagesDash(output)
+} // func (gentooDriver) ParsePackages(output []string) []*model.Package
@@ -0,0 +1,170 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/driver_macos.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:43:03 krylon>
+
+package probe
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/blicero/carebear/model"
+)
+
+// macOS updates come from two places, softwareupdate for the system and
+// Apple's applications, and Homebrew for everything else, so we run both
+// and split the output at a marker. Homebrew usually is not in the PATH of
+// a non-interactive session. It refuses to run as root, so installing
+// updates only covers softwareupdate.
+const (
+	macosBrewMarker = "--- brew outdated ---"
+	macosBrewEnv    = `env PATH="$PATH:/opt/homebrew/bin:/usr/local/bin" `
+	macosUpdateCmd  = "softwareupdate -l 2>&1; echo '" + macosBrewMarker + "'; " + macosBrewEnv + "brew outdated --json=v2"
+	macosUpgradeCmd = "softwareupdate -i -a"
+	macosPackageCmd = macosBrewEnv + "brew list --versions"
+)
+
+// Sample output:
+// * Label: macOS Sonoma 14.6.1-23G93
+// 	Title: macOS Sonoma 14.6.1, Version: 14.6.1, Size: 1545896KiB, Recommended: YES, Action: restart,
+
+var patUpdateMacOS = regexp.MustCompile(`Title: ([^,]+), Version: ([^,]+)`)
+
+// brewOutdated is an entry in the output of brew outdated --json.
+type brewOutdated struct {
+	Name              string   `json:"name"`
+	InstalledVersions []string `json:"installed_versions"`
+	CurrentVersion    string   `json:"current_version"`
+}
+
+func (b *brewOutdated) update(repo string) *model.PackageUpdate {
+	var upd = &model.PackageUpdate{
+		Name:       b.Name,
+		NewVersion: b.CurrentVersion,
+		Repo:       repo,
+		Source:     "homebrew",
+	}
+
+	if len(b.InstalledVersions) > 0 {
+		upd.CurrentVersion = b.InstalledVersions[len(b.InstalledVersions)-1]
+	}
+
+	return upd
+} // func (b *brewOutdated) update(repo string) *model.PackageUpdate
+
+// macosDriver handles macOS.
+type macosDriver struct{}
+
+func init() {
+	RegisterDriver(macosDriver{}, "darwin", "macos")
+}
+
+func (macosDriver) Family() string     { return "macos" }
+func (macosDriver) UpdateCmd() string  { return macosUpdateCmd }
+func (macosDriver) UpgradeCmd() string { return macosUpgradeCmd }
+func (macosDriver) RebootCmd() string  { return "" }
+func (macosDriver) PackageCmd() string { return macosPackageCmd }
+
+// ParseUpdates combines the updates reported by softwareupdate and brew.
+// Apple flags security updates in their title, e.g. "Background Security
+// Improvement".
+func (macosDriver) ParseUpdates(output []string) []*model.PackageUpdate {
+	var (
+		updates = make([]*model.PackageUpdate, 0)
+		brew    []string
+	)
+
+	for i, l := range output {
+		if strings.TrimSpace(l) == macosBrewMarker {
+			brew = output[i+1:]
+			break
+		}
+
+		var match []string
+
+		if match = patUpdateMacOS.FindStringSubmatch(l); match == nil {
+			continue
+		}
+
+		updates = append(updates, &model.PackageUpdate{
+			Name:       strings.TrimSpace(match[1]),
+			NewVersion: strings.TrimSpace(match[2]),
+			Repo:       "softwareupdate",
+			Source:     "softwareupdate",
+			Security:   strings.Contains(match[1], "Security"),
+		})
+	}
+
+	return append(updates, parseBrewOutdated(brew)...)
+} // func (macosDriver) ParseUpdates(output []string) []*model.PackageUpdate
+
+// parseBrewOutdated parses the output of brew outdated --json. Version 2 of
+// the format lists formulae and casks separately, version 1 is a flat list
+// of formulae.
+func parseBrewOutdated(output []string) []*model.PackageUpdate {
+	var (
+		err  error
+		text = []byte(strings.TrimSpace(strings.Join(output, "\n")))
+		v2   struct {
+			Formulae []brewOutdated `json:"formulae"`
+			Casks    []brewOutdated `json:"casks"`
+		}
+		updates = make([]*model.PackageUpdate, 0)
+	)
+
+	if len(text) == 0 {
+		return updates
+	} else if err = json.Unmarshal(text, &v2); err != nil {
+		if err = json.Unmarshal(text, &v2.Formulae); err != nil {
+			return updates
+		}
+	}
+
+	for _, b := range v2.Formulae {
+		updates = append(updates, b.update("homebrew"))
+	}
+
+	for _, b := range v2.Casks {
+		updates = append(updates, b.update("homebrew-cask"))
+	}
+
+	return updates
+} // func parseBrewOutdated(output []string) []*model.PackageUpdate
+
+// ExitStatus accepts the shell's status for a missing command, which we
+// get if Homebrew is not installed.
+func (macosDriver) ExitStatus(cmd string, status int) CmdStatus {
+	switch {
+	case cmd == macosUpdateCmd && status == statusNotFound:
+		return CmdOK
+	case cmd == macosPackageCmd && status == statusNotFound:
+		return CmdEmpty
+	}
+
+	return exitOK(status)
+} // func (macosDriver) ExitStatus(cmd string, status int) CmdStatus
+
+func (macosDriver) ParseReboot(_ []string, _ int) bool {
+	return false
+} // func (macosDriver) ParseReboot(_ []string, _ int) bool
+
+// ParsePackages lists the formulae installed with Homebrew, e.g.
+// "git 2.46.0". If several versions are installed, we report the last one.
+func (macosDriver) ParsePackages(output []string) []*model.Package {
+	var pkgs = make([]*model.Package, 0, len(output))
+
+	for _, l := range nonEmpty(output) {
+		var fields = strings.Fields(l)
+
+		if len(fields) < 2 {
+			continue
+		}
+
+		pkgs = append(pkgs, &model.Package{Name: fields[0], Version: fields[len(fields)-1]})
+	}
+
+	return pkgs
+} // func (macosDriver) ParsePackages(output []string) []*model.Package
@@ -0,0 +1,118 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/driver_nixos.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:09:49 krylon>
+
+package probe
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/blicero/carebear/model"
+)
+
+// NixOS has no notion of individual package updates. Instead, we ask
+// nixos-rebuild what it would have to build or fetch to switch to the
+// system described by the current configuration and channels. Evaluating
+// the configuration may need to read files only root can read.
+//
+// We do not pass --upgrade, because that updates root's channels as a side
+// effect, even for a dry build. So we only see updates once the channels
+// have been updated by other means, e.g. system.autoUpgrade or a timer
+// running nix-channel --update, or by applying updates from carebear,
+// which does pass --upgrade. This assumes the system is built from
+// channels rather than a flake.
+const (
+	nixosUpdateCmd  = rootPrefix + "nixos-rebuild dry-build"
+	nixosUpgradeCmd = "nixos-rebuild switch --upgrade"
+	nixosRebootCmd  = "readlink /run/booted-system/kernel /run/current-system/kernel"
+	nixosPackageCmd = "nix-store -qR /run/current-system/sw"
+)
+
+// Sample output:
+// these 2 derivations will be built:
+//   /nix/store/8f5s8...-nixos-system-nixbox-24.05.4328.bcd44e2.drv
+// these 12 paths will be fetched (45.2 MiB download, 180.3 MiB unpacked):
+//   /nix/store/1xz3w...-firefox-129.0.2
+
+var patNixStorePath = regexp.MustCompile(`^/nix/store/[0-9a-z]{32}-(.+?)(?:\.drv)?$`)
+
+// nixosDriver handles NixOS.
+type nixosDriver struct{}
+
+func init() {
+	RegisterDriver(nixosDriver{}, "nixos")
+}
+
+func (nixosDriver) Family() string     { return "nixos" }
+func (nixosDriver) UpdateCmd() string  { return nixosUpdateCmd }
+func (nixosDriver) UpgradeCmd() string { return nixosUpgradeCmd }
+func (nixosDriver) RebootCmd() string  { return nixosRebootCmd }
+func (nixosDriver) PackageCmd() string { return nixosPackageCmd }
+
+// ParseUpdates reports the store paths nixos-rebuild would build or fetch.
+// Many of them are bits of configuration rather than packages, we skip
+// those that do not have a version.
+func (nixosDriver) ParseUpdates(output []string) []*model.PackageUpdate {
+	var (
+		updates = make([]*model.PackageUpdate, 0)
+		seen    = make(map[string]bool)
+	)
+
+	for _, pkg := range parsePackagesNix(output) {
+		var key = pkg.Name + "-" + pkg.Version
+
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		updates = append(updates, &model.PackageUpdate{
+			Name:       pkg.Name,
+			NewVersion: pkg.Version,
+		})
+	}
+
+	return updates
+} // func (nixosDriver) ParseUpdates(output []string) []*model.PackageUpdate
+
+func (nixosDriver) ExitStatus(_ string, status int) CmdStatus {
+	return exitOK(status)
+} // func (nixosDriver) ExitStatus(_ string, status int) CmdStatus
+
+// ParseReboot compares the kernel we booted to that of the current system.
+func (nixosDriver) ParseReboot(output []string, status int) bool {
+	var lines = nonEmpty(output)
+
+	if status != 0 || len(lines) < 2 {
+		return false
+	}
+
+	return lines[0] != lines[1]
+} // func (nixosDriver) ParseReboot(output []string, status int) bool
+
+func (nixosDriver) ParsePackages(output []string) []*model.Package {
+	return parsePackagesNix(output)
+} // func (nixosDriver) ParsePackages(output []string) []*model.Package
+
+// parsePackagesNix extracts name and version from a list of store paths,
+// skipping those without a version.
+func parsePackagesNix(output []string) []*model.Package {
+	var pkgs = make([]*model.Package, 0, len(output))
+
+	for _, l := range nonEmpty(output) {
+		var match, pkg []string
+
+		if match = patNixStorePath.FindStringSubmatch(strings.TrimSpace(l)); match == nil {
+			continue
+		} else if pkg = patPkgVersion.FindStringSubmatch(match[1]); pkg == nil {
+			continue
+		}
+
+		pkgs = append(pkgs, &model.Package{Name: pkg[1], Version: pkg[2]})
+	}
+
+	return pkgs
+} // func parsePackagesNix(output []string) []*model.Package
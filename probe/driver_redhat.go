@@ -0,0 +1,96 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/driver_redhat.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:26:44 krylon>
+
+package probe
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/blicero/carebear/model"
+)
+
+const (
+	dnfUpdateCmd   = "env DNF5_FORCE_INTERACTIVE=0 dnf check-upgrade"
+	dnfSecurityCmd = "env DNF5_FORCE_INTERACTIVE=0 dnf check-upgrade --security"
+	dnfUpgradeCmd  = "dnf -y upgrade"
+	dnfRebootCmd   = "dnf needs-restarting -r"
+	rpmPackageCmd  = "rpm -qa --queryformat '%{NAME}\\t%{VERSION}-%{RELEASE}\\t%{ARCH}\\n'"
+)
+
+// Sample output:
+// kernel.x86_64    6.10.5-200.fc40    updates
+
+var patUpdateDNF = regexp.MustCompile(`\s+`)
+
+// dnfDriver handles Fedora and the Red Hat family, i.e. anything that uses dnf.
+type dnfDriver struct{}
+
+func init() {
+	RegisterDriver(dnfDriver{}, "fedora", "rhel", "centos")
+}
+
+func (dnfDriver) Family() string      { return "fedora" }
+func (dnfDriver) UpdateCmd() string   { return dnfUpdateCmd }
+func (dnfDriver) UpgradeCmd() string  { return dnfUpgradeCmd }
+func (dnfDriver) RebootCmd() string   { return dnfRebootCmd }
+func (dnfDriver) PackageCmd() string  { return rpmPackageCmd }
+func (dnfDriver) SecurityCmd() string { return dnfSecurityCmd }
+
+func (dnfDriver) ParseUpdates(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0)
+
+	for _, l := range output {
+		var pieces = patUpdateDNF.Split(strings.TrimSpace(l), -1)
+		if len(pieces) == 3 {
+			var upd = &model.PackageUpdate{
+				Name:       pieces[0],
+				NewVersion: pieces[1],
+				Repo:       pieces[2],
+			}
+
+			if idx := strings.LastIndex(upd.Name, "."); idx > 0 {
+				upd.Arch = upd.Name[idx+1:]
+				upd.Name = upd.Name[:idx]
+			}
+
+			updates = append(updates, upd)
+		}
+	}
+
+	return updates
+} // func (dnfDriver) ParseUpdates(output []string) []*model.PackageUpdate
+
+// ParseSecurity parses the output of "dnf check-upgrade --security", which
+// looks just like the full list, only shorter.
+func (drv dnfDriver) ParseSecurity(output []string) []*model.PackageUpdate {
+	return drv.ParseUpdates(output)
+} // func (drv dnfDriver) ParseSecurity(output []string) []*model.PackageUpdate
+
+func (dnfDriver) ExitStatus(cmd string, status int) CmdStatus {
+	switch cmd {
+	case dnfUpdateCmd, dnfSecurityCmd:
+		// dnf check-upgrade exits with status 100 if there are updates available.
+		if status == 100 {
+			return CmdOK
+		}
+	case dnfRebootCmd:
+		// needs-restarting -r exits with status 1 if a reboot is required.
+		if status == 1 {
+			return CmdOK
+		}
+	}
+
+	return exitOK(status)
+} // func (dnfDriver) ExitStatus(cmd string, status int) CmdStatus
+
+func (dnfDriver) ParseReboot(_ []string, status int) bool {
+	return status == 1
+} // func (dnfDriver) ParseReboot(_ []string, status int) bool
+
+func (dnfDriver) ParsePackages(output []string) []*model.Package {
+	return parsePackagesTab(output)
+} // func (dnfDriver) ParsePackages(output []string) []*model.Package
@@ -0,0 +1,120 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/driver_suse.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:21:40 krylon>
+
+package probe
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/blicero/carebear/model"
+)
+
+const (
+	suseUpdateCmd   = "zypper lu"
+	suseUpgradeCmd  = "zypper -n up"
+	suseRebootCmd   = "zypper needs-rebooting"
+	suseSecurityCmd = "zypper list-patches --category security"
+)
+
+// Sample output of list-patches:
+// repo-update | openSUSE-SLE-15.6-2025-1234 | security | important | --- | needed | Security update for curl
+
+var (
+	patUpdateSuse      = regexp.MustCompile(`\s+\|\s+`)
+	patPatchSummary    = regexp.MustCompile(`^(?i:security update for)\s+(.+)$`)
+	patPatchSummarySep = regexp.MustCompile(`\s*,\s*|\s+and\s+`)
+)
+
+// suseDriver handles openSUSE and SLES.
+type suseDriver struct{}
+
+func init() {
+	RegisterDriver(suseDriver{}, "suse", "opensuse", "sles")
+}
+
+func (suseDriver) Family() string      { return "suse" }
+func (suseDriver) UpdateCmd() string   { return suseUpdateCmd }
+func (suseDriver) UpgradeCmd() string  { return suseUpgradeCmd }
+func (suseDriver) RebootCmd() string   { return suseRebootCmd }
+func (suseDriver) PackageCmd() string  { return rpmPackageCmd }
+func (suseDriver) SecurityCmd() string { return suseSecurityCmd }
+
+// ParseUpdates picks the package lines from the table zypper prints.
+// They start with a "v" in the status column, the header and separator
+// lines do not. The remaining columns are repository, name, current
+// version, available version and architecture.
+func (suseDriver) ParseUpdates(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0)
+
+	for _, l := range output {
+		l = strings.Trim(l, " \t\n")
+		var pieces = patUpdateSuse.Split(l, -1)
+		if len(pieces) == 6 && pieces[0] == "v" {
+			var upd = &model.PackageUpdate{
+				Repo:           pieces[1],
+				Name:           pieces[2],
+				CurrentVersion: pieces[3],
+				NewVersion:     pieces[4],
+				Arch:           pieces[5],
+			}
+			updates = append(updates, upd)
+		}
+	}
+
+	return updates
+} // func (suseDriver) ParseUpdates(output []string) []*model.PackageUpdate
+
+// ParseSecurity picks the needed security patches from the table zypper
+// prints. Patches are not packages, but their summary usually names the
+// packages they update, e.g. "Security update for curl". If it does not,
+// we report the patch itself.
+func (suseDriver) ParseSecurity(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0)
+
+	for _, l := range output {
+		var pieces = patUpdateSuse.Split(strings.Trim(l, " \t\n"), -1)
+		if len(pieces) != 7 || pieces[2] != "security" || pieces[5] != "needed" {
+			continue
+		}
+
+		var names = []string{pieces[1]}
+
+		if match := patPatchSummary.FindStringSubmatch(pieces[6]); match != nil {
+			names = patPatchSummarySep.Split(match[1], -1)
+		}
+
+		for _, n := range names {
+			updates = append(updates, &model.PackageUpdate{
+				Name: n,
+				Repo: pieces[1],
+			})
+		}
+	}
+
+	return updates
+} // func (suseDriver) ParseSecurity(output []string) []*model.PackageUpdate
+
+func (suseDriver) ExitStatus(cmd string, status int) CmdStatus {
+	if cmd == suseRebootCmd && status == 102 {
+		// zypper needs-rebooting exits with status 102 if a reboot is required.
+		return CmdOK
+	} else if cmd == suseSecurityCmd && (status == 100 || status == 101) {
+		// zypper list-patches exits with status 100 if patches are
+		// needed, and with 101 if any of them are security patches.
+		return CmdOK
+	}
+
+	return exitOK(status)
+} // func (suseDriver) ExitStatus(cmd string, status int) CmdStatus
+
+func (suseDriver) ParseReboot(_ []string, status int) bool {
+	return status == 102
+} // func (suseDriver) ParseReboot(_ []string, status int) bool
+
+func (suseDriver) ParsePackages(output []string) []*model.Package {
+	return parsePackagesTab(output)
+} // func (suseDriver) ParsePackages(output []string) []*model.Package
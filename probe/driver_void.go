@@ -0,0 +1,95 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/driver_void.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:39:19 krylon>
+
+package probe
+
+import (
+	"strings"
+
+	"github.com/blicero/carebear/model"
+)
+
+// xbps-install needs root privileges to sync the repository index, even
+// if it is only asked what it would do.
+const (
+	voidUpdateCmd  = rootPrefix + "xbps-install -Sun"
+	voidUpgradeCmd = "xbps-install -Syu"
+	voidPackageCmd = "xbps-query -l"
+)
+
+// Sample output:
+// firefox-128.0_1 update x86_64 https://repo-default.voidlinux.org/current 250363392 72138864
+// ii  bash-5.2.21_1     GNU Bourne Again Shell
+
+// voidDriver handles Void Linux.
+type voidDriver struct{}
+
+func init() {
+	RegisterDriver(voidDriver{}, "void")
+}
+
+func (voidDriver) Family() string     { return "void" }
+func (voidDriver) UpdateCmd() string  { return voidUpdateCmd }
+func (voidDriver) UpgradeCmd() string { return voidUpgradeCmd }
+func (voidDriver) RebootCmd() string  { return "" }
+func (voidDriver) PackageCmd() string { return voidPackageCmd }
+
+// ParseUpdates lists the packages xbps-install would update or install.
+// It does not tell us the installed version.
+func (voidDriver) ParseUpdates(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0)
+
+	for _, l := range nonEmpty(output) {
+		var (
+			pkg    []string
+			fields = strings.Fields(l)
+		)
+
+		if len(fields) < 4 || (fields[1] != "update" && fields[1] != "install") {
+			continue
+		} else if pkg = patPkgVersion.FindStringSubmatch(fields[0]); pkg == nil {
+			continue
+		}
+
+		updates = append(updates, &model.PackageUpdate{
+			Name:       pkg[1],
+			NewVersion: pkg[2],
+			Arch:       fields[2],
+			Repo:       fields[3],
+		})
+	}
+
+	return updates
+} // func (voidDriver) ParseUpdates(output []string) []*model.PackageUpdate
+
+func (voidDriver) ExitStatus(_ string, status int) CmdStatus {
+	return exitOK(status)
+} // func (voidDriver) ExitStatus(_ string, status int) CmdStatus
+
+func (voidDriver) ParseReboot(_ []string, _ int) bool {
+	return false
+} // func (voidDriver) ParseReboot(_ []string, _ int) bool
+
+func (voidDriver) ParsePackages(output []string) []*model.Package {
+	var pkgs = make([]*model.Package, 0, len(output))
+
+	for _, l := range nonEmpty(output) {
+		var (
+			match  []string
+			fields = strings.Fields(l)
+		)
+
+		if len(fields) < 2 {
+			continue
+		} else if match = patPkgVersion.FindStringSubmatch(fields[1]); match == nil {
+			continue
+		}
+
+		pkgs = append(pkgs, &model.Package{Name: match[1], Version: match[2]})
+	}
+
+	return pkgs
+} // func (voidDriver) ParsePackages(output []string) []*model.Package
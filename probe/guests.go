@@ -0,0 +1,283 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/guests.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:10:12 krylon>
+
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/carebear/model"
+)
+
+// We first ask the shell which of the tools are installed, so we do not
+// mistake a missing tool for a failure. POSIX command -v only looks at its
+// first argument, so we ask about one tool at a time. Docker, system-wide
+// Podman containers and libvirt's system VMs are only visible to root, jls
+// works for any user.
+const (
+	guestToolsCmd = `for t in docker podman virsh jls; do command -v "$t"; done; true`
+	dockerPsCmd   = "docker ps -a --format json"
+	podmanPsCmd   = "podman ps -a --format json"
+	virshListCmd  = "virsh list --all"
+	jlsCmd        = "jls --libxo json"
+)
+
+// guestTool lists the guests known to one container or VM manager.
+type guestTool struct {
+	kind  string
+	cmd   string
+	parse func(output []string, now time.Time) ([]model.Guest, error)
+}
+
+var guestTools = map[string]guestTool{
+	"docker": {kind: model.GuestDocker, cmd: asRoot(dockerPsCmd), parse: parseContainers},
+	"podman": {kind: model.GuestPodman, cmd: asRoot(podmanPsCmd), parse: parseContainers},
+	"virsh":  {kind: model.GuestLibvirt, cmd: asRoot(virshListCmd), parse: parseVirsh},
+	"jls":    {kind: model.GuestJail, cmd: jlsCmd, parse: parseJls},
+}
+
+// QueryGuests asks the given Device for the containers, VMs and jails it
+// runs. If one of the tools fails, e.g. because the Docker daemon is not
+// running, we log the error and carry on with the others.
+func (p *Probe) QueryGuests(ctx context.Context, d *model.Device) (*model.Guests, error) {
+	var (
+		err    error
+		tools  []string
+		guests = &model.Guests{
+			DevID:     d.ID,
+			Timestamp: time.Now(),
+			Guests:    make([]model.Guest, 0),
+		}
+	)
+
+	// command -v fails for the tools that are missing, the loop prints
+	// the ones it found and succeeds regardless.
+	if tools, _, err = p.runCommand(ctx, d, guestToolsCmd); err != nil {
+		return nil, err
+	}
+
+	for _, l := range nonEmpty(tools) {
+		var (
+			ok     bool
+			tool   guestTool
+			output []string
+			list   []model.Guest
+		)
+
+		if tool, ok = guestTools[path.Base(l)]; !ok {
+			continue
+		} else if output, err = p.executeCommand(ctx, d, tool.cmd); err != nil {
+			continue
+		} else if list, err = tool.parse(output, guests.Timestamp); err != nil {
+			p.log.Printf("[ERROR] Cannot parse list of %s guests on %s: %s\n",
+				tool.kind,
+				d.Name,
+				err.Error())
+			continue
+		}
+
+		for i := range list {
+			list[i].Kind = tool.kind
+		}
+
+		guests.Guests = append(guests.Guests, list...)
+	}
+
+	return guests, nil
+} // func (p *Probe) QueryGuests(ctx context.Context, d *model.Device) (*model.Guests, error)
+
+// container is an entry in the output of docker ps or podman ps. Docker
+// gives the names as a comma-separated string, Podman as an array. Only
+// Podman tells us when the container was started, for Docker, we have to
+// make do with the Status, e.g. "Up 2 hours".
+type container struct {
+	Names     json.RawMessage
+	Image     string
+	State     string
+	Status    string
+	StartedAt int64
+}
+
+func (c *container) name() string {
+	var (
+		str  string
+		list []string
+	)
+
+	if json.Unmarshal(c.Names, &str) == nil {
+		list = strings.Split(str, ",")
+	} else if json.Unmarshal(c.Names, &list) != nil {
+		return ""
+	}
+
+	if len(list) == 0 {
+		return ""
+	}
+
+	return strings.TrimPrefix(list[0], "/")
+} // func (c *container) name() string
+
+// parseContainers parses the output of docker ps --format json, which is
+// one object per line, and of podman ps --format json, which is an array.
+func parseContainers(output []string, now time.Time) ([]model.Guest, error) {
+	var (
+		list   []container
+		text   = bytes.TrimSpace([]byte(strings.Join(output, "\n")))
+		guests = make([]model.Guest, 0)
+	)
+
+	if len(text) == 0 {
+		return guests, nil
+	} else if text[0] == '[' {
+		if err := json.Unmarshal(text, &list); err != nil {
+			return nil, fmt.Errorf("Cannot parse list of containers: %w", err)
+		}
+	} else {
+		var dec = json.NewDecoder(bytes.NewReader(text))
+
+		for dec.More() {
+			var c container
+
+			if err := dec.Decode(&c); err != nil {
+				return nil, fmt.Errorf("Cannot parse list of containers: %w", err)
+			}
+
+			list = append(list, c)
+		}
+	}
+
+	for _, c := range list {
+		var g = model.Guest{
+			Name:  c.name(),
+			Image: c.Image,
+			State: strings.ToLower(c.State),
+		}
+
+		if g.Running() {
+			if c.StartedAt > 0 {
+				g.Started = time.Unix(c.StartedAt, 0)
+			} else if up, ok := parseHumanDuration(strings.TrimPrefix(c.Status, "Up ")); ok {
+				g.Started = now.Add(-up)
+			}
+		}
+
+		guests = append(guests, g)
+	}
+
+	return guests, nil
+} // func parseContainers(output []string, now time.Time) ([]model.Guest, error)
+
+// Docker spells durations the way its go-units package does, e.g.
+// "About an hour", "3 days" or "Less than a second". The Status of a
+// container may have more to say after the duration, e.g. "(healthy)".
+
+var patHumanDuration = regexp.MustCompile(`^(?:(\d+)|About an?|Less than a) (second|minute|hour|day|week|month|year)s?\b`)
+
+var humanUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    time.Hour * 24,
+	"week":   time.Hour * 24 * 7,
+	"month":  time.Hour * 24 * 30,
+	"year":   time.Hour * 24 * 365,
+}
+
+// parseHumanDuration turns a duration spelled out by Docker into a
+// time.Duration. It is only ever approximately right.
+func parseHumanDuration(s string) (time.Duration, bool) {
+	var match []string
+
+	if match = patHumanDuration.FindStringSubmatch(s); match == nil {
+		return 0, false
+	} else if match[1] == "" {
+		return humanUnits[match[2]], true
+	}
+
+	var n, err = strconv.ParseInt(match[1], 10, 64)
+
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Duration(n) * humanUnits[match[2]], true
+} // func parseHumanDuration(s string) (time.Duration, bool)
+
+// Sample output:
+//  Id   Name     State
+// -------------------------
+//  1    web      running
+//  -    backup   shut off
+
+// parseVirsh parses the output of virsh list --all. virsh does not tell us
+// when a domain was started.
+func parseVirsh(output []string, _ time.Time) ([]model.Guest, error) {
+	var guests = make([]model.Guest, 0)
+
+	for _, l := range nonEmpty(output) {
+		var fields = strings.Fields(l)
+
+		if len(fields) < 3 || fields[0] == "Id" || strings.HasPrefix(l, "---") {
+			continue
+		}
+
+		guests = append(guests, model.Guest{
+			Name:  fields[1],
+			State: strings.Join(fields[2:], " "),
+		})
+	}
+
+	return guests, nil
+} // func parseVirsh(output []string, _ time.Time) ([]model.Guest, error)
+
+// jail is an entry in the output of jls --libxo json.
+type jail struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	Path     string `json:"path"`
+}
+
+// parseJls parses the output of jls --libxo json. jls only lists running
+// jails, and does not tell us when they were started. Jails have no image,
+// the closest thing is the path of their root directory.
+func parseJls(output []string, _ time.Time) ([]model.Guest, error) {
+	var (
+		doc struct {
+			Info struct {
+				Jails []jail `json:"jail"`
+			} `json:"jail-information"`
+		}
+		guests = make([]model.Guest, 0)
+	)
+
+	if err := json.Unmarshal([]byte(strings.Join(output, "\n")), &doc); err != nil {
+		return nil, fmt.Errorf("Cannot parse list of jails: %w", err)
+	}
+
+	for _, j := range doc.Info.Jails {
+		var g = model.Guest{
+			Name:  j.Name,
+			Image: j.Path,
+			State: model.GuestStateRunning,
+		}
+
+		if g.Name == "" {
+			g.Name = j.Hostname
+		}
+
+		guests = append(guests, g)
+	}
+
+	return guests, nil
+} // func parseJls(output []string, _ time.Time) ([]model.Guest, error)
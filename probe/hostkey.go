@@ -0,0 +1,160 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/hostkey.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:25:29 krylon>
+
+package probe
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/blicero/carebear/model"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ErrHostKeyMismatch indicates that a Device presented an SSH host key that
+// differs from the one we trust for it.
+var ErrHostKeyMismatch = errors.New("SSH host key does not match the one on record")
+
+// hostKeyCallback returns a HostKeyCallback that verifies the host key
+// presented by the given Device.
+//
+// If a known_hosts file is configured, we consult it first. Keys it does
+// not know about are handled on a trust-on-first-use basis: The first key
+// a Device presents is stored in the database and trusted, any different key
+// after that is recorded, too, but the connection is refused until the user
+// accepts the new key through the web interface. A key that contradicts
+// known_hosts is recorded as untrusted, too, so the user gets to see it,
+// but since known_hosts has the final say, accepting it does not help.
+//
+// The callback runs during the SSH handshake, possibly for several Devices
+// at once, so it takes the Probe's dbLock to use the database.
+func (p *Probe) hostKeyCallback(d *model.Device) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		var (
+			err      error
+			keys     []*model.HostKey
+			found    *model.HostKey
+			mismatch bool
+			fp       = ssh.FingerprintSHA256(key)
+			now      = time.Now()
+		)
+
+		if p.known != nil {
+			var kerr *knownhosts.KeyError
+
+			// known_hosts has the final say, if it knows the key, we do
+			// not care what the database thinks of it.
+			if err = p.known(hostname, remote, key); err == nil {
+				p.log.Printf("[TRACE] Host key %s of %s was found in known_hosts\n",
+					fp,
+					d.Name)
+				return nil
+			} else if errors.As(err, &kerr) && len(kerr.Want) > 0 {
+				p.log.Printf("[CRITICAL] Host key of %s (%s) does not match known_hosts: %s\n",
+					d.Name,
+					hostname,
+					fp)
+				mismatch = true
+			} else if !errors.As(err, &kerr) {
+				p.log.Printf("[ERROR] Failed to check host key of %s against known_hosts: %s\n",
+					d.Name,
+					err.Error())
+				return err
+			}
+		}
+
+		p.dbLock.Lock()
+		defer p.dbLock.Unlock()
+
+		if keys, err = p.db.HostKeyGetByDevice(d); err != nil {
+			p.log.Printf("[ERROR] Failed to load host keys for %s: %s\n",
+				d.Name,
+				err.Error())
+			if mismatch {
+				return fmt.Errorf("%w: %s presented %s",
+					ErrHostKeyMismatch,
+					d.Name,
+					fp)
+			}
+			return err
+		}
+
+		for _, k := range keys {
+			if k.Fingerprint == fp {
+				found = k
+				break
+			}
+		}
+
+		if found != nil {
+			if err = p.db.HostKeyUpdateLastSeen(found, now); err != nil {
+				p.log.Printf("[ERROR] Failed to update timestamp of host key %s: %s\n",
+					fp,
+					err.Error())
+			}
+
+			if mismatch {
+				return fmt.Errorf("%w: %s presented %s",
+					ErrHostKeyMismatch,
+					d.Name,
+					fp)
+			} else if found.Trusted {
+				return nil
+			}
+
+			p.log.Printf("[CRITICAL] %s presented host key %s, which has not been accepted, yet\n",
+				d.Name,
+				fp)
+			return fmt.Errorf("%w: %s presented %s",
+				ErrHostKeyMismatch,
+				d.Name,
+				fp)
+		}
+
+		var hk = &model.HostKey{
+			DevID:       d.ID,
+			KeyType:     key.Type(),
+			Fingerprint: fp,
+			Key:         base64.StdEncoding.EncodeToString(key.Marshal()),
+			FirstSeen:   now,
+			LastSeen:    now,
+			Trusted:     len(keys) == 0 && !mismatch,
+		}
+
+		if err = p.db.HostKeyAdd(hk); err != nil && !mismatch {
+			p.log.Printf("[ERROR] Failed to record host key %s of %s: %s\n",
+				fp,
+				d.Name,
+				err.Error())
+			return err
+		} else if mismatch {
+			return fmt.Errorf("%w: %s presented %s",
+				ErrHostKeyMismatch,
+				d.Name,
+				fp)
+		} else if hk.Trusted {
+			p.log.Printf("[INFO] Trusting host key %s %s of %s on first use\n",
+				hk.KeyType,
+				fp,
+				d.Name)
+			return nil
+		}
+
+		p.log.Printf("[CRITICAL] HOST KEY OF %s HAS CHANGED! Got %s %s, refusing to connect.\n",
+			d.Name,
+			hk.KeyType,
+			fp)
+
+		return fmt.Errorf("%w: %s presented %s",
+			ErrHostKeyMismatch,
+			d.Name,
+			fp)
+	}
+} // func (p *Probe) hostKeyCallback(d *model.Device) ssh.HostKeyCallback
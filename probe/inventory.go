@@ -0,0 +1,152 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/inventory.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:26:50 krylon>
+
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/carebear/model"
+)
+
+// The inventory commands print one key=value pair per line. Any piece of
+// information that is not available on a system is simply left empty.
+// systemd-detect-virt prints "none" on bare metal, FreeBSD's kern.vm_guest
+// does the same. OpenBSD has no way to tell, but hw.vendor and hw.product
+// usually give it away. macOS has neither /proc nor DMI, but sysctl knows
+// all we need.
+const (
+	invCmdLinux = `echo "kernel=$(uname -r)"
+echo "arch=$(uname -m)"
+echo "version=$(. /etc/os-release 2>/dev/null; echo $VERSION_ID)"
+echo "cpu=$(grep -m1 -E '^(model name|Hardware|cpu model)' /proc/cpuinfo | cut -d: -f2-)"
+echo "cores=$(nproc 2>/dev/null)"
+echo "ram=$(( $(awk '/^MemTotal:/ { print $2 }' /proc/meminfo) * 1024 ))"
+echo "virt=$(systemd-detect-virt 2>/dev/null)"
+echo "vendor=$(cat /sys/class/dmi/id/sys_vendor 2>/dev/null)"
+echo "product=$(cat /sys/class/dmi/id/product_name 2>/dev/null)"`
+	invCmdFreeBSD = `echo "kernel=$(uname -r)"
+echo "arch=$(uname -m)"
+echo "version=$(freebsd-version -u)"
+echo "cpu=$(sysctl -n hw.model)"
+echo "cores=$(sysctl -n hw.ncpu)"
+echo "ram=$(sysctl -n hw.physmem)"
+echo "virt=$(sysctl -n kern.vm_guest 2>/dev/null)"
+echo "vendor=$(kenv -q smbios.system.maker)"
+echo "product=$(kenv -q smbios.system.product)"`
+	invCmdOpenBSD = `echo "kernel=$(uname -v)"
+echo "arch=$(uname -m)"
+echo "version=$(uname -r)"
+echo "cpu=$(sysctl -n hw.model)"
+echo "cores=$(sysctl -n hw.ncpu)"
+echo "ram=$(sysctl -n hw.physmem)"
+echo "vendor=$(sysctl -n hw.vendor 2>/dev/null)"
+echo "product=$(sysctl -n hw.product 2>/dev/null)"`
+	invCmdDarwin = `echo "kernel=$(uname -r)"
+echo "arch=$(uname -m)"
+echo "version=$(sw_vers -productVersion)"
+echo "cpu=$(sysctl -n machdep.cpu.brand_string)"
+echo "cores=$(sysctl -n hw.ncpu)"
+echo "ram=$(sysctl -n hw.memsize)"
+echo "vendor=Apple"
+echo "product=$(sysctl -n hw.model)"`
+)
+
+// QueryInventory asks the given Device about its hardware and software.
+func (p *Probe) QueryInventory(ctx context.Context, d *model.Device) (*model.Inventory, error) {
+	var (
+		err    error
+		output []string
+		inv    *model.Inventory
+	)
+
+	if output, err = p.executeCommand(ctx, d, inventoryCmd(d)); err != nil {
+		return nil, err
+	} else if inv, err = parseInventory(output); err != nil {
+		var ex = fmt.Errorf("Cannot parse inventory of %s: %w",
+			d.Name,
+			err)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	inv.DevID = d.ID
+	inv.Timestamp = time.Now()
+
+	return inv, nil
+} // func (p *Probe) QueryInventory(ctx context.Context, d *model.Device) (*model.Inventory, error)
+
+// inventoryCmd returns the inventory command for the given Device's OS.
+func inventoryCmd(d *model.Device) string {
+	switch strings.ToLower(d.OSID) {
+	case "freebsd", "dragonfly":
+		return invCmdFreeBSD
+	case "openbsd", "netbsd":
+		return invCmdOpenBSD
+	case "darwin", "macos":
+		return invCmdDarwin
+	default:
+		return invCmdLinux
+	}
+} // func inventoryCmd(d *model.Device) string
+
+// parseInventory parses the output of the inventory commands.
+func parseInventory(output []string) (*model.Inventory, error) {
+	var (
+		err error
+		inv = new(model.Inventory)
+	)
+
+	for _, l := range nonEmpty(output) {
+		var key, val, ok = strings.Cut(l, "=")
+
+		if !ok {
+			continue
+		}
+
+		val = strings.TrimSpace(val)
+
+		switch key {
+		case "kernel":
+			inv.Kernel = val
+		case "arch":
+			inv.Arch = val
+		case "version":
+			inv.OSVersion = val
+		case "cpu":
+			inv.CPUModel = strings.Join(strings.Fields(val), " ")
+		case "cores":
+			if val != "" {
+				if inv.CPUCores, err = strconv.ParseInt(val, 10, 64); err != nil {
+					return nil, fmt.Errorf("Cannot parse number of CPU cores %q: %w", val, err)
+				}
+			}
+		case "ram":
+			if val != "" {
+				if inv.RAM, err = strconv.ParseInt(val, 10, 64); err != nil {
+					return nil, fmt.Errorf("Cannot parse size of RAM %q: %w", val, err)
+				}
+			}
+		case "virt":
+			inv.Virt = val
+		case "vendor":
+			inv.Vendor = val
+		case "product":
+			inv.Product = val
+		}
+	}
+
+	if inv.Kernel == "" {
+		return nil, errors.New("kernel version is missing")
+	}
+
+	return inv, nil
+} // func parseInventory(output []string) (*model.Inventory, error)
@@ -0,0 +1,165 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/memory.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:08:49 krylon>
+
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/carebear/model"
+)
+
+// On the BSDs, hw.physmem tells us how much RAM there is, vmstat -s how
+// much of it is free, and swapctl how much swap space is in use. Not every
+// system has swap, so we ignore it if swapctl fails.
+const (
+	memCmdLinux = "cat /proc/meminfo"
+	memCmdBSD   = "sysctl -n hw.physmem && vmstat -s && { swapctl -sk 2>/dev/null; true; }"
+)
+
+// Sample output:
+// MemTotal:       32803308 kB
+//      4096 bytes per page
+//    123456 pages free
+// Total:         2097152        0
+// total: 1048576 1K-blocks allocated, 0 used, 1048576 available
+
+var (
+	patMeminfo = regexp.MustCompile(`^(\w+):\s+(\d+)(?:\s+kB)?$`)
+	patVmstat  = regexp.MustCompile(`^(\d+)\s+(bytes per page|pages free|pages inactive|pages in (?:the )?cache)$`)
+	patSwapctl = regexp.MustCompile(`^(?i:total):\s+(\d+)(?:\s+1K-blocks allocated,)?\s+(\d+)`)
+)
+
+// QueryMemory asks the given Device how much RAM and swap space it has and
+// how much of it is in use.
+func (p *Probe) QueryMemory(ctx context.Context, d *model.Device) (*model.Memory, error) {
+	var (
+		err    error
+		cmd    string
+		output []string
+		mem    *model.Memory
+	)
+
+	if isBSD(d) {
+		cmd = memCmdBSD
+	} else {
+		cmd = memCmdLinux
+	}
+
+	if output, err = p.executeCommand(ctx, d, cmd); err != nil {
+		return nil, err
+	}
+
+	if cmd == memCmdLinux {
+		mem, err = parseMeminfo(output)
+	} else {
+		mem, err = parseMemoryBSD(output)
+	}
+
+	if err != nil {
+		var ex = fmt.Errorf("Cannot parse memory usage of %s: %w",
+			d.Name,
+			err)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	mem.DevID = d.ID
+	mem.Timestamp = time.Now()
+
+	return mem, nil
+} // func (p *Probe) QueryMemory(ctx context.Context, d *model.Device) (*model.Memory, error)
+
+// parseMeminfo parses /proc/meminfo. Kernels older than 3.14 do not report
+// MemAvailable, in that case we estimate it from the free memory and the
+// caches.
+func parseMeminfo(output []string) (*model.Memory, error) {
+	var vals = make(map[string]int64, len(output))
+
+	for _, l := range nonEmpty(output) {
+		var match []string
+
+		if match = patMeminfo.FindStringSubmatch(l); match == nil {
+			continue
+		}
+
+		var n, err = strconv.ParseInt(match[2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		vals[match[1]] = n * 1024
+	}
+
+	var (
+		ok  bool
+		mem = new(model.Memory)
+	)
+
+	if mem.Total, ok = vals["MemTotal"]; !ok {
+		return nil, errors.New("MemTotal is missing from /proc/meminfo")
+	} else if mem.Available, ok = vals["MemAvailable"]; !ok {
+		mem.Available = vals["MemFree"] + vals["Buffers"] + vals["Cached"]
+	}
+
+	mem.SwapTotal = vals["SwapTotal"]
+	mem.SwapUsed = vals["SwapTotal"] - vals["SwapFree"]
+
+	return mem, nil
+} // func parseMeminfo(output []string) (*model.Memory, error)
+
+// parseMemoryBSD parses the output of memCmdBSD. We count inactive and
+// cached pages as available, since the kernel reclaims them as needed.
+func parseMemoryBSD(output []string) (*model.Memory, error) {
+	var (
+		err             error
+		pageSize, pages int64
+		lines           = nonEmpty(output)
+		mem             = new(model.Memory)
+	)
+
+	if len(lines) == 0 {
+		return nil, errors.New("no output")
+	} else if mem.Total, err = strconv.ParseInt(lines[0], 10, 64); err != nil {
+		return nil, fmt.Errorf("Cannot parse hw.physmem %q: %w", lines[0], err)
+	}
+
+	for _, l := range lines[1:] {
+		var match []string
+
+		if match = patSwapctl.FindStringSubmatch(l); match != nil {
+			var (
+				total, _ = strconv.ParseInt(match[1], 10, 64)
+				used, _  = strconv.ParseInt(match[2], 10, 64)
+			)
+
+			mem.SwapTotal = total * 1024
+			mem.SwapUsed = used * 1024
+		} else if match = patVmstat.FindStringSubmatch(l); match != nil {
+			var n, _ = strconv.ParseInt(match[1], 10, 64)
+
+			if strings.HasPrefix(match[2], "bytes") {
+				pageSize = n
+			} else {
+				pages += n
+			}
+		}
+	}
+
+	if pageSize == 0 {
+		return nil, errors.New("vmstat -s did not report the page size")
+	}
+
+	mem.Available = pages * pageSize
+
+	return mem, nil
+} // func parseMemoryBSD(output []string) (*model.Memory, error)
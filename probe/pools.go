@@ -0,0 +1,220 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/pools.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:15:27 krylon>
+
+package probe
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/carebear/model"
+)
+
+// Most of our Devices have neither ZFS nor mdraid, so a missing zpool
+// command or /proc/mdstat is not an error, we just get no output.
+const (
+	poolCmdZFS = "command -v zpool >/dev/null || exit 0; zpool list -Hp -o name,size,allocated,health && echo '%%' && zpool status -p"
+	poolCmdMD  = "cat /proc/mdstat 2>/dev/null; true"
+)
+
+// zpoolTimeFormat is the format zpool status uses for timestamps, after
+// collapsing runs of whitespace.
+const zpoolTimeFormat = "Mon Jan 2 15:04:05 2006"
+
+// Sample output:
+//   pool: zroot
+//  state: ONLINE
+//   scan: scrub repaired 0 in 00:00:21 with 0 errors on Sun Oct 11 03:01:02 2026
+// 	zroot       ONLINE       0     0     0
+// errors: 3 data errors, use '-v' for a list
+//
+// md0 : active raid1 sdb1[1] sda1[0](F)
+//       976630464 blocks super 1.2 [2/1] [U_]
+//       [===>.................]  recovery = 15.2% (148477440/976630464) finish=72.1min speed=191290K/sec
+
+var (
+	patZpoolPool     = regexp.MustCompile(`^pool:\s+(\S+)$`)
+	patZpoolState    = regexp.MustCompile(`^state:\s+(\S+)$`)
+	patZpoolScrub    = regexp.MustCompile(`^scan:\s+scrub repaired .* on (.+)$`)
+	patZpoolScanning = regexp.MustCompile(`^scan:\s+(scrub|resilver) in progress`)
+	patZpoolDone     = regexp.MustCompile(`([\d.]+)% done`)
+	patZpoolVdev     = regexp.MustCompile(`^\S+\s+[A-Z]+\s+(\d+)\s+(\d+)\s+(\d+)`)
+	patZpoolErrors   = regexp.MustCompile(`^errors:\s+(\d+) data errors`)
+	patMdHead        = regexp.MustCompile(`^(md\S+)\s+:\s+(\w+)\s+(.*)$`)
+	patMdBlocks      = regexp.MustCompile(`^(\d+) blocks`)
+	patMdDisks       = regexp.MustCompile(`\[(\d+)/(\d+)\]`)
+	patMdProgress    = regexp.MustCompile(`(recovery|resync|reshape|check)\s*=\s*([\d.]+%)`)
+)
+
+// QueryPools asks the given Device about the state of its ZFS pools and,
+// on Linux, its mdraid arrays.
+func (p *Probe) QueryPools(ctx context.Context, d *model.Device) (*model.PoolStatus, error) {
+	var (
+		err    error
+		output []string
+		pools  []*model.Pool
+		ps     = &model.PoolStatus{
+			DevID:     d.ID,
+			Timestamp: time.Now(),
+		}
+	)
+
+	if output, err = p.executeCommand(ctx, d, poolCmdZFS); err != nil {
+		return nil, err
+	} else if pools, err = parseZpool(output, time.Local); err != nil {
+		var ex = fmt.Errorf("Cannot parse ZFS pool status of %s: %w",
+			d.Name,
+			err)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	ps.Pools = pools
+
+	if isBSD(d) {
+		return ps, nil
+	} else if output, err = p.executeCommand(ctx, d, poolCmdMD); err != nil {
+		return nil, err
+	}
+
+	ps.Pools = append(ps.Pools, parseMdstat(output)...)
+
+	return ps, nil
+} // func (p *Probe) QueryPools(ctx context.Context, d *model.Device) (*model.PoolStatus, error)
+
+// parseZpool parses the output of poolCmdZFS. The capacity and health of
+// each pool comes from zpool list, the scrub and error counters from
+// zpool status. zpool prints timestamps in the Device's local time, which
+// we assume to be the same as ours.
+func parseZpool(output []string, loc *time.Location) ([]*model.Pool, error) {
+	var (
+		err    error
+		pools  = make([]*model.Pool, 0, 2)
+		byName = make(map[string]*model.Pool)
+		status bool
+		cur    *model.Pool
+	)
+
+	for _, l := range nonEmpty(output) {
+		if l == "%%" {
+			status = true
+			continue
+		} else if !status {
+			var fields = strings.Split(l, "\t")
+
+			if len(fields) != 4 {
+				return nil, fmt.Errorf("Unexpected output of zpool list: %q", l)
+			}
+
+			var pool = &model.Pool{
+				Name:  fields[0],
+				Type:  "zfs",
+				State: fields[3],
+			}
+
+			if pool.Size, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+				return nil, fmt.Errorf("Cannot parse size of pool %s: %w", pool.Name, err)
+			} else if pool.Alloc, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+				return nil, fmt.Errorf("Cannot parse allocated space of pool %s: %w", pool.Name, err)
+			}
+
+			pools = append(pools, pool)
+			byName[pool.Name] = pool
+			continue
+		}
+
+		var match []string
+
+		if match = patZpoolPool.FindStringSubmatch(l); match != nil {
+			cur = byName[match[1]]
+		} else if cur == nil {
+			continue
+		} else if match = patZpoolState.FindStringSubmatch(l); match != nil {
+			cur.State = match[1]
+		} else if match = patZpoolScrub.FindStringSubmatch(l); match != nil {
+			var stamp = strings.Join(strings.Fields(match[1]), " ")
+
+			if cur.LastScrub, err = time.ParseInLocation(zpoolTimeFormat, stamp, loc); err != nil {
+				return nil, fmt.Errorf("Cannot parse time of last scrub of %s: %w", cur.Name, err)
+			}
+		} else if match = patZpoolScanning.FindStringSubmatch(l); match != nil {
+			cur.Progress = match[1] + " in progress"
+		} else if match = patZpoolDone.FindStringSubmatch(l); match != nil && cur.Progress != "" {
+			cur.Progress += ", " + match[1] + "% done"
+		} else if match = patZpoolVdev.FindStringSubmatch(l); match != nil {
+			for _, cnt := range match[1:] {
+				var n, _ = strconv.ParseInt(cnt, 10, 64)
+				cur.Errors += n
+			}
+		} else if match = patZpoolErrors.FindStringSubmatch(l); match != nil {
+			var n, _ = strconv.ParseInt(match[1], 10, 64)
+			cur.Errors += n
+		}
+	}
+
+	return pools, nil
+} // func parseZpool(output []string, loc *time.Location) ([]*model.Pool, error)
+
+// parseMdstat parses /proc/mdstat. An active array is ONLINE if all of its
+// disks are present, DEGRADED otherwise. Failed disks are marked with (F)
+// in the list of members. Inactive arrays do not tell us their RAID level.
+func parseMdstat(output []string) []*model.Pool {
+	var (
+		pools = make([]*model.Pool, 0)
+		cur   *model.Pool
+	)
+
+	for _, l := range nonEmpty(output) {
+		var match []string
+
+		if match = patMdHead.FindStringSubmatch(l); match != nil {
+			cur = &model.Pool{
+				Name:  match[1],
+				Type:  "md",
+				State: "ONLINE",
+			}
+
+			if match[2] != "active" {
+				cur.State = "INACTIVE"
+			}
+
+			for _, f := range strings.Fields(match[3]) {
+				if strings.HasPrefix(f, "(") {
+					// e.g. (auto-read-only)
+					continue
+				} else if !strings.Contains(f, "[") {
+					cur.Type = f
+				} else if strings.HasSuffix(f, "(F)") {
+					cur.Errors++
+				}
+			}
+
+			pools = append(pools, cur)
+			continue
+		} else if cur == nil {
+			continue
+		}
+
+		if match = patMdBlocks.FindStringSubmatch(l); match != nil {
+			var n, _ = strconv.ParseInt(match[1], 10, 64)
+			cur.Size = n * 1024
+		}
+
+		if match = patMdDisks.FindStringSubmatch(l); match != nil && match[1] != match[2] && cur.State == "ONLINE" {
+			cur.State = "DEGRADED"
+		}
+
+		if match = patMdProgress.FindStringSubmatch(l); match != nil {
+			cur.Progress = match[1] + " " + match[2]
+		}
+	}
+
+	return pools
+} // func parseMdstat(output []string) []*model.Pool
@@ -0,0 +1,227 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/ports.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:36:12 krylon>
+
+package probe
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/carebear/model"
+)
+
+// Without root privileges, ss and sockstat only tell us about the processes
+// of the user we log in as, so we escalate them. OpenBSD has no sockstat,
+// netstat gives us the ports, but not the processes.
+const (
+	portCmdLinux   = "ss -tulnpH"
+	portCmdBSD     = "sockstat -46l"
+	portCmdOpenBSD = "netstat -an -f inet && netstat -an -f inet6"
+)
+
+// Sample output:
+// tcp   LISTEN 0      4096         0.0.0.0:22        0.0.0.0:*    users:(("sshd",pid=1000,fd=3))
+// root     sshd       1234  4   tcp4   *:22                  *:*
+// tcp          0      0  127.0.0.1.25           *.*                    LISTEN
+
+var patSSUsers = regexp.MustCompile(`users:\(\("([^"]+)"`)
+
+// QueryPorts asks the given Device which ports it listens on.
+func (p *Probe) QueryPorts(ctx context.Context, d *model.Device) (*model.Ports, error) {
+	var (
+		err    error
+		cmd    string
+		output []string
+		parse  func([]string) ([]model.Listener, error)
+		ports  = &model.Ports{
+			DevID:     d.ID,
+			Timestamp: time.Now(),
+		}
+	)
+
+	switch strings.ToLower(d.OSID) {
+	case "openbsd":
+		cmd = portCmdOpenBSD
+		parse = parseNetstat
+	case "freebsd", "netbsd", "dragonfly":
+		cmd = asRoot(portCmdBSD)
+		parse = parseSockstat
+	default:
+		cmd = asRoot(portCmdLinux)
+		parse = parseSS
+	}
+
+	if output, err = p.executeCommand(ctx, d, cmd); err != nil {
+		return nil, err
+	} else if ports.Listeners, err = parse(output); err != nil {
+		var ex = fmt.Errorf("Cannot parse listening ports of %s: %w",
+			d.Name,
+			err)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	return ports, nil
+} // func (p *Probe) QueryPorts(ctx context.Context, d *model.Device) (*model.Ports, error)
+
+// splitHostPort splits an address of the form host<sep>port, where host may
+// be an IPv6 address in brackets.
+func splitHostPort(addr string, sep byte) (string, int64, error) {
+	var idx = strings.LastIndexByte(addr, sep)
+
+	if idx < 0 {
+		return "", 0, fmt.Errorf("Address %q has no port", addr)
+	}
+
+	var port, err = strconv.ParseInt(addr[idx+1:], 10, 64)
+
+	if err != nil {
+		return "", 0, fmt.Errorf("Cannot parse port of %q: %w", addr, err)
+	}
+
+	return strings.Trim(addr[:idx], "[]"), port, nil
+} // func splitHostPort(addr string, sep byte) (string, int64, error)
+
+// listenerSet collects Listeners, dropping duplicates, which we get e.g.
+// when several processes share a socket.
+type listenerSet map[string]model.Listener
+
+func (s listenerSet) add(l model.Listener) {
+	if _, ok := s[l.Key()]; !ok {
+		s[l.Key()] = l
+	}
+} // func (s listenerSet) add(l model.Listener)
+
+// list returns the Listeners sorted by protocol and port.
+func (s listenerSet) list() []model.Listener {
+	var list = make([]model.Listener, 0, len(s))
+
+	for _, l := range s {
+		list = append(list, l)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Proto != list[j].Proto {
+			return list[i].Proto < list[j].Proto
+		} else if list[i].Port != list[j].Port {
+			return list[i].Port < list[j].Port
+		}
+
+		return list[i].Addr < list[j].Addr
+	})
+
+	return list
+} // func (s listenerSet) list() []model.Listener
+
+// parseSS parses the output of ss -tulnpH.
+func parseSS(output []string) ([]model.Listener, error) {
+	var set = make(listenerSet)
+
+	for _, l := range nonEmpty(output) {
+		var (
+			err    error
+			fields = strings.Fields(l)
+			lst    model.Listener
+		)
+
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("Unexpected output of ss: %q", l)
+		}
+
+		lst.Proto = fields[0]
+
+		if lst.Addr, lst.Port, err = splitHostPort(fields[4], ':'); err != nil {
+			return nil, err
+		}
+
+		if match := patSSUsers.FindStringSubmatch(l); match != nil {
+			lst.Process = match[1]
+		}
+
+		set.add(lst)
+	}
+
+	return set.list(), nil
+} // func parseSS(output []string) ([]model.Listener, error)
+
+// parseSockstat parses the output of sockstat -46l.
+func parseSockstat(output []string) ([]model.Listener, error) {
+	var set = make(listenerSet)
+
+	for _, l := range nonEmpty(output) {
+		var (
+			err    error
+			fields = strings.Fields(l)
+			lst    model.Listener
+		)
+
+		if len(fields) < 6 || fields[0] == "USER" {
+			continue
+		} else if !strings.HasPrefix(fields[4], "tcp") &&
+			!strings.HasPrefix(fields[4], "udp") &&
+			!strings.HasPrefix(fields[4], "sctp") {
+			continue
+		}
+
+		lst.Proto = fields[4]
+		lst.Process = fields[1]
+
+		if lst.Addr, lst.Port, err = splitHostPort(fields[5], ':'); err != nil {
+			return nil, err
+		}
+
+		set.add(lst)
+	}
+
+	return set.list(), nil
+} // func parseSockstat(output []string) ([]model.Listener, error)
+
+// parseNetstat parses the output of netstat -an on OpenBSD. TCP sockets
+// are listening if they are in the LISTEN state, UDP sockets if they are
+// not connected to a peer.
+func parseNetstat(output []string) ([]model.Listener, error) {
+	var set = make(listenerSet)
+
+	for _, l := range nonEmpty(output) {
+		var (
+			err    error
+			fields = strings.Fields(l)
+			lst    model.Listener
+		)
+
+		if len(fields) < 5 {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(fields[0], "tcp"):
+			if len(fields) < 6 || fields[5] != "LISTEN" {
+				continue
+			}
+		case strings.HasPrefix(fields[0], "udp"):
+			if fields[4] != "*.*" {
+				continue
+			}
+		default:
+			continue
+		}
+
+		lst.Proto = fields[0]
+
+		if lst.Addr, lst.Port, err = splitHostPort(fields[3], '.'); err != nil {
+			return nil, err
+		}
+
+		set.add(lst)
+	}
+
+	return set.list(), nil
+} // func parseNetstat(output []string) ([]model.Listener, error)
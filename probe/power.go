@@ -0,0 +1,80 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/power.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:36:12 krylon>
+
+package probe
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/blicero/carebear/model"
+)
+
+// We schedule the reboot or shutdown one minute into the future rather than
+// run it right away. That way, shutdown returns before the Device goes
+// down, so we get to see its exit status and learn if we lack the
+// privileges to run it, and anyone logged into the Device gets a warning.
+// On the BSDs, shutdown forks into the background by itself when given a
+// time in the future.
+const (
+	powerCmdRebootLinux = "shutdown -r +1"
+	powerCmdRebootBSD   = "shutdown -r +1"
+	powerCmdHaltLinux   = "shutdown -h +1"
+	powerCmdHaltBSD     = "shutdown -p +1"
+	powerCmdHaltOpenBSD = "shutdown -hp +1"
+)
+
+// powerCmd returns the command to reboot or shut down the given Device.
+func powerCmd(d *model.Device, reboot bool) string {
+	switch strings.ToLower(d.OSID) {
+	case "openbsd":
+		if reboot {
+			return powerCmdRebootBSD
+		}
+		return powerCmdHaltOpenBSD
+	case "freebsd", "netbsd", "dragonfly":
+		if reboot {
+			return powerCmdRebootBSD
+		}
+		return powerCmdHaltBSD
+	default:
+		if reboot {
+			return powerCmdRebootLinux
+		}
+		return powerCmdHaltLinux
+	}
+} // func powerCmd(d *model.Device, reboot bool) string
+
+// Shutdown asks the given Device to reboot, or to shut down if reboot is
+// false. It returns once the Device has accepted the request, the Device
+// goes down about a minute later.
+func (p *Probe) Shutdown(ctx context.Context, d *model.Device, reboot bool) ([]string, error) {
+	var (
+		err    error
+		status int
+		output []string
+		cmd    = asRoot(powerCmd(d, reboot))
+	)
+
+	if output, status, err = p.runCommand(ctx, d, cmd); err != nil {
+		return nil, err
+	} else if status != 0 {
+		var ex = fmt.Errorf("Command on %s exited with status %d\n>>> Command: %s\n%s",
+			d.Name,
+			status,
+			cmd,
+			strings.Join(output, "\n"))
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return output, ex
+	}
+
+	// Once the Device goes down, the connection is dead, we might as
+	// well drop it right away.
+	_ = p.disconnect(d)
+
+	return nonEmpty(output), nil
+} // func (p *Probe) Shutdown(ctx context.Context, d *model.Device, reboot bool) ([]string, error)
@@ -2,53 +2,110 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 21. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-08-20 18:31:44 krylon>
+// Time-stamp: <2026-10-16 18:25:29 krylon>
 
 // Package probe implements probing Devices to determine what OS they run.
 package probe
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/blicero/carebear/common"
 	"github.com/blicero/carebear/database"
 	"github.com/blicero/carebear/logdomain"
 	"github.com/blicero/carebear/model"
 	"github.com/blicero/carebear/ping"
+	"github.com/blicero/carebear/settings"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 // ErrPingOffline indicates a Device did not respond to a ping.
 var ErrPingOffline = errors.New("Device did not respond to ping")
 
+// ErrUnsupported indicates we do not know how to perform a query on a Device.
+var ErrUnsupported = errors.New("Query is not supported for this Device")
+
+// TimeoutError indicates a command on a Device did not finish within the
+// configured time limit.
+type TimeoutError struct {
+	Device  string
+	Command string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("Command %q on %s timed out after %s",
+		e.Command,
+		e.Device,
+		e.Timeout)
+} // func (e *TimeoutError) Error() string
+
+// EscalationError indicates we could not gain root privileges to run a
+// command on a Device, e.g. because sudo wants a password, or doas has no
+// rule that permits the command.
+type EscalationError struct {
+	Device  string
+	Command string
+	Method  string
+	Message string
+}
+
+func (e *EscalationError) Error() string {
+	return fmt.Sprintf("Cannot run command %q on %s as root via %s: %s",
+		e.Command,
+		e.Device,
+		e.Method,
+		e.Message)
+} // func (e *EscalationError) Error() string
+
 const (
-	osReleaseCmd = "/bin/cat /etc/os-release"
-	unameCmd     = "/usr/bin/uname -s"
+	osReleaseCmd   = "/bin/cat /etc/os-release"
+	unameCmd       = "/usr/bin/uname -s"
+	connectTimeout = time.Second * 30
 )
 
 // Probe attempts to query Devices for the OS they are running.
+//
+// lock protects the maps of connections and profiles, it is never held
+// while we talk to the network. dbLock serializes access to db, which may
+// be used by several connection attempts at once.
 type Probe struct {
-	log     *log.Logger
-	db      *database.Database
-	lock    sync.RWMutex // nolint: unused
-	cfg     *ssh.ClientConfig
-	pp      *ping.Pinger
-	clients map[int64]*ssh.Client
+	log       *log.Logger
+	db        *database.Database
+	lock      sync.RWMutex
+	dbLock    sync.Mutex
+	cfg       *ssh.ClientConfig
+	pp        *ping.Pinger
+	clients   map[int64]*ssh.Client
+	jumps     map[int64]*ssh.Client
+	profiles  map[int64]*model.SSHProfile
+	dialing   map[int64]chan struct{}
+	known     ssh.HostKeyCallback
+	port      int
+	keys      []ssh.Signer
+	agent     agent.ExtendedAgent
+	agentConn net.Conn
 }
 
 // New creates a new Probe.
-func New(userName string, keyPath ...string) (*Probe, error) {
+// userName and port are used to connect to Devices that do not have an
+// SSH profile of their own.
+func New(userName string, port int, keyPath ...string) (*Probe, error) {
 	var (
 		err error
-		p   = new(Probe)
+		p   = &Probe{port: port}
 	)
 
 	if p.log, err = common.GetLogger(logdomain.Probe); err != nil {
@@ -64,6 +121,9 @@ func New(userName string, keyPath ...string) (*Probe, error) {
 	}
 
 	p.clients = make(map[int64]*ssh.Client)
+	p.jumps = make(map[int64]*ssh.Client)
+	p.profiles = make(map[int64]*model.SSHProfile)
+	p.dialing = make(map[int64]chan struct{})
 
 	return p, nil
 } // func New(keyPath string) (*Probe, error)
@@ -71,11 +131,11 @@ func New(userName string, keyPath ...string) (*Probe, error) {
 func (p *Probe) initConfig(userName string, keyPath ...string) error {
 	var (
 		err    error
-		keyRaw []byte
 		signer ssh.Signer
-		keys   = make([]ssh.Signer, 0, len(keyPath))
 	)
 
+	p.keys = make([]ssh.Signer, 0, len(keyPath))
+
 	for _, path := range keyPath {
 		var (
 			fh       *os.File
@@ -108,42 +168,47 @@ func (p *Probe) initConfig(userName string, keyPath ...string) error {
 
 			p.log.Printf("[DEBUG] Import SSH key %s\n", fullPath)
 
-			if keyRaw, err = os.ReadFile(fullPath); err != nil {
-				var ex = fmt.Errorf("Failed to read SSH key from %s: %w",
-					fullPath,
-					err)
-				p.log.Printf("[ERROR] %s\n", ex.Error())
-				return ex
-			} else if signer, err = ssh.ParsePrivateKey(keyRaw); err != nil {
-				var ex = fmt.Errorf("Failed to parse SSH key: %w",
-					err)
-				p.log.Printf("[ERROR] %s\n", ex.Error())
-				return ex
-			} else if signer == nil {
-				var ex = fmt.Errorf("ParsePrivateKey did not return an error, but signer is nil!\nKey File: %s\nKey: %s",
-					fullPath,
-					keyRaw)
-				p.log.Printf("[ERROR] %s\n",
-					ex.Error())
-				return ex
+			if signer, err = p.loadKey(fullPath); err != nil {
+				var pmerr *ssh.PassphraseMissingError
+
+				if errors.As(err, &pmerr) {
+					p.log.Printf("[WARN] Skipping SSH key %s, it is protected by a passphrase. Add it to ssh-agent to use it.\n",
+						fullPath)
+					continue
+				}
+
+				return err
 			}
-			keys = append(keys, signer)
+			p.keys = append(p.keys, signer)
 		}
 	}
 
-	// XXX The documentation for the ssh package says very explicitly to NOT use
-	//     InsecureIgnoreHostKey in production code, which makes sense for obvious reasons.
-	//     But I intend to only run this application on my local network, where I own and
-	//     administer all the devices.
-	//     But if anyone ever intends to use this code (or parts of it) for any other purpose,
-	//     please, PLEASE rectify this!!! You have been warned.
+	// The HostKeyCallback depends on the Device we connect to, so
+	// clientConfig fills it in for each connection.
 	p.cfg = &ssh.ClientConfig{
 		User: userName,
-		// Auth: make([]ssh.AuthMethod, 0, len(keys)),
 		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(keys...),
+			p.authMethod(),
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout: connectTimeout,
+	}
+
+	if os.Getenv("SSH_AUTH_SOCK") == "" && len(p.keys) == 0 {
+		p.log.Println("[WARN] Neither ssh-agent nor any usable SSH keys were found, we will not be able to log into any Device.")
+	}
+
+	if settings.Settings != nil && settings.Settings.KnownHostsPath != "" {
+		var path = settings.Settings.KnownHostsPath
+
+		p.log.Printf("[DEBUG] Check host keys against %s\n", path)
+
+		if p.known, err = knownhosts.New(path); err != nil {
+			var ex = fmt.Errorf("Failed to load known hosts from %s: %w",
+				path,
+				err)
+			p.log.Printf("[ERROR] %s\n", ex.Error())
+			return ex
+		}
 	}
 
 	return nil
@@ -176,15 +241,119 @@ func (p *Probe) disconnect(d *model.Device) error {
 	}
 
 END:
+	if c, ok = p.jumps[d.ID]; ok {
+		delete(p.jumps, d.ID)
+		c.Close() // nolint: errcheck
+	}
+
 	return err
 } // func (p *Probe) disconnect(d *model.Device) error
 
-func (p *Probe) connect(d *model.Device, port int) (*ssh.Client, error) {
+// Disconnect closes the cached SSH connection to the given Device, if there
+// is one, so the next query establishes a fresh one.
+func (p *Probe) Disconnect(d *model.Device) error {
+	return p.disconnect(d)
+} // func (p *Probe) Disconnect(d *model.Device) error
+
+// dial connects to the given address and performs the SSH handshake.
+func (p *Probe) dial(ctx context.Context, addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+	var (
+		err    error
+		conn   net.Conn
+		dialer = net.Dialer{Timeout: connectTimeout}
+	)
+
+	if conn, err = dialer.DialContext(ctx, "tcp", addr); err != nil {
+		return nil, err
+	}
+
+	return handshake(ctx, conn, addr, cfg)
+} // func (p *Probe) dial(ctx context.Context, addr string, cfg *ssh.ClientConfig) (*ssh.Client, error)
+
+// handshake establishes an SSH connection over conn. ssh.NewClientConn has
+// no timeout of its own, a Device that accepts the TCP connection but never
+// speaks SSH would keep us waiting forever. So we set a deadline on conn
+// while the handshake runs, and cut it short if ctx is cancelled.
+// conn is closed if the handshake fails.
+func handshake(ctx context.Context, conn net.Conn, addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+	var (
+		err      error
+		cconn    ssh.Conn
+		chans    <-chan ssh.NewChannel
+		reqs     <-chan *ssh.Request
+		stop     func() bool
+		deadline = time.Now().Add(connectTimeout)
+	)
+
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+
+	if err = conn.SetDeadline(deadline); err != nil {
+		conn.Close() // nolint: errcheck
+		return nil, err
+	}
+
+	stop = context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now()) // nolint: errcheck
+	})
+
+	if cconn, chans, reqs, err = ssh.NewClientConn(conn, addr, cfg); err != nil {
+		stop()
+		conn.Close() // nolint: errcheck
+		return nil, err
+	} else if !stop() {
+		// ctx was cancelled just as the handshake finished.
+		cconn.Close() // nolint: errcheck
+		return nil, ctx.Err()
+	} else if err = conn.SetDeadline(time.Time{}); err != nil {
+		cconn.Close() // nolint: errcheck
+		return nil, err
+	}
+
+	return ssh.NewClient(cconn, chans, reqs), nil
+} // func handshake(ctx context.Context, conn net.Conn, addr string, cfg *ssh.ClientConfig) (*ssh.Client, error)
+
+// connect establishes an SSH connection to the given Device, via its jump
+// host, if it has one. It returns the connection, the connection to the
+// jump host, if any, and the Device's SSH profile.
+func (p *Probe) connect(ctx context.Context, d *model.Device) (*ssh.Client, *ssh.Client, *model.SSHProfile, error) {
 	var (
 		err    error
+		port   int
 		client *ssh.Client
+		prof   *model.SSHProfile
+		cfg    *ssh.ClientConfig
 	)
 
+	p.dbLock.Lock()
+	prof, err = p.db.SSHProfileGetByDevice(d)
+	p.dbLock.Unlock()
+
+	if err != nil {
+		p.log.Printf("[ERROR] Failed to load SSH profile for %s: %s\n",
+			d.Name,
+			err.Error())
+		return nil, nil, nil, err
+	} else if cfg, port, err = p.clientConfig(d, prof); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if prof != nil && prof.ProxyJump != "" {
+		// The Device may well be unreachable from here except via the
+		// jump host, so there is no point in pinging it.
+		var jump *ssh.Client
+
+		if jump, err = p.dialJump(ctx, d, prof); err != nil {
+			return nil, nil, nil, err
+		} else if client, err = p.connectVia(ctx, jump, d, cfg, port); err != nil {
+			jump.Close() // nolint: errcheck
+			return nil, nil, nil, err
+		}
+
+		return client, jump, prof, nil
+	}
+
 	for _, a := range d.Addr {
 		if !p.pp.PingAddr(a.String()) {
 			// p.log.Printf("[INFO] Device %s did not respond to ping\n",
@@ -195,32 +364,66 @@ func (p *Probe) connect(d *model.Device, port int) (*ssh.Client, error) {
 		var addr = fmt.Sprintf("%s:%d",
 			a,
 			port)
-		if client, err = ssh.Dial("tcp", addr, p.cfg); err != nil {
+		if client, err = p.dial(ctx, addr, cfg); err != nil {
 			p.log.Printf("[ERROR] Failed to connect to %s at %s: %s\n",
 				d.Name,
 				a,
 				err.Error())
+			if errors.Is(err, ErrHostKeyMismatch) || ctx.Err() != nil {
+				return nil, nil, nil, err
+			}
 		} else {
-			return client, nil
+			return client, nil, prof, nil
 		}
 	}
 
-	return nil, ErrPingOffline
-} // func (p *Probe) connect(d *model.Device, port int) (*ssh.Client, error)
+	return nil, nil, nil, ErrPingOffline
+} // func (p *Probe) connect(ctx context.Context, d *model.Device) (*ssh.Client, *ssh.Client, *model.SSHProfile, error)
 
-func (p *Probe) getClient(d *model.Device, port int) (*ssh.Client, error) {
+// getClient returns the SSH connection to the given Device, connecting to
+// it if we have no connection, yet.
+//
+// We do not hold the lock while we connect, but only one goroutine at a
+// time connects to any one Device, the others wait for it to finish.
+func (p *Probe) getClient(ctx context.Context, d *model.Device) (*ssh.Client, error) {
 	var (
-		err error
-		ok  bool
-		c   *ssh.Client
+		err  error
+		ok   bool
+		c    *ssh.Client
+		jump *ssh.Client
+		prof *model.SSHProfile
+		done chan struct{}
 	)
 
+	for {
+		p.lock.Lock()
+		if c, ok = p.clients[d.ID]; ok {
+			p.lock.Unlock()
+			return c, nil
+		} else if done, ok = p.dialing[d.ID]; !ok {
+			done = make(chan struct{})
+			p.dialing[d.ID] = done
+			p.lock.Unlock()
+			break
+		}
+		p.lock.Unlock()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	c, jump, prof, err = p.connect(ctx, d)
+
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	if c, ok = p.clients[d.ID]; ok {
-		return c, nil
-	} else if c, err = p.connect(d, port); err != nil {
+	delete(p.dialing, d.ID)
+	close(done)
+
+	if err != nil {
 		return nil, err
 	} else if c == nil {
 		var ex = fmt.Errorf("probe.connect did not return an error, but connection to %s is nil",
@@ -229,11 +432,17 @@ func (p *Probe) getClient(d *model.Device, port int) (*ssh.Client, error) {
 		return nil, ex
 	}
 
+	// We need the profile again to decide how to run commands as root.
+	p.profiles[d.ID] = prof
 	p.clients[d.ID] = c
+	if jump != nil {
+		p.jumps[d.ID] = jump
+	}
+
 	return c, nil
-} // func (p *Probe) getClient(d *model.Device, port int) (*ssh.Client, error)
+} // func (p *Probe) getClient(ctx context.Context, d *model.Device) (*ssh.Client, error)
 
-func (p *Probe) getSession(d *model.Device, port int) (s *ssh.Session, e error) {
+func (p *Probe) getSession(ctx context.Context, d *model.Device) (s *ssh.Session, e error) {
 	var (
 		err    error
 		client *ssh.Client
@@ -250,7 +459,7 @@ func (p *Probe) getSession(d *model.Device, port int) (s *ssh.Session, e error)
 		}
 	}()
 
-	if client, err = p.getClient(d, port); err != nil {
+	if client, err = p.getClient(ctx, d); err != nil {
 		if err == ErrPingOffline {
 			return nil, err
 		}
@@ -271,50 +480,20 @@ func (p *Probe) getSession(d *model.Device, port int) (s *ssh.Session, e error)
 	}
 
 	return sess, nil
-} // func (p *Probe) getSession(d *model.Device, port int) (*ssh.Session, error)
+} // func (p *Probe) getSession(ctx context.Context, d *model.Device) (*ssh.Session, error)
 
 // QueryOS attempts to find out what operating system the device runs.
-func (p *Probe) QueryOS(d *model.Device, port int) (string, error) {
+func (p *Probe) QueryOS(ctx context.Context, d *model.Device) (*model.OSRelease, error) {
 	var (
-		err     error
-		client  *ssh.Client
-		session *ssh.Session
+		err    error
+		output []string
 	)
 
-	if client, err = p.getClient(d, port); err != nil {
-		return "", err
-	} else if client == nil {
-		p.log.Printf("[ERROR] Could not connect to %s on any address.\n",
-			d.Name)
-		return "", err
-	}
-
-	// defer client.Close()
-
-	if session, err = client.NewSession(); err != nil {
-		var ex = fmt.Errorf("Failed to create SSH session with %s: %w",
-			d.Name,
-			err)
-		p.log.Printf("[ERROR] %s\n", ex.Error())
-		return "", ex
-	}
-
-	defer session.Close()
-
-	var rawOutput []byte
-
-	if rawOutput, err = session.CombinedOutput(unameCmd); err != nil {
-		var ex = fmt.Errorf("Failed to run %q on %s: %w",
-			unameCmd,
-			d.Name,
-			err)
-		p.log.Printf("[ERROR] %s\n", ex.Error())
-		return "", ex
+	if output, err = p.executeCommand(ctx, d, unameCmd); err != nil {
+		return nil, err
 	}
 
-	rawOutput = bytes.Trim(rawOutput, "\n\t ")
-
-	var kernel = string(rawOutput)
+	var kernel = strings.Trim(strings.Join(output, "\n"), "\n\t ")
 
 	// If the kernel isn't Linux, it almost certainly is some kind of BSD, in which
 	// case we have the information we want.
@@ -322,36 +501,45 @@ func (p *Probe) QueryOS(d *model.Device, port int) (string, error) {
 	// If it is, we try to read /etc/os-release to determine what distro we
 	// are dealing with.
 	if kernel != "Linux" {
-		return kernel, nil
-	} else if session, err = client.NewSession(); err != nil {
-		var ex = fmt.Errorf("Failed to create SSH session on %s: %w",
+		return &model.OSRelease{
+			Name: kernel,
+			ID:   strings.ToLower(kernel),
+		}, nil
+	} else if output, err = p.executeCommand(ctx, d, osReleaseCmd); err != nil {
+		var ex = fmt.Errorf("Failed to cat(1) /etc/os-release on %s: %w",
 			d.Name,
 			err)
 		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
 	}
 
-	defer session.Close()
+	return parseOSRelease(output), nil
+} // func (p *Probe) QueryOS(ctx context.Context, d *model.Device) (*model.OSRelease, error)
 
-	if rawOutput, err = session.CombinedOutput(osReleaseCmd); err != nil {
-		var ex = fmt.Errorf("Failed to cat(1) /etc/os-release on %s: %w",
-			d.Name,
-			err)
-		p.log.Printf("[ERROR] %s\n", ex.Error())
-		return "", ex
-	}
+// parseOSRelease extracts the fields we care about from /etc/os-release.
+func parseOSRelease(lines []string) *model.OSRelease {
+	var rel = new(model.OSRelease)
 
-	var (
-		osname      string
-		releaseInfo = string(rawOutput)
-	)
+	for _, l := range lines {
+		var key, val, ok = strings.Cut(strings.TrimSpace(l), "=")
+
+		if !ok {
+			continue
+		}
+
+		val = strings.Trim(val, "\"' \t")
 
-	for l := range strings.Lines(releaseInfo) {
-		if strings.HasPrefix(l, "NAME=") {
-			osname = strings.Trim(l[5:], "\"\n\t ")
+		switch key {
+		case "NAME":
+			rel.Name = val
+		case "ID":
+			rel.ID = val
+		case "ID_LIKE":
+			rel.IDLike = val
 		}
 	}
 
-	return osname, nil
-} // func (p *Probe) QueryOS(d *model.Device) (string, error)
+	return rel
+} // func parseOSRelease(lines []string) *model.OSRelease
 
 //func (p *Probe)
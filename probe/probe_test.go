@@ -2,14 +2,26 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 24. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-08-29 19:19:34 krylon>
+// Time-stamp: <2026-10-16 18:26:50 krylon>
 
 package probe
 
 import (
+	"context"
+	"maps"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/blicero/carebear/model"
+	"github.com/blicero/carebear/settings"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestUptimePattern(t *testing.T) {
@@ -87,3 +99,1583 @@ func TestUpdatePatterns(t *testing.T) {
 		}
 	}
 } // func TestUpdatePatterns(t *testing.T)
+
+func TestParseJumpHost(t *testing.T) {
+	type testCase struct {
+		spec      string
+		user      string
+		host      string
+		port      int
+		expectErr bool
+	}
+
+	var cases = []testCase{
+		{spec: "bastion", user: "krylon", host: "bastion", port: 22},
+		{spec: "admin@bastion", user: "admin", host: "bastion", port: 22},
+		{spec: "admin@bastion:2222", user: "admin", host: "bastion", port: 2222},
+		{spec: "[fd00::1]:2222", user: "krylon", host: "fd00::1", port: 2222},
+		{spec: "admin@bastion:ssh", expectErr: true},
+		{spec: "admin@", expectErr: true},
+	}
+
+	for _, c := range cases {
+		var (
+			err        error
+			user, host string
+			port       int
+		)
+
+		if user, host, port, err = parseJumpHost(c.spec, "krylon", 22); err != nil {
+			if !c.expectErr {
+				t.Errorf("Failed to parse jump host %q: %s",
+					c.spec,
+					err.Error())
+			}
+		} else if c.expectErr {
+			t.Errorf("Parsing jump host %q should have failed", c.spec)
+		} else if user != c.user || host != c.host || port != c.port {
+			t.Errorf("Unexpected result for %q: %s@%s:%d (expected %s@%s:%d)",
+				c.spec,
+				user, host, port,
+				c.user, c.host, c.port)
+		}
+	}
+} // func TestParseJumpHost(t *testing.T)
+
+func TestParseOSRelease(t *testing.T) {
+	var sample = []string{
+		`NAME="Rocky Linux"`,
+		`VERSION="9.4 (Blue Onyx)"`,
+		`ID="rocky"`,
+		`ID_LIKE="rhel centos fedora"`,
+		`VERSION_ID="9.4"`,
+		``,
+	}
+
+	var rel = parseOSRelease(sample)
+
+	if rel.Name != "Rocky Linux" || rel.ID != "rocky" || rel.IDLike != "rhel centos fedora" {
+		t.Errorf("Unexpected result from parseOSRelease: %#v", rel)
+	}
+} // func TestParseOSRelease(t *testing.T)
+
+func TestDriverFor(t *testing.T) {
+	type testCase struct {
+		id, like string
+		family   string
+	}
+
+	var cases = []testCase{
+		{id: "debian", family: "debian"},
+		{id: "ubuntu", like: "debian", family: "ubuntu"},
+		{id: "linuxmint", like: "ubuntu debian", family: "ubuntu"},
+		{id: "raspbian", like: "debian", family: "debian"},
+		{id: "rocky", like: "rhel centos fedora", family: "fedora"},
+		{id: "fedora", family: "fedora"},
+		{id: "opensuse-leap", like: "suse opensuse", family: "suse"},
+		{id: "opensuse-tumbleweed", like: "opensuse suse", family: "suse"},
+		{id: "manjaro", like: "arch", family: "arch"},
+		{id: "freebsd", family: "freebsd"},
+		{id: "openbsd", family: "openbsd"},
+		{id: "alpine", family: "alpine"},
+		{id: "gentoo", family: "gentoo"},
+		{id: "void", family: "void"},
+		{id: "nixos", family: "nixos"},
+		{id: "darwin", family: "macos"},
+		{id: "plan9"},
+	}
+
+	for _, c := range cases {
+		var (
+			d   = &model.Device{Name: c.id, OSID: c.id, OSLike: c.like}
+			drv = DriverFor(d)
+		)
+
+		if drv == nil {
+			if c.family != "" {
+				t.Errorf("No driver found for %s (%s)", c.id, c.like)
+			}
+		} else if drv.Family() != c.family {
+			t.Errorf("Wrong driver for %s (%s): %s (expected %s)",
+				c.id,
+				c.like,
+				drv.Family(),
+				c.family)
+		}
+	}
+} // func TestDriverFor(t *testing.T)
+
+func TestDriverReboot(t *testing.T) {
+	type testCase struct {
+		drv    OSDriver
+		output []string
+		status int
+		reboot bool
+	}
+
+	var cases = []testCase{
+		{drv: debianDriver{}, status: 0, reboot: true},
+		{drv: debianDriver{}, status: 1, reboot: false},
+		{drv: dnfDriver{}, status: 1, reboot: true},
+		{drv: suseDriver{}, status: 102, reboot: true},
+		{drv: suseDriver{}, status: 0, reboot: false},
+		{
+			drv:    archDriver{},
+			output: []string{"6.16.4-arch1-1", "linux 6.16.4.arch1-1", ""},
+			reboot: false,
+		},
+		{
+			drv:    archDriver{},
+			output: []string{"6.16.3-arch1-1", "linux 6.16.4.arch1-1", ""},
+			reboot: true,
+		},
+		{
+			drv:    freebsdDriver{},
+			output: []string{"14.3-RELEASE-p2", "14.3-RELEASE-p1", ""},
+			reboot: true,
+		},
+		{
+			drv:    freebsdDriver{},
+			output: []string{"14.3-RELEASE-p2", "14.3-RELEASE-p2", ""},
+			reboot: false,
+		},
+		{
+			drv: nixosDriver{},
+			output: []string{
+				"/nix/store/8k1z5l2q0gkq8cr7ymk0s8w7hzv6y1ad-linux-6.6.44/bzImage",
+				"/nix/store/rj4b0jx2ivsh3xw5b2c8i8f7fyq1qmbl-linux-6.6.47/bzImage",
+			},
+			reboot: true,
+		},
+	}
+
+	for _, c := range cases {
+		if c.drv.ExitStatus(c.drv.RebootCmd(), c.status) == CmdFailed {
+			t.Errorf("%s: Exit status %d of %q should not be a failure",
+				c.drv.Family(),
+				c.status,
+				c.drv.RebootCmd())
+		} else if r := c.drv.ParseReboot(c.output, c.status); r != c.reboot {
+			t.Errorf("%s: ParseReboot returned %t for %q / %d",
+				c.drv.Family(),
+				r,
+				c.output,
+				c.status)
+		}
+	}
+} // func TestDriverReboot(t *testing.T)
+
+func TestParsePackagesOpenBSD(t *testing.T) {
+	var (
+		drv    openbsdDriver
+		sample = []string{
+			"py3-setuptools-69.5.1v0",
+			"vim-9.1.0707-no_x11",
+			"p5-Mail-Tools-2.21p0",
+			"",
+		}
+		expect = []model.Package{
+			{Name: "py3-setuptools", Version: "69.5.1v0"},
+			{Name: "vim", Version: "9.1.0707-no_x11"},
+			{Name: "p5-Mail-Tools", Version: "2.21p0"},
+		}
+	)
+
+	var pkgs = drv.ParsePackages(sample)
+
+	if len(pkgs) != len(expect) {
+		t.Fatalf("Expected %d packages, got %d", len(expect), len(pkgs))
+	}
+
+	for i, p := range pkgs {
+		if *p != expect[i] {
+			t.Errorf("Unexpected package: %#v (expected %#v)", p, expect[i])
+		}
+	}
+} // func TestParsePackagesOpenBSD(t *testing.T)
+
+func TestParsePackages(t *testing.T) {
+	type testCase struct {
+		drv    OSDriver
+		output []string
+		expect []model.Package
+	}
+
+	var cases = []testCase{
+		{
+			drv:    alpineDriver{},
+			output: []string{"busybox-1.36.1-r15", "libcrypto3-3.1.4-r5", ""},
+			expect: []model.Package{
+				{Name: "busybox", Version: "1.36.1-r15"},
+				{Name: "libcrypto3", Version: "3.1.4-r5"},
+			},
+		},
+		{
+			drv:    gentooDriver{},
+			output: []string{"sys-kernel/gentoo-sources-6.6.30", "sys-libs/glibc-2.39-r6"},
+			expect: []model.Package{
+				{Name: "sys-kernel/gentoo-sources", Version: "6.6.30"},
+				{Name: "sys-libs/glibc", Version: "2.39-r6"},
+			},
+		},
+		{
+			drv: voidDriver{},
+			output: []string{
+				"ii  bash-5.2.21_1                        GNU Bourne Again Shell",
+				"ii  xbps-0.59.2_3                        XBPS package system utilities",
+			},
+			expect: []model.Package{
+				{Name: "bash", Version: "5.2.21_1"},
+				{Name: "xbps", Version: "0.59.2_3"},
+			},
+		},
+		{
+			drv: nixosDriver{},
+			output: []string{
+				"/nix/store/0b9rhd3j5c2yzz2gl8rqxyhxyxzxbl0g-glibc-2.39-52",
+				"/nix/store/3jl8d0wpmbmn7gz7gmb9vhq5mbvi5ljl-system-path",
+			},
+			expect: []model.Package{
+				{Name: "glibc", Version: "2.39-52"},
+			},
+		},
+		{
+			drv:    macosDriver{},
+			output: []string{"git 2.46.0", "python@3.12 3.12.4 3.12.5"},
+			expect: []model.Package{
+				{Name: "git", Version: "2.46.0"},
+				{Name: "python@3.12", Version: "3.12.5"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		var pkgs = c.drv.ParsePackages(c.output)
+
+		if len(pkgs) != len(c.expect) {
+			t.Errorf("%s: Expected %d packages, got %d",
+				c.drv.Family(),
+				len(c.expect),
+				len(pkgs))
+			continue
+		}
+
+		for i, p := range pkgs {
+			if *p != c.expect[i] {
+				t.Errorf("%s: Unexpected package: %#v (expected %#v)",
+					c.drv.Family(),
+					p,
+					c.expect[i])
+			}
+		}
+	}
+} // func TestParsePackages(t *testing.T)
+
+func TestParseUpdates(t *testing.T) {
+	type testCase struct {
+		drv    OSDriver
+		output []string
+		expect []model.PackageUpdate
+	}
+
+	var cases = []testCase{
+		{
+			drv: debianDriver{},
+			output: []string{
+				"Listing...",
+				"openssl/stable-security 3.0.15-1~deb12u1 amd64 [upgradable from: 3.0.14-1~deb12u2]",
+				"tzdata/stable-updates 2025b-0+deb12u1 all [upgradable from: 2024b-0+deb12u1]",
+				"libssl3/stable-security,stable-updates 3.0.15-1~deb12u1 amd64 [upgradable from: 3.0.14-1~deb12u2]",
+			},
+			expect: []model.PackageUpdate{
+				{Name: "openssl", Repo: "stable-security", NewVersion: "3.0.15-1~deb12u1", CurrentVersion: "3.0.14-1~deb12u2", Arch: "amd64", Security: true},
+				{Name: "tzdata", Repo: "stable-updates", NewVersion: "2025b-0+deb12u1", CurrentVersion: "2024b-0+deb12u1", Arch: "all"},
+				{Name: "libssl3", Repo: "stable-security,stable-updates", NewVersion: "3.0.15-1~deb12u1", CurrentVersion: "3.0.14-1~deb12u2", Arch: "amd64", Security: true},
+			},
+		},
+		{
+			drv: dnfDriver{},
+			output: []string{
+				"",
+				"kernel.x86_64          6.16.7-200.fc42         updates",
+				"python3-libs.x86_64    3.13.7-1.fc42           updates",
+			},
+			expect: []model.PackageUpdate{
+				{Name: "kernel", Arch: "x86_64", NewVersion: "6.16.7-200.fc42", Repo: "updates"},
+				{Name: "python3-libs", Arch: "x86_64", NewVersion: "3.13.7-1.fc42", Repo: "updates"},
+			},
+		},
+		{
+			drv: suseDriver{},
+			output: []string{
+				"S | Repository | Name   | Current Version | Available Version | Arch",
+				"--+------------+--------+-----------------+-------------------+-------",
+				"v | repo-oss   | curl   | 8.14.1-1.1      | 8.15.0-1.1        | x86_64",
+			},
+			expect: []model.PackageUpdate{
+				{Repo: "repo-oss", Name: "curl", CurrentVersion: "8.14.1-1.1", NewVersion: "8.15.0-1.1", Arch: "x86_64"},
+			},
+		},
+		{
+			drv: ubuntuDriver{},
+			output: []string{
+				"Listing...",
+				"linux-generic/noble-updates,noble-security 6.8.0.45.45 amd64 [upgradable from: 6.8.0.44.44]",
+			},
+			expect: []model.PackageUpdate{
+				{Name: "linux-generic", Repo: "noble-updates,noble-security", NewVersion: "6.8.0.45.45", CurrentVersion: "6.8.0.44.44", Arch: "amd64", Security: true},
+			},
+		},
+		{
+			drv: alpineDriver{},
+			output: []string{
+				"Installed:                                Available:",
+				"busybox-1.36.1-r15                      < 1.36.1-r16",
+				"py3-cryptography-41.0.7-r0              < 42.0.5-r0",
+			},
+			expect: []model.PackageUpdate{
+				{Name: "busybox", CurrentVersion: "1.36.1-r15", NewVersion: "1.36.1-r16"},
+				{Name: "py3-cryptography", CurrentVersion: "41.0.7-r0", NewVersion: "42.0.5-r0"},
+			},
+		},
+		{
+			drv: gentooDriver{},
+			output: []string{
+				"",
+				"These are the packages that would be merged, in order:",
+				"",
+				"Calculating dependencies... done!",
+				`[ebuild     U  ] sys-libs/glibc-2.39-r6:2.2::gentoo [2.39-r5:2.2::gentoo] USE="multiarch ssp"`,
+				"[ebuild  N     ] dev-libs/libfoo-1.0::guru",
+				"[binary     U  ] app-editors/vim-9.1.0707 [9.1.0600]",
+				"",
+				"Total: 3 packages (2 upgrades, 1 new), Size of downloads: 19,374 KiB",
+			},
+			expect: []model.PackageUpdate{
+				{Name: "sys-libs/glibc", NewVersion: "2.39-r6", CurrentVersion: "2.39-r5", Repo: "gentoo"},
+				{Name: "dev-libs/libfoo", NewVersion: "1.0", Repo: "guru"},
+				{Name: "app-editors/vim", NewVersion: "9.1.0707", CurrentVersion: "9.1.0600"},
+			},
+		},
+		{
+			drv: voidDriver{},
+			output: []string{
+				"[*] Updating repository `https://repo-default.voidlinux.org/current/x86_64-repodata' ...",
+				"firefox-128.0_1 update x86_64 https://repo-default.voidlinux.org/current 250363392 72138864",
+				"libnotify-0.8.3_1 install x86_64 https://repo-default.voidlinux.org/current 184320 61440",
+			},
+			expect: []model.PackageUpdate{
+				{Name: "firefox", NewVersion: "128.0_1", Arch: "x86_64", Repo: "https://repo-default.voidlinux.org/current"},
+				{Name: "libnotify", NewVersion: "0.8.3_1", Arch: "x86_64", Repo: "https://repo-default.voidlinux.org/current"},
+			},
+		},
+		{
+			drv: nixosDriver{},
+			output: []string{
+				"unpacking channels...",
+				"building the system configuration...",
+				"these 2 derivations will be built:",
+				"  /nix/store/8f5s8qz7b1w5ydsm0ylx4i0a2gq5x3pn-nixos-system-nixbox-24.05.4328.bcd44e2.drv",
+				"  /nix/store/a0kz0pdqv0fd3lm6i2wnxg1jx7h2l4z9-etc.drv",
+				"these 2 paths will be fetched (45.2 MiB download, 180.3 MiB unpacked):",
+				"  /nix/store/1xz3wbl1n6bm0ydyjlbpr8s5h5y7ivwq-firefox-129.0.2",
+				"  /nix/store/1xz3wbl1n6bm0ydyjlbpr8s5h5y7ivwq-firefox-129.0.2",
+			},
+			expect: []model.PackageUpdate{
+				{Name: "nixos-system-nixbox", NewVersion: "24.05.4328.bcd44e2"},
+				{Name: "firefox", NewVersion: "129.0.2"},
+			},
+		},
+		{
+			drv: macosDriver{},
+			output: []string{
+				"Software Update Tool",
+				"",
+				"Finding available software",
+				"Software Update found the following new or updated software:",
+				"* Label: macOS Sonoma 14.6.1-23G93",
+				"\tTitle: macOS Sonoma 14.6.1, Version: 14.6.1, Size: 1545896KiB, Recommended: YES, Action: restart,",
+				"* Label: Background Security Improvement 14.6.1 (a)-23G93a",
+				"\tTitle: Background Security Improvement 14.6.1 (a), Version: 14.6.1 (a), Size: 12345KiB, Recommended: YES,",
+				macosBrewMarker,
+				`{"formulae": [{"name": "git", "installed_versions": ["2.45.2"], "current_version": "2.46.0", "pinned": false, "pinned_version": null}],`,
+				` "casks": [{"name": "firefox", "installed_versions": ["128.0"], "current_version": "129.0"}]}`,
+			},
+			expect: []model.PackageUpdate{
+				{Name: "macOS Sonoma 14.6.1", NewVersion: "14.6.1", Repo: "softwareupdate", Source: "softwareupdate"},
+				{Name: "Background Security Improvement 14.6.1 (a)", NewVersion: "14.6.1 (a)", Repo: "softwareupdate", Source: "softwareupdate", Security: true},
+				{Name: "git", CurrentVersion: "2.45.2", NewVersion: "2.46.0", Repo: "homebrew", Source: "homebrew"},
+				{Name: "firefox", CurrentVersion: "128.0", NewVersion: "129.0", Repo: "homebrew-cask", Source: "homebrew"},
+			},
+		},
+		{
+			drv: macosDriver{},
+			output: []string{
+				"Software Update Tool",
+				"",
+				"No new software available.",
+				macosBrewMarker,
+				`[{"name": "wget", "installed_versions": ["1.24.5"], "current_version": "1.25.0"}]`,
+			},
+			expect: []model.PackageUpdate{
+				{Name: "wget", CurrentVersion: "1.24.5", NewVersion: "1.25.0", Repo: "homebrew", Source: "homebrew"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		var updates = c.drv.ParseUpdates(c.output)
+
+		if len(updates) != len(c.expect) {
+			t.Errorf("%s: Expected %d updates, got %d",
+				c.drv.Family(),
+				len(c.expect),
+				len(updates))
+			continue
+		}
+
+		for i, u := range updates {
+			if *u != c.expect[i] {
+				t.Errorf("%s: Unexpected update: %#v (expected %#v)",
+					c.drv.Family(),
+					u,
+					c.expect[i])
+			}
+		}
+	}
+} // func TestParseUpdates(t *testing.T)
+
+func TestParseUpdateSources(t *testing.T) {
+	type testCase struct {
+		src    string
+		output []string
+		expect []model.PackageUpdate
+	}
+
+	var cases = []testCase{
+		{
+			src: "pkg",
+			output: []string{
+				"Updating FreeBSD repository catalogue...",
+				"FreeBSD repository is up to date.",
+				"curl-8.9.0                         <   needs updating (remote has 8.9.1)",
+				"pkg-1.21.3                         ?   orphaned: ports-mgmt/pkg",
+				"py311-cryptography-42.0.8_1,1      <   needs updating (remote has 43.0.0,1)",
+			},
+			expect: []model.PackageUpdate{
+				{Name: "curl", CurrentVersion: "8.9.0", NewVersion: "8.9.1"},
+				{Name: "py311-cryptography", CurrentVersion: "42.0.8_1,1", NewVersion: "43.0.0,1"},
+			},
+		},
+		{
+			src: "pkg_add",
+			output: []string{
+				"quirks-7.14 signed on 2024-08-10T12:34:56Z",
+				"curl-8.9.0->8.9.1: ok",
+				"python-3.11.9p1->python-3.11.10: ok",
+			},
+			expect: []model.PackageUpdate{
+				{Name: "curl", CurrentVersion: "8.9.0", NewVersion: "8.9.1"},
+				{Name: "python", CurrentVersion: "3.11.9p1", NewVersion: "3.11.10"},
+			},
+		},
+		{
+			src: "flatpak",
+			output: []string{
+				"org.mozilla.firefox\t130.0\tstable\tx86_64\tflathub",
+				"org.freedesktop.Platform.GL.default\t\t23.08\tx86_64\tflathub",
+			},
+			expect: []model.PackageUpdate{
+				{Name: "org.mozilla.firefox", NewVersion: "130.0", Arch: "x86_64", Repo: "flathub"},
+				{Name: "org.freedesktop.Platform.GL.default", NewVersion: "23.08", Arch: "x86_64", Repo: "flathub"},
+			},
+		},
+		{
+			src: "snap",
+			output: []string{
+				"Name     Version        Rev    Size   Publisher   Notes",
+				"firefox  130.0-2        4848   278MB  mozilla✓    -",
+			},
+			expect: []model.PackageUpdate{
+				{Name: "firefox", NewVersion: "130.0-2"},
+			},
+		},
+		{
+			src:    "snap",
+			output: []string{"All snaps up to date."},
+		},
+	}
+
+	for _, c := range cases {
+		var idx = slices.IndexFunc(updateSources, func(s updateSource) bool { return s.Name == c.src })
+
+		if idx == -1 {
+			t.Errorf("Unknown update source %s", c.src)
+			continue
+		}
+
+		var updates = updateSources[idx].Parse(c.output)
+
+		if len(updates) != len(c.expect) {
+			t.Errorf("%s: Expected %d updates, got %d",
+				c.src,
+				len(c.expect),
+				len(updates))
+			continue
+		}
+
+		for i, u := range updates {
+			if *u != c.expect[i] {
+				t.Errorf("%s: Unexpected update: %#v (expected %#v)",
+					c.src,
+					u,
+					c.expect[i])
+			}
+		}
+	}
+} // func TestParseUpdateSources(t *testing.T)
+
+func TestParseSecurity(t *testing.T) {
+	var (
+		suse = mergeSecurity(
+			suseDriver{}.ParseUpdates([]string{
+				"v | repo-update | curl     | 8.14.1-1.1 | 8.15.0-1.1 | x86_64",
+				"v | repo-update | libcurl4 | 8.14.1-1.1 | 8.15.0-1.1 | x86_64",
+				"v | repo-update | vim      | 9.1.1-1.1  | 9.1.2-1.1  | x86_64",
+			}),
+			suseDriver{}.ParseSecurity([]string{
+				"Repository  | Name                        | Category | Severity  | Interactive | Status | Summary",
+				"------------+-----------------------------+----------+-----------+-------------+--------+--------",
+				"repo-update | openSUSE-SLE-15.6-2025-1234 | security | important | ---         | needed | Security update for curl",
+			}))
+		bsd = mergeSecurity(
+			freebsdDriver{}.ParseUpdates([]string{"There are updates available"}),
+			freebsdDriver{}.ParseSecurity([]string{"curl-8.9.0", "py311-cryptography-42.0.8_1,1", ""}))
+	)
+
+	if len(suse) != 3 || !suse[0].Security || suse[1].Security || suse[2].Security {
+		t.Errorf("SUSE: Expected only curl to be a security update, got %d updates", len(suse))
+	}
+
+	for _, status := range []int{0, 100, 101} {
+		if s := (suseDriver{}).ExitStatus(suseSecurityCmd, status); s != CmdOK {
+			t.Errorf("SUSE: Exit status %d of %q should be OK, got %d",
+				status,
+				suseSecurityCmd,
+				s)
+		}
+	}
+
+	if (suseDriver{}).ExitStatus(suseSecurityCmd, 104) != CmdFailed {
+		t.Errorf("SUSE: Exit status 104 of %q should be a failure", suseSecurityCmd)
+	}
+
+	if len(bsd) != 3 {
+		t.Fatalf("FreeBSD: Expected 3 updates, got %d", len(bsd))
+	} else if bsd[0].Security || !bsd[1].Security || !bsd[2].Security {
+		t.Errorf("FreeBSD: Expected pkg audit findings to be security updates")
+	} else if bsd[2].Name != "py311-cryptography" || bsd[2].CurrentVersion != "42.0.8_1,1" {
+		t.Errorf("FreeBSD: Unexpected vulnerable package: %#v", bsd[2])
+	}
+} // func TestParseSecurity(t *testing.T)
+
+func TestParseTemperature(t *testing.T) {
+	var (
+		linux = parseTemperatureLinux([]string{
+			"acpitz\t27800",
+			"x86_pkg_temp\t45000",
+			"coretemp/Package id 0\t45000",
+			"nvme/Composite\t38850",
+			"broken\tN/A",
+			"",
+		})
+		bsd = parseTemperatureBSD([]string{
+			"dev.cpu.0.temperature=45.0C",
+			"hw.acpi.thermal.tz0.temperature=27.9C",
+			"hw.sensors.cpu0.temp0=52.00 degC",
+			"hw.sensors.km0.temp0=42.50 degC (Tctl)",
+			"hw.sensors.acpibat0.volt0=11.10 VDC (voltage)",
+		})
+	)
+
+	if len(linux) != 4 {
+		t.Errorf("Expected 4 sensors on Linux, got %d", len(linux))
+	} else if linux[3].Name != "nvme/Composite" || linux[3].Celsius != 38.85 {
+		t.Errorf("Unexpected sensor reading: %#v", linux[3])
+	}
+
+	if len(bsd) != 4 {
+		t.Errorf("Expected 4 sensors on BSD, got %d", len(bsd))
+	} else if bsd[3].Name != "km0.temp0" || bsd[3].Celsius != 42.5 {
+		t.Errorf("Unexpected sensor reading: %#v", bsd[3])
+	}
+} // func TestParseTemperature(t *testing.T)
+
+func TestParseUptime(t *testing.T) {
+	type testCase struct {
+		output string
+		uptime time.Duration
+		load   [3]float64
+	}
+
+	var cases = []testCase{
+		{
+			output: "6:02PM  up 56 days,  5:16, 4 users, load averages: 0.00, 0.01, 0.00",
+			uptime: 56*24*time.Hour + 5*time.Hour + 16*time.Minute,
+			load:   [3]float64{0, 0.01, 0},
+		},
+		{
+			output: " 18:01:18 up  1:05,  2 users,  load average: 1.08, 0.98, 0.94",
+			uptime: time.Hour + 5*time.Minute,
+			load:   [3]float64{1.08, 0.98, 0.94},
+		},
+		{
+			output: "11:12AM  up 3 days, 2 hrs, 1 user, load averages: 0.12, 0.10, 0.09",
+			uptime: 3*24*time.Hour + 2*time.Hour,
+			load:   [3]float64{0.12, 0.10, 0.09},
+		},
+		{
+			output: "11:12  up 14 mins, 1 user, load averages: 2,50 2,01 1,75",
+			uptime: 14 * time.Minute,
+			load:   [3]float64{2.5, 2.01, 1.75},
+		},
+	}
+
+	for _, c := range cases {
+		var uptime, load, err = parseUptime(c.output)
+
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", c.output, err.Error())
+		} else if uptime != c.uptime {
+			t.Errorf("Unexpected uptime from %q: %s (expected %s)",
+				c.output,
+				uptime,
+				c.uptime)
+		} else if load != c.load {
+			t.Errorf("Unexpected load average from %q: %v (expected %v)",
+				c.output,
+				load,
+				c.load)
+		}
+	}
+
+	var (
+		now           = time.Unix(1760000000, 0)
+		uptime, _     = time.ParseDuration("1h0m0.5s")
+		linux, _, err = parseUptimeLinux([]string{"3600.50 7000.00", "0.10 0.20 0.30 1/123 4567"})
+	)
+
+	if err != nil {
+		t.Errorf("Failed to parse /proc/uptime: %s", err.Error())
+	} else if linux != uptime {
+		t.Errorf("Unexpected uptime from /proc/uptime: %s", linux)
+	}
+
+	for _, boot := range []string{"{ sec = 1759996400, usec = 123456 } Thu Oct  9 08:33:20 2025", "1759996400"} {
+		var bsd, load, err = parseUptimeBSD([]string{boot, "{ 0.10 0.20 0.30 }"}, now)
+
+		if err != nil {
+			t.Errorf("Failed to parse kern.boottime %q: %s", boot, err.Error())
+		} else if bsd != time.Hour {
+			t.Errorf("Unexpected uptime from kern.boottime %q: %s", boot, bsd)
+		} else if load != [3]float64{0.1, 0.2, 0.3} {
+			t.Errorf("Unexpected load average: %v", load)
+		}
+	}
+} // func TestParseUptime(t *testing.T)
+
+func TestParseMemory(t *testing.T) {
+	var (
+		err   error
+		linux *model.Memory
+		bsd   *model.Memory
+	)
+
+	if linux, err = parseMeminfo([]string{
+		"MemTotal:       16384000 kB",
+		"MemFree:         1024000 kB",
+		"MemAvailable:    8192000 kB",
+		"Buffers:          512000 kB",
+		"SwapTotal:       4096000 kB",
+		"SwapFree:        3072000 kB",
+		"HugePages_Total:       0",
+	}); err != nil {
+		t.Errorf("Failed to parse /proc/meminfo: %s", err.Error())
+	} else if linux.Total != 16384000*1024 || linux.Available != 8192000*1024 {
+		t.Errorf("Unexpected RAM usage: %#v", linux)
+	} else if linux.SwapTotal != 4096000*1024 || linux.SwapUsed != 1024000*1024 {
+		t.Errorf("Unexpected swap usage: %#v", linux)
+	}
+
+	if bsd, err = parseMemoryBSD([]string{
+		"8589934592",
+		"   123456 cpu context switches",
+		"     4096 bytes per page",
+		"   500000 pages active",
+		"   250000 pages inactive",
+		"   750000 pages free",
+		"Total:         2097152   524288",
+	}); err != nil {
+		t.Errorf("Failed to parse memory usage on FreeBSD: %s", err.Error())
+	} else if bsd.Total != 8589934592 || bsd.Available != 1000000*4096 {
+		t.Errorf("Unexpected RAM usage: %#v", bsd)
+	} else if bsd.SwapTotal != 2097152*1024 || bsd.SwapUsed != 524288*1024 {
+		t.Errorf("Unexpected swap usage: %#v", bsd)
+	}
+
+	if bsd, err = parseMemoryBSD([]string{
+		"4277919744",
+		"       4096 bytes per page",
+		"     100000 pages free",
+		"total: 1048576 1K-blocks allocated, 1024 used, 1047552 available",
+	}); err != nil {
+		t.Errorf("Failed to parse memory usage on OpenBSD: %s", err.Error())
+	} else if bsd.SwapTotal != 1048576*1024 || bsd.SwapUsed != 1024*1024 {
+		t.Errorf("Unexpected swap usage: %#v", bsd)
+	}
+} // func TestParseMemory(t *testing.T)
+
+func TestParseDf(t *testing.T) {
+	var (
+		err    error
+		mounts []*model.Filesystem
+	)
+
+	if mounts, err = parseDfLinux([]string{
+		"Filesystem     Type         1B-blocks         Used       Avail  Inodes IUsed Mounted on",
+		"/dev/nvme0n1p2 ext4      502392610816 251196305408 225568149504 31260672 812345 /",
+		"tmpfs          tmpfs       8388608000      4096000  8384512000  2048000     45 /run/user/1000",
+		"nas:/export    nfs4     4000000000000 100000000000 3900000000000      -     - /mnt/my files",
+	}); err != nil {
+		t.Fatalf("Failed to parse output of GNU df: %s", err.Error())
+	} else if len(mounts) != 3 {
+		t.Fatalf("Expected 3 filesystems, got %d", len(mounts))
+	} else if mounts[0].Mountpoint != "/" || mounts[0].FSType != "ext4" || mounts[0].Inodes != 31260672 {
+		t.Errorf("Unexpected filesystem: %#v", mounts[0])
+	} else if mounts[2].Mountpoint != "/mnt/my files" || mounts[2].Inodes != 0 {
+		t.Errorf("Unexpected filesystem: %#v", mounts[2])
+	}
+
+	if mounts, err = parseDfLinux([]string{
+		"df: /run/user/1000/doc: Permission denied",
+		"Filesystem     Type         1B-blocks         Used       Avail  Inodes IUsed Mounted on",
+		"/dev/nvme0n1p2 ext4      502392610816 251196305408 225568149504 31260672 812345 /",
+	}); err != nil {
+		t.Fatalf("Failed to parse output of GNU df with complaints: %s", err.Error())
+	} else if len(mounts) != 1 || mounts[0].Mountpoint != "/" {
+		t.Errorf("Unexpected filesystems: %#v", mounts)
+	}
+
+	if mounts, err = parseDfBSD([]string{
+		"Filesystem         1024-blocks    Used    Avail Capacity iused    ifree %iused  Mounted on",
+		"zroot/ROOT/default   180000000 9000000 171000000     5%  312345 342000000    0%  /",
+		"devfs                        1       1        0   100%       0        0  100%  /dev",
+		"zroot/home           171500000  500000 171000000     0%    1234 342000000    0%  /home",
+		"%%",
+		"zroot/ROOT/default on / (zfs, local, noatime, nfsv4acls)",
+		"devfs on /dev (devfs)",
+		"zroot/home on /home (zfs, local, noatime, nosuid, nfsv4acls)",
+	}); err != nil {
+		t.Fatalf("Failed to parse output of BSD df: %s", err.Error())
+	} else if len(mounts) != 3 {
+		t.Fatalf("Expected 3 filesystems, got %d", len(mounts))
+	} else if mounts[1].FSType != "devfs" || mounts[2].FSType != "zfs" {
+		t.Errorf("Unexpected filesystem types: %q, %q", mounts[1].FSType, mounts[2].FSType)
+	} else if mounts[2].Size != 171500000*1024 || mounts[2].Inodes != 342001234 {
+		t.Errorf("Unexpected filesystem: %#v", mounts[2])
+	} else if pct := mounts[0].PercentUsed(); pct != 5 {
+		t.Errorf("Unexpected usage of %s: %d%%", mounts[0].Mountpoint, pct)
+	}
+
+	if mounts, err = parseDfBSD([]string{
+		"Filesystem  1K-blocks      Used     Avail Capacity iused   ifree  %iused  Mounted on",
+		"/dev/sd0a      1005342    123456    831620    13%    4567  130000     3%   /",
+		"%%",
+		"/dev/sd0a on / type ffs (local)",
+	}); err != nil {
+		t.Fatalf("Failed to parse output of OpenBSD df: %s", err.Error())
+	} else if len(mounts) != 1 || mounts[0].FSType != "ffs" {
+		t.Errorf("Unexpected filesystems: %#v", mounts)
+	}
+} // func TestParseDf(t *testing.T)
+
+// TestDfCmdLinux runs dfCmdLinux, if we have GNU df, to make sure df
+// accepts it and we can parse the result.
+func TestDfCmdLinux(t *testing.T) {
+	var (
+		err    error
+		out    []byte
+		mounts []*model.Filesystem
+	)
+
+	if out, err = exec.Command("df", "--version").Output(); err != nil || !strings.Contains(string(out), "GNU coreutils") {
+		t.Skip("GNU df is not available")
+	} else if out, err = exec.Command("sh", "-c", dfCmdLinux).Output(); err != nil {
+		t.Fatalf("Failed to run %q: %s", dfCmdLinux, err.Error())
+	} else if mounts, err = parseDfLinux(strings.Split(string(out), "\n")); err != nil {
+		t.Fatalf("Failed to parse output of %q: %s", dfCmdLinux, err.Error())
+	} else if len(mounts) == 0 {
+		t.Errorf("%q did not report any filesystems", dfCmdLinux)
+	}
+} // func TestDfCmdLinux(t *testing.T)
+
+func TestParseSmart(t *testing.T) {
+	var (
+		err error
+		si  *model.SmartInfo
+	)
+
+	const ata = `{
+  "device": {"name": "/dev/sda", "info_name": "/dev/sda [SAT]", "type": "sat", "protocol": "ATA"},
+  "model_name": "WDC WD40EFRX-68N32N0",
+  "serial_number": "WD-WCC7K1234567",
+  "smart_status": {"passed": false},
+  "ata_smart_attributes": {
+    "revision": 16,
+    "table": [
+      {"id": 1, "name": "Raw_Read_Error_Rate", "raw": {"value": 12, "string": "12"}},
+      {"id": 5, "name": "Reallocated_Sector_Ct", "raw": {"value": 168, "string": "168"}}
+    ]
+  },
+  "power_on_time": {"hours": 41877},
+  "temperature": {"current": 34}
+}`
+
+	const nvme = `{
+  "device": {"name": "/dev/nvme0", "info_name": "/dev/nvme0", "type": "nvme", "protocol": "NVMe"},
+  "model_name": "Samsung SSD 980 PRO 1TB",
+  "serial_number": "S5GXNF0R123456",
+  "smart_status": {"passed": true, "nvme": {"value": 0}},
+  "nvme_smart_health_information_log": {"critical_warning": 0, "temperature": 41, "media_errors": 0, "power_on_hours": 5210},
+  "power_on_time": {"hours": 5210},
+  "temperature": {"current": 41}
+}`
+
+	const usb = `{
+  "device": {"name": "/dev/sdb", "info_name": "/dev/sdb", "type": "scsi", "protocol": "SCSI"},
+  "model_name": "USB Flash Disk"
+}`
+
+	if si, err = parseSmart([]byte(ata)); err != nil {
+		t.Fatalf("Failed to parse SMART status of ATA disk: %s", err.Error())
+	} else if si.Passed || si.Reallocated != 168 || si.PowerOnHours != 41877 || si.Temperature != 34 {
+		t.Errorf("Unexpected SMART status of ATA disk: %#v", si)
+	} else if si.Disk != "/dev/sda" || si.Serial != "WD-WCC7K1234567" {
+		t.Errorf("Unexpected disk: %#v", si)
+	}
+
+	if si, err = parseSmart([]byte(nvme)); err != nil {
+		t.Fatalf("Failed to parse SMART status of NVMe disk: %s", err.Error())
+	} else if !si.Passed || si.Reallocated != 0 || si.PowerOnHours != 5210 || si.Temperature != 41 {
+		t.Errorf("Unexpected SMART status of NVMe disk: %#v", si)
+	}
+
+	if si, err = parseSmart([]byte(usb)); err != nil {
+		t.Fatalf("Failed to parse output for disk without SMART: %s", err.Error())
+	} else if si != nil {
+		t.Errorf("Expected no SMART status for disk without SMART: %#v", si)
+	}
+
+	if _, err = parseSmart([]byte("smartctl: command not found")); err == nil {
+		t.Error("Parsing garbage should have failed")
+	}
+} // func TestParseSmart(t *testing.T)
+
+func TestParsePools(t *testing.T) {
+	var (
+		err   error
+		pools []*model.Pool
+	)
+
+	if pools, err = parseZpool([]string{
+		"zroot\t987842478080\t123480309760\tONLINE",
+		"tank\t7999999967232\t5999999975424\tDEGRADED",
+		"%%",
+		"  pool: tank",
+		" state: DEGRADED",
+		"status: One or more devices could not be used because the label is missing or",
+		"\tinvalid.  Sufficient replicas exist for the pool to continue",
+		"\tfunctioning in a degraded state.",
+		"  scan: scrub in progress since Thu Oct 15 02:00:00 2026",
+		"\t3.10T / 5.46T scanned at 512M/s, 2.90T / 5.46T issued at 480M/s",
+		"\t0B repaired, 53.12% done, 01:33:07 to go",
+		"config:",
+		"",
+		"\tNAME        STATE     READ WRITE CKSUM",
+		"\ttank        DEGRADED     0     0     0",
+		"\t  raidz1-0  DEGRADED     0     0     0",
+		"\t    ada1    ONLINE       0     0     0",
+		"\t    ada2    UNAVAIL      3    12     0  corrupted data",
+		"\t    ada3    ONLINE       0     0     0",
+		"",
+		"errors: No known data errors",
+		"",
+		"  pool: zroot",
+		" state: ONLINE",
+		"  scan: scrub repaired 0 in 00:05:12 with 0 errors on Sun Oct  4 03:05:12 2026",
+		"config:",
+		"",
+		"\tNAME        STATE     READ WRITE CKSUM",
+		"\tzroot       ONLINE       0     0     0",
+		"\t  ada0p3    ONLINE       0     0     0",
+		"",
+		"errors: No known data errors",
+	}, time.UTC); err != nil {
+		t.Fatalf("Failed to parse zpool output: %s", err.Error())
+	} else if len(pools) != 2 {
+		t.Fatalf("Expected 2 pools, got %d", len(pools))
+	} else if !pools[0].Healthy() || pools[0].PercentUsed() != 12 {
+		t.Errorf("Unexpected state of zroot: %#v", pools[0])
+	} else if !pools[0].LastScrub.Equal(time.Date(2026, time.October, 4, 3, 5, 12, 0, time.UTC)) {
+		t.Errorf("Unexpected time of last scrub of zroot: %s", pools[0].LastScrub)
+	} else if pools[1].Healthy() || pools[1].Errors != 15 {
+		t.Errorf("Unexpected state of tank: %#v", pools[1])
+	} else if pools[1].Progress != "scrub in progress, 53.12% done" || !pools[1].LastScrub.IsZero() {
+		t.Errorf("Unexpected scrub status of tank: %q, %s", pools[1].Progress, pools[1].LastScrub)
+	}
+
+	if pools, err = parseZpool([]string{""}, time.UTC); err != nil {
+		t.Errorf("Failed to parse output of host without ZFS: %s", err.Error())
+	} else if len(pools) != 0 {
+		t.Errorf("Expected no pools, got %d", len(pools))
+	}
+
+	pools = parseMdstat([]string{
+		"Personalities : [raid1] [raid6] [raid5] [raid4]",
+		"md1 : active raid5 sdc1[0] sdd1[1] sde1[3](F)",
+		"      1953260544 blocks super 1.2 level 5, 512k chunk, algorithm 2 [3/2] [UU_]",
+		"      [===>.................]  recovery = 15.2% (148477440/976630464) finish=72.1min speed=191290K/sec",
+		"",
+		"md0 : active raid1 sdb1[1] sda1[0]",
+		"      976630464 blocks super 1.2 [2/2] [UU]",
+		"      bitmap: 0/8 pages [0KB], 65536KB chunk",
+		"",
+		"md127 : inactive sdf[0](S)",
+		"      976630464 blocks super 1.2",
+		"",
+		"unused devices: <none>",
+	})
+
+	if len(pools) != 3 {
+		t.Fatalf("Expected 3 arrays, got %d", len(pools))
+	} else if pools[0].State != "DEGRADED" || pools[0].Errors != 1 || pools[0].Type != "raid5" {
+		t.Errorf("Unexpected state of md1: %#v", pools[0])
+	} else if pools[0].Progress != "recovery 15.2%" {
+		t.Errorf("Unexpected progress of md1: %q", pools[0].Progress)
+	} else if !pools[1].Healthy() || pools[1].Size != 976630464*1024 {
+		t.Errorf("Unexpected state of md0: %#v", pools[1])
+	} else if pools[2].State != "INACTIVE" || pools[2].Type != "md" {
+		t.Errorf("Unexpected state of md127: %#v", pools[2])
+	}
+} // func TestParsePools(t *testing.T)
+
+func TestParseFailedUnits(t *testing.T) {
+	var (
+		err   error
+		units []*model.FailedUnit
+	)
+
+	const systemd = `[{"unit":"nfs-server.service","load":"loaded","active":"failed","sub":"failed","description":"NFS server and services"},` +
+		`{"unit":"logrotate.service","load":"loaded","active":"failed","sub":"failed","description":"Rotate log files"}]`
+
+	if units, err = parseFailedSystemd([]byte(systemd)); err != nil {
+		t.Fatalf("Failed to parse output of systemctl: %s", err.Error())
+	} else if len(units) != 2 {
+		t.Fatalf("Expected 2 failed units, got %d", len(units))
+	} else if units[0].Name != "nfs-server.service" || units[0].Description != "NFS server and services" {
+		t.Errorf("Unexpected unit: %#v", units[0])
+	}
+
+	if units, err = parseFailedSystemd([]byte("[]")); err != nil {
+		t.Fatalf("Failed to parse empty output of systemctl: %s", err.Error())
+	} else if len(units) != 0 {
+		t.Errorf("Expected no failed units, got %d", len(units))
+	}
+
+	units = parseFailedRC([]string{
+		"ntpd is not running.",
+		"smartd is not running.",
+		"",
+	})
+
+	if len(units) != 2 || units[0].Name != "ntpd" || units[1].Name != "smartd" {
+		t.Errorf("Unexpected failed services on FreeBSD: %#v", units)
+	}
+
+	units = parseFailedRC([]string{"httpd", "smtpd"})
+
+	if len(units) != 2 || units[1].Name != "smtpd" {
+		t.Errorf("Unexpected failed services on OpenBSD: %#v", units)
+	}
+} // func TestParseFailedUnits(t *testing.T)
+
+func TestParseInventory(t *testing.T) {
+	var (
+		err error
+		inv *model.Inventory
+	)
+
+	if inv, err = parseInventory([]string{
+		"kernel=6.8.0-45-generic",
+		"arch=x86_64",
+		"version=24.04",
+		"cpu= Intel(R) Core(TM) i5-8500T CPU @ 2.10GHz",
+		"cores=6",
+		"ram=16585297920",
+		"virt=none",
+		"vendor=HP",
+		"product=HP ProDesk 400 G4 DM",
+	}); err != nil {
+		t.Fatalf("Failed to parse inventory: %s", err.Error())
+	} else if inv.Kernel != "6.8.0-45-generic" || inv.OSVersion != "24.04" || inv.Arch != "x86_64" {
+		t.Errorf("Unexpected software inventory: %#v", inv)
+	} else if inv.CPUModel != "Intel(R) Core(TM) i5-8500T CPU @ 2.10GHz" || inv.CPUCores != 6 {
+		t.Errorf("Unexpected CPU: %q (%d cores)", inv.CPUModel, inv.CPUCores)
+	} else if inv.RAM != 16585297920 || inv.Virt != "none" || inv.Product != "HP ProDesk 400 G4 DM" {
+		t.Errorf("Unexpected hardware inventory: %#v", inv)
+	}
+
+	// OpenBSD does not tell us about virtualization, and a VM may not
+	// have any DMI information.
+	if inv, err = parseInventory([]string{
+		"kernel=GENERIC.MP#338",
+		"arch=amd64",
+		"version=7.6",
+		"cpu=AMD EPYC Processor",
+		"cores=2",
+		"ram=2130640896",
+		"vendor=",
+		"product=",
+	}); err != nil {
+		t.Fatalf("Failed to parse inventory of OpenBSD: %s", err.Error())
+	} else if inv.Virt != "" || inv.Vendor != "" || inv.CPUCores != 2 {
+		t.Errorf("Unexpected inventory of OpenBSD: %#v", inv)
+	}
+
+	if inv, err = parseInventory([]string{
+		"kernel=24.6.0",
+		"arch=arm64",
+		"version=15.6",
+		"cpu=Apple M1 Pro",
+		"cores=10",
+		"ram=17179869184",
+		"vendor=Apple",
+		"product=MacBookPro18,3",
+	}); err != nil {
+		t.Fatalf("Failed to parse inventory of macOS: %s", err.Error())
+	} else if inv.RAM != 17179869184 || inv.OSVersion != "15.6" || inv.Product != "MacBookPro18,3" {
+		t.Errorf("Unexpected inventory of macOS: %#v", inv)
+	}
+
+	if _, err = parseInventory([]string{"sh: uname: not found"}); err == nil {
+		t.Error("Parsing inventory without kernel version should have failed")
+	}
+
+	for _, id := range []string{"darwin", "macos", "freebsd", "openbsd"} {
+		if cmd := inventoryCmd(&model.Device{OSID: id}); strings.Contains(cmd, "/proc/") {
+			t.Errorf("Inventory command for %s reads from /proc", id)
+		}
+	}
+} // func TestParseInventory(t *testing.T)
+
+func TestParsePorts(t *testing.T) {
+	var (
+		err  error
+		list []model.Listener
+	)
+
+	// sshd listens on IPv4 and IPv6, and two worker processes share the
+	// socket of the web server.
+	if list, err = parseSS([]string{
+		`udp   UNCONN 0      0          127.0.0.54:53         0.0.0.0:*    users:(("systemd-resolve",pid=612,fd=16))`,
+		`tcp   LISTEN 0      128           0.0.0.0:22         0.0.0.0:*    users:(("sshd",pid=1000,fd=3))`,
+		`tcp   LISTEN 0      511           0.0.0.0:80         0.0.0.0:*    users:(("nginx",pid=1201,fd=6),("nginx",pid=1200,fd=6))`,
+		`tcp   LISTEN 0      511           0.0.0.0:80         0.0.0.0:*    users:(("nginx",pid=1202,fd=6))`,
+		`tcp   LISTEN 0      128              [::]:22            [::]:*    users:(("sshd",pid=1000,fd=4))`,
+		`tcp   LISTEN 0      4096    [::ffff:127.0.0.1]:631        *:*`,
+	}); err != nil {
+		t.Fatalf("Failed to parse output of ss: %s", err.Error())
+	} else if len(list) != 5 {
+		t.Fatalf("Unexpected number of listeners: %d (expected 5)\n%#v", len(list), list)
+	} else if l := list[0]; l.Proto != "tcp" || l.Addr != "0.0.0.0" || l.Port != 22 || l.Process != "sshd" {
+		t.Errorf("Unexpected listener: %#v", l)
+	} else if l = list[1]; l.Addr != "::" || l.Port != 22 {
+		t.Errorf("Unexpected IPv6 listener: %#v", l)
+	} else if l = list[3]; l.Addr != "::ffff:127.0.0.1" || l.Port != 631 || l.Process != "" {
+		t.Errorf("Unexpected listener without process: %#v", l)
+	} else if l = list[4]; l.Proto != "udp" || l.Port != 53 || l.Process != "systemd-resolve" {
+		t.Errorf("Unexpected UDP listener: %#v", l)
+	}
+
+	if list, err = parseSockstat([]string{
+		"USER     COMMAND    PID   FD  PROTO  LOCAL ADDRESS         FOREIGN ADDRESS",
+		"root     sshd        812   4  tcp6   *:22                  *:*",
+		"root     sshd        812   5  tcp4   *:22                  *:*",
+		"ntpd     ntpd        701  21  udp4   192.168.1.10:123      *:*",
+		"root     syslogd     601   6  local  /var/run/log          ",
+	}); err != nil {
+		t.Fatalf("Failed to parse output of sockstat: %s", err.Error())
+	} else if len(list) != 3 {
+		t.Fatalf("Unexpected number of listeners: %d (expected 3)\n%#v", len(list), list)
+	} else if l := list[0]; l.Proto != "tcp4" || l.Addr != "*" || l.Port != 22 || l.Process != "sshd" {
+		t.Errorf("Unexpected listener: %#v", l)
+	} else if l = list[2]; l.Proto != "udp4" || l.Addr != "192.168.1.10" || l.Port != 123 {
+		t.Errorf("Unexpected UDP listener: %#v", l)
+	}
+
+	if list, err = parseNetstat([]string{
+		"Active Internet connections (including servers)",
+		"Proto   Recv-Q Send-Q  Local Address          Foreign Address        (state)",
+		"tcp          0      0  192.168.1.20.22        192.168.1.5.51234      ESTABLISHED",
+		"tcp          0      0  *.22                   *.*                    LISTEN",
+		"tcp          0      0  127.0.0.1.25           *.*                    LISTEN",
+		"udp          0      0  192.168.1.20.4711      9.9.9.9.53",
+		"udp          0      0  *.514                  *.*",
+		"tcp6         0      0  ::1.25                 *.*                    LISTEN",
+	}); err != nil {
+		t.Fatalf("Failed to parse output of netstat: %s", err.Error())
+	} else if len(list) != 4 {
+		t.Fatalf("Unexpected number of listeners: %d (expected 4)\n%#v", len(list), list)
+	} else if l := list[1]; l.Proto != "tcp" || l.Addr != "127.0.0.1" || l.Port != 25 {
+		t.Errorf("Unexpected listener: %#v", l)
+	} else if l = list[2]; l.Proto != "tcp6" || l.Addr != "::1" {
+		t.Errorf("Unexpected IPv6 listener: %#v", l)
+	} else if l = list[3]; l.Proto != "udp" || l.Addr != "*" || l.Port != 514 {
+		t.Errorf("Unexpected UDP listener: %#v", l)
+	}
+} // func TestParsePorts(t *testing.T)
+
+func TestPowerCmd(t *testing.T) {
+	type testCase struct {
+		osid   string
+		reboot bool
+		cmd    string
+	}
+
+	var cases = []testCase{
+		{osid: "debian", reboot: true, cmd: "shutdown -r +1"},
+		{osid: "fedora", reboot: false, cmd: "shutdown -h +1"},
+		{osid: "freebsd", reboot: false, cmd: "shutdown -p +1"},
+		{osid: "OpenBSD", reboot: false, cmd: "shutdown -hp +1"},
+		{osid: "openbsd", reboot: true, cmd: "shutdown -r +1"},
+	}
+
+	for _, c := range cases {
+		var cmd = powerCmd(&model.Device{OSID: c.osid}, c.reboot)
+
+		if cmd != c.cmd {
+			t.Errorf("Unexpected command for %s (reboot = %t): %q (expected %q)",
+				c.osid,
+				c.reboot,
+				cmd,
+				c.cmd)
+		}
+	}
+} // func TestPowerCmd(t *testing.T)
+
+func TestParseCustom(t *testing.T) {
+	type testCase struct {
+		cfg    settings.CustomProbe
+		output []string
+		values map[string]string
+		err    bool
+	}
+
+	var cases = []testCase{
+		{
+			cfg:    settings.CustomProbe{Name: "load", Parser: settings.ParserNumber},
+			output: []string{" 0.42 ", ""},
+			values: map[string]string{"value": "0.42"},
+		},
+		{
+			cfg:    settings.CustomProbe{Name: "load", Parser: settings.ParserNumber},
+			output: []string{"command not found"},
+			err:    true,
+		},
+		{
+			cfg: settings.CustomProbe{
+				Name:    "nginx",
+				Parser:  settings.ParserRegex,
+				Pattern: `Active connections: (?P<active>\d+)(?s:.*)Reading: (?P<reading>\d+)`,
+			},
+			output: []string{
+				"Active connections: 291",
+				"server accepts handled requests",
+				" 16630948 16630948 31070465",
+				"Reading: 6 Writing: 179 Waiting: 106",
+			},
+			values: map[string]string{"active": "291", "reading": "6"},
+		},
+		{
+			cfg: settings.CustomProbe{
+				Name:    "nginx",
+				Parser:  settings.ParserRegex,
+				Pattern: `Active connections: (?P<active>\d+)`,
+			},
+			output: []string{"502 Bad Gateway"},
+			err:    true,
+		},
+		{
+			cfg: settings.CustomProbe{
+				Name:    "queue",
+				Parser:  settings.ParserJSON,
+				Pattern: "stats.queues.1.depth",
+			},
+			output: []string{`{"stats": {"queues": [{"depth": 3}, {"depth": 17.5}]}}`},
+			values: map[string]string{"value": "17.5"},
+		},
+		{
+			cfg: settings.CustomProbe{
+				Name:    "queue",
+				Parser:  settings.ParserJSON,
+				Pattern: "stats.missing",
+			},
+			output: []string{`{"stats": {}}`},
+			err:    true,
+		},
+	}
+
+	for _, c := range cases {
+		var (
+			err    error
+			probe  *Custom
+			values map[string]string
+		)
+
+		if probe, err = NewCustom(c.cfg); err != nil {
+			t.Fatalf("Cannot prepare custom probe %s: %s", c.cfg.Name, err.Error())
+		} else if values, err = probe.Parse(c.output); err != nil {
+			if !c.err {
+				t.Errorf("Failed to parse output of %s: %s", c.cfg.Name, err.Error())
+			}
+		} else if c.err {
+			t.Errorf("Parsing %q for %s should have failed, got %v", c.output, c.cfg.Name, values)
+		} else if !maps.Equal(values, c.values) {
+			t.Errorf("Unexpected values for %s: %v (expected %v)", c.cfg.Name, values, c.values)
+		}
+	}
+
+	if _, err := NewCustom(settings.CustomProbe{
+		Name:    "unnamed",
+		Parser:  settings.ParserRegex,
+		Pattern: `(\d+)`,
+	}); err == nil {
+		t.Error("NewCustom should reject a pattern without named groups")
+	}
+
+	var (
+		c, _ = NewCustom(settings.CustomProbe{Name: "zfs", Parser: settings.ParserNumber, OS: []string{"bsd", "ubuntu"}})
+		devs = map[*model.Device]bool{
+			{OSID: "freebsd"}:                      true,
+			{OSID: "pop", OSLike: "ubuntu debian"}: true,
+			{OSID: "fedora"}:                       false,
+		}
+	)
+
+	for d, expect := range devs {
+		if c.Applies(d) != expect {
+			t.Errorf("Custom probe %s should apply to %s/%s: %t",
+				c.Name,
+				d.OSID,
+				d.OSLike,
+				expect)
+		}
+	}
+} // func TestParseCustom(t *testing.T)
+
+func TestEscalate(t *testing.T) {
+	var (
+		cfg = &settings.Options{
+			Escalate:   settings.EscalateSudo,
+			EscalateOS: map[string]string{"openbsd": settings.EscalateDoas, "arch": settings.EscalateNone},
+		}
+		obsd = &model.Device{OSID: "openbsd"}
+		endv = &model.Device{OSID: "endeavouros", OSLike: "arch"}
+		deb  = &model.Device{OSID: "debian"}
+	)
+
+	if m := escalationMethod(obsd, nil, cfg); m != settings.EscalateDoas {
+		t.Errorf("Unexpected escalation method for OpenBSD: %s", m)
+	} else if m = escalationMethod(endv, nil, cfg); m != settings.EscalateNone {
+		t.Errorf("Escalation method for arch was not inherited via ID_LIKE: %s", m)
+	} else if m = escalationMethod(deb, nil, cfg); m != settings.EscalateSudo {
+		t.Errorf("Unexpected default escalation method: %s", m)
+	} else if m = escalationMethod(obsd, &model.SSHProfile{Escalate: settings.EscalateNone}, cfg); m != settings.EscalateNone {
+		t.Errorf("SSH profile did not override escalation method: %s", m)
+	} else if m = escalationMethod(deb, &model.SSHProfile{}, nil); m != settings.EscalateNone {
+		t.Errorf("Unexpected escalation method without configuration: %s", m)
+	}
+
+	if cmd := escalateCmd(settings.EscalateDoas, openbsdUpdateCmd); cmd != "doas -n syspatch -c" {
+		t.Errorf("Unexpected command: %q", cmd)
+	} else if cmd = escalateCmd(settings.EscalateSudo, asRoot(smartScanCmd)); cmd != "sudo -n "+smartScanCmd {
+		t.Errorf("Unexpected command: %q", cmd)
+	} else if cmd = escalateCmd(settings.EscalateNone, freebsdAuditCmd); cmd != "pkg audit -Fq" {
+		t.Errorf("Unexpected command: %q", cmd)
+	} else if cmd = escalateCmd(settings.EscalateSudo, freebsdPackageCmd); cmd != freebsdPackageCmd {
+		t.Errorf("Command that does not need root was escalated: %q", cmd)
+	}
+
+	var failures = [][]string{
+		{"sudo: a password is required"},
+		{"sudo: a terminal is required to read the password; either use the -S option to read from standard input or configure an askpass helper"},
+		{"krylon is not in the sudoers file.", "sudo: krylon is not in the sudoers file.  This incident will be reported."},
+		{"doas: Authentication required"},
+		{"doas: Operation not permitted"},
+		{"ksh: doas: not found"},
+		{"bash: line 1: sudo: command not found"},
+	}
+
+	for _, output := range failures {
+		if escalationFailed(output) == "" {
+			t.Errorf("Escalation failure was not detected: %q", output)
+		}
+	}
+
+	if msg := escalationFailed([]string{
+		"syspatch: Error retrieving https://cdn.openbsd.org/pub/OpenBSD/syspatch/7.5/amd64/SHA256.sig: 404 Not Found",
+	}); msg != "" {
+		t.Errorf("Other failure was mistaken for an escalation failure: %q", msg)
+	}
+} // func TestEscalate(t *testing.T)
+
+// TestSmartCmdEscalated runs smartScanCmd through a stand-in for sudo on a
+// system without smartctl, to make sure we still hear from sudo and get
+// the exit status of a missing command.
+func TestSmartCmdEscalated(t *testing.T) {
+	var (
+		err    error
+		sh     string
+		out    []byte
+		dir    = t.TempDir()
+		sudo   = filepath.Join(dir, "sudo")
+		script = "#!/bin/sh\nif [ -n \"$SUDO_FAIL\" ]; then echo 'sudo: a password is required' >&2; exit 1; fi\nshift\nexec \"$@\"\n"
+	)
+
+	if sh, err = exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	} else if err = os.Symlink(sh, filepath.Join(dir, "sh")); err != nil {
+		t.Fatalf("Cannot link %s: %s", sh, err.Error())
+	} else if err = os.WriteFile(sudo, []byte(script), 0700); err != nil {
+		t.Fatalf("Cannot create %s: %s", sudo, err.Error())
+	}
+
+	var (
+		cmd = escalateCmd(settings.EscalateSudo, asRoot(smartScanCmd))
+		run = func(env ...string) (string, int) {
+			var c = exec.Command(sh, "-c", cmd)
+
+			c.Env = append([]string{"PATH=" + dir}, env...)
+			out, _ = c.CombinedOutput()
+
+			return string(out), c.ProcessState.ExitCode()
+		}
+	)
+
+	if output, status := run(); status != statusNotFound {
+		t.Errorf("Unexpected exit status %d (expected %d): %s",
+			status,
+			statusNotFound,
+			output)
+	} else if output != "" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+
+	if output, _ := run("SUDO_FAIL=1"); escalationFailed([]string{output}) == "" {
+		t.Errorf("sudo's complaint was lost: %q", output)
+	}
+} // func TestSmartCmdEscalated(t *testing.T)
+
+// TestGuestToolsCmd runs guestToolsCmd under sh with only some of the tools
+// installed.
+func TestGuestToolsCmd(t *testing.T) {
+	var (
+		err   error
+		sh    string
+		out   []byte
+		found []string
+		dir   = t.TempDir()
+	)
+
+	if sh, err = exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	for _, tool := range []string{"podman", "jls"} {
+		if err = os.WriteFile(filepath.Join(dir, tool), []byte("#!/bin/sh\n"), 0700); err != nil {
+			t.Fatalf("Cannot create %s: %s", tool, err.Error())
+		}
+	}
+
+	var c = exec.Command(sh, "-c", guestToolsCmd)
+
+	c.Env = []string{"PATH=" + dir}
+
+	if out, err = c.Output(); err != nil {
+		t.Fatalf("Failed to run %q: %s", guestToolsCmd, err.Error())
+	}
+
+	for _, l := range nonEmpty(strings.Split(string(out), "\n")) {
+		found = append(found, filepath.Base(l))
+	}
+
+	if !slices.Equal(found, []string{"podman", "jls"}) {
+		t.Errorf("Unexpected tools: %v (expected [podman jls])", found)
+	}
+} // func TestGuestToolsCmd(t *testing.T)
+
+func TestParseGuests(t *testing.T) {
+	type testCase struct {
+		name   string
+		parse  func([]string, time.Time) ([]model.Guest, error)
+		output []string
+		expect []model.Guest
+	}
+
+	var (
+		now   = time.Unix(1723300000, 0)
+		cases = []testCase{
+			{
+				name:  "docker",
+				parse: parseContainers,
+				output: []string{
+					`{"Command":"\"/docker-entrypoint.…\"","ID":"3f1c","Image":"nginx:latest","Names":"web","State":"running","Status":"Up 2 hours (healthy)"}`,
+					`{"Command":"\"redis-server\"","ID":"9a2b","Image":"redis:7","Names":"cache,cache-alias","State":"exited","Status":"Exited (0) 3 days ago"}`,
+				},
+				expect: []model.Guest{
+					{Name: "web", Image: "nginx:latest", State: "running", Started: now.Add(-2 * time.Hour)},
+					{Name: "cache", Image: "redis:7", State: "exited"},
+				},
+			},
+			{
+				name:  "podman",
+				parse: parseContainers,
+				output: []string{
+					`[{"Names": ["db"], "Image": "docker.io/library/postgres:16", "State": "running", "StartedAt": 1723290000, "Status": "Up 2 hours"}]`,
+				},
+				expect: []model.Guest{
+					{Name: "db", Image: "docker.io/library/postgres:16", State: "running", Started: time.Unix(1723290000, 0)},
+				},
+			},
+			{
+				name:  "virsh",
+				parse: parseVirsh,
+				output: []string{
+					" Id   Name     State",
+					"-------------------------",
+					" 1    web      running",
+					" -    backup   shut off",
+					"",
+				},
+				expect: []model.Guest{
+					{Name: "web", State: "running"},
+					{Name: "backup", State: "shut off"},
+				},
+			},
+			{
+				name:  "jls",
+				parse: parseJls,
+				output: []string{
+					`{"__version": "2", "jail-information": {"jail": [{"jid":1,"ipv4":"10.0.0.2","ipv6":"","hostname":"www.example.com","path":"/usr/jails/www","name":"www"}]}}`,
+				},
+				expect: []model.Guest{
+					{Name: "www", Image: "/usr/jails/www", State: "running"},
+				},
+			},
+		}
+	)
+
+	for _, c := range cases {
+		var (
+			err    error
+			guests []model.Guest
+		)
+
+		if guests, err = c.parse(c.output, now); err != nil {
+			t.Errorf("%s: Failed to parse output: %s", c.name, err.Error())
+			continue
+		} else if len(guests) != len(c.expect) {
+			t.Errorf("%s: Expected %d guests, got %d",
+				c.name,
+				len(c.expect),
+				len(guests))
+			continue
+		}
+
+		for i, g := range guests {
+			if g != c.expect[i] {
+				t.Errorf("%s: Unexpected guest: %#v (expected %#v)",
+					c.name,
+					g,
+					c.expect[i])
+			}
+		}
+	}
+} // func TestParseGuests(t *testing.T)
+
+func TestParseHumanDuration(t *testing.T) {
+	var cases = map[string]time.Duration{
+		"2 hours (healthy)":  2 * time.Hour,
+		"About a minute":     time.Minute,
+		"About an hour":      time.Hour,
+		"Less than a second": time.Second,
+		"3 weeks":            3 * 7 * 24 * time.Hour,
+	}
+
+	for s, expect := range cases {
+		if d, ok := parseHumanDuration(s); !ok {
+			t.Errorf("Cannot parse duration %q", s)
+		} else if d != expect {
+			t.Errorf("Unexpected duration for %q: %s (expected %s)", s, d, expect)
+		}
+	}
+
+	if _, ok := parseHumanDuration("Exited (0) 3 days ago"); ok {
+		t.Error("parseHumanDuration should not accept a status")
+	}
+} // func TestParseHumanDuration(t *testing.T)
+
+// TestHandshakeTimeout makes sure we give up on a server that accepts the
+// connection but never says a word, rather than wait for it forever.
+func TestHandshakeTimeout(t *testing.T) {
+	var (
+		err    error
+		lst    net.Listener
+		conn   net.Conn
+		start  time.Time
+		cancel context.CancelFunc
+		ctx    context.Context
+		cfg    = &ssh.ClientConfig{
+			User:            "nobody",
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(), // nolint: gosec
+		}
+	)
+
+	if lst, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+		t.Skipf("Cannot listen on localhost: %s", err.Error())
+	}
+
+	defer lst.Close() // nolint: errcheck
+
+	go func() {
+		if c, aerr := lst.Accept(); aerr == nil {
+			defer c.Close() // nolint: errcheck
+			time.Sleep(time.Second * 5)
+		}
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+
+	if conn, err = net.Dial("tcp", lst.Addr().String()); err != nil {
+		t.Fatalf("Cannot connect to %s: %s", lst.Addr(), err.Error())
+	}
+
+	start = time.Now()
+
+	if _, err = handshake(ctx, conn, lst.Addr().String(), cfg); err == nil {
+		t.Fatal("Handshake with a silent server should fail")
+	} else if d := time.Since(start); d > time.Second*2 {
+		t.Errorf("Handshake took %s to give up", d)
+	}
+} // func TestHandshakeTimeout(t *testing.T)
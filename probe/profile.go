@@ -0,0 +1,246 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/profile.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:25:29 krylon>
+
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/blicero/carebear/model"
+	"github.com/blicero/krylib"
+	"golang.org/x/crypto/ssh"
+)
+
+// loadKey reads a private key from the given file.
+func (p *Probe) loadKey(path string) (ssh.Signer, error) {
+	var (
+		err    error
+		keyRaw []byte
+		signer ssh.Signer
+	)
+
+	if strings.HasPrefix(path, "~/") {
+		path = filepath.Join(krylib.GetHomeDirectory(), path[2:])
+	}
+
+	if keyRaw, err = os.ReadFile(path); err != nil {
+		var ex = fmt.Errorf("Failed to read SSH key from %s: %w",
+			path,
+			err)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	} else if signer, err = ssh.ParsePrivateKey(keyRaw); err != nil {
+		var (
+			pmerr *ssh.PassphraseMissingError
+			ex    = fmt.Errorf("Failed to parse SSH key %s: %w",
+				path,
+				err)
+		)
+
+		// Encrypted keys are not an error per se, the caller decides
+		// what to do about them.
+		if !errors.As(err, &pmerr) {
+			p.log.Printf("[ERROR] %s\n", ex.Error())
+		}
+		return nil, ex
+	} else if signer == nil {
+		var ex = fmt.Errorf("ParsePrivateKey did not return an error, but signer is nil!\nKey File: %s",
+			path)
+		p.log.Printf("[ERROR] %s\n",
+			ex.Error())
+		return nil, ex
+	}
+
+	return signer, nil
+} // func (p *Probe) loadKey(path string) (ssh.Signer, error)
+
+// clientConfig assembles the SSH client configuration to connect to the
+// given Device, taking into account the Device's SSH profile, if it has one.
+// It returns the configuration and the port to connect to.
+func (p *Probe) clientConfig(d *model.Device, prof *model.SSHProfile) (*ssh.ClientConfig, int, error) {
+	var (
+		cfg  = *p.cfg
+		port = p.port
+	)
+
+	cfg.HostKeyCallback = p.hostKeyCallback(d)
+
+	if prof == nil {
+		return &cfg, port, nil
+	}
+
+	if prof.User != "" {
+		cfg.User = prof.User
+	}
+
+	if prof.Port != 0 {
+		port = prof.Port
+	}
+
+	if prof.KeyFile != "" {
+		var (
+			err    error
+			signer ssh.Signer
+		)
+
+		if signer, err = p.loadKey(prof.KeyFile); err != nil {
+			var pmerr *ssh.PassphraseMissingError
+
+			if !errors.As(err, &pmerr) {
+				return nil, 0, err
+			}
+
+			// If the key is encrypted, it is hopefully held by
+			// ssh-agent, which we try anyway.
+			p.log.Printf("[WARN] SSH key %s for %s is protected by a passphrase, relying on ssh-agent\n",
+				prof.KeyFile,
+				d.Name)
+		} else {
+			// The Device's own key goes first, the default keys remain
+			// available as a fallback.
+			cfg.Auth = []ssh.AuthMethod{p.authMethod(signer)}
+		}
+	}
+
+	return &cfg, port, nil
+} // func (p *Probe) clientConfig(d *model.Device, prof *model.SSHProfile) (*ssh.ClientConfig, int, error)
+
+// parseJumpHost splits a ProxyJump specification of the form
+// [user@]host[:port] into its components. Missing parts are
+// filled in with the given defaults.
+func parseJumpHost(spec, defUser string, defPort int) (user, host string, port int, err error) {
+	user = defUser
+	port = defPort
+	host = strings.TrimSpace(spec)
+
+	if idx := strings.LastIndex(host, "@"); idx != -1 {
+		user = host[:idx]
+		host = host[idx+1:]
+	}
+
+	if h, pstr, e := net.SplitHostPort(host); e == nil {
+		host = h
+		if port, err = strconv.Atoi(pstr); err != nil {
+			return "", "", 0, fmt.Errorf("Invalid port in jump host %q: %w",
+				spec,
+				err)
+		}
+	}
+
+	if host == "" || user == "" {
+		return "", "", 0, fmt.Errorf("Invalid jump host %q", spec)
+	}
+
+	return user, host, port, nil
+} // func parseJumpHost(spec, defUser string, defPort int) (user, host string, port int, err error)
+
+// dialJump connects to the jump host given in the Device's SSH profile.
+//
+// If the jump host is a Device we know, its host key is verified the same way
+// as for any other Device. Otherwise, we can only verify it if we have a
+// known_hosts file, so we refuse to connect if we don't.
+func (p *Probe) dialJump(ctx context.Context, d *model.Device, prof *model.SSHProfile) (*ssh.Client, error) {
+	var (
+		err    error
+		user   string
+		host   string
+		port   int
+		jdev   *model.Device
+		client *ssh.Client
+		cfg    = *p.cfg
+	)
+
+	if user, host, port, err = parseJumpHost(prof.ProxyJump, p.cfg.User, p.port); err != nil {
+		p.log.Printf("[ERROR] Cannot use jump host for %s: %s\n",
+			d.Name,
+			err.Error())
+		return nil, err
+	}
+
+	p.dbLock.Lock()
+	jdev, err = p.db.DeviceGetByName(host)
+	p.dbLock.Unlock()
+
+	if err != nil {
+		p.log.Printf("[ERROR] Failed to look up jump host %s: %s\n",
+			host,
+			err.Error())
+		return nil, err
+	} else if jdev != nil {
+		cfg.HostKeyCallback = p.hostKeyCallback(jdev)
+	} else if p.known != nil {
+		cfg.HostKeyCallback = p.known
+	} else {
+		var ex = fmt.Errorf("Cannot verify host key of jump host %s for %s: it is neither a known Device nor listed in known_hosts",
+			host,
+			d.Name)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	cfg.User = user
+
+	var addr = net.JoinHostPort(host, strconv.Itoa(port))
+
+	if client, err = p.dial(ctx, addr, &cfg); err != nil {
+		var ex = fmt.Errorf("Failed to connect to jump host %s for %s: %w",
+			addr,
+			d.Name,
+			err)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	return client, nil
+} // func (p *Probe) dialJump(ctx context.Context, d *model.Device, prof *model.SSHProfile) (*ssh.Client, error)
+
+// connectVia connects to the Device through the given jump host.
+func (p *Probe) connectVia(ctx context.Context, jump *ssh.Client, d *model.Device, cfg *ssh.ClientConfig, port int) (*ssh.Client, error) {
+	var err error
+
+	for _, a := range d.Addr {
+		var (
+			conn   net.Conn
+			client *ssh.Client
+			addr   = net.JoinHostPort(a.String(), strconv.Itoa(port))
+		)
+
+		if conn, err = jump.DialContext(ctx, "tcp", addr); err != nil {
+			p.log.Printf("[ERROR] Failed to reach %s at %s via jump host: %s\n",
+				d.Name,
+				addr,
+				err.Error())
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			continue
+		} else if client, err = handshake(ctx, conn, addr, cfg); err != nil {
+			p.log.Printf("[ERROR] Failed to connect to %s at %s via jump host: %s\n",
+				d.Name,
+				addr,
+				err.Error())
+			if errors.Is(err, ErrHostKeyMismatch) || ctx.Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		return client, nil
+	}
+
+	if err == nil {
+		err = fmt.Errorf("Device %s has no addresses", d.Name)
+	}
+
+	return nil, err
+} // func (p *Probe) connectVia(ctx context.Context, jump *ssh.Client, d *model.Device, cfg *ssh.ClientConfig, port int) (*ssh.Client, error)
@@ -0,0 +1,135 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/services.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:36:12 krylon>
+
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/blicero/carebear/model"
+)
+
+// FreeBSD has no notion of a failed service, so we ask each enabled rc
+// script for its status and pick the ones that should be running but are
+// not. Scripts that do not support the status command, e.g. the ones that
+// run once at boot, print a usage message instead, which we ignore.
+const (
+	failedCmdSystemd = "systemctl --failed --output=json --no-pager"
+	failedCmdFreeBSD = "for s in $(service -e); do $s status 2>&1 | grep 'is not running'; done; true"
+	failedCmdOpenBSD = "rcctl ls failed"
+)
+
+// suffixNotRunning is what rc.subr appends to the name of a stopped service.
+const suffixNotRunning = " is not running."
+
+type systemdUnit struct {
+	Unit        string `json:"unit"`
+	Load        string `json:"load"`
+	Active      string `json:"active"`
+	Sub         string `json:"sub"`
+	Description string `json:"description"`
+}
+
+// QueryFailedUnits asks the given Device for services that have failed.
+// Linux systems without systemd are not supported.
+func (p *Probe) QueryFailedUnits(ctx context.Context, d *model.Device) ([]*model.FailedUnit, error) {
+	var (
+		err    error
+		status int
+		cmd    string
+		output []string
+		units  []*model.FailedUnit
+	)
+
+	switch strings.ToLower(d.OSID) {
+	case "freebsd", "dragonfly":
+		cmd = failedCmdFreeBSD
+	case "openbsd":
+		cmd = asRoot(failedCmdOpenBSD)
+	case "netbsd":
+		return nil, ErrUnsupported
+	default:
+		cmd = failedCmdSystemd
+	}
+
+	if output, status, err = p.runCommand(ctx, d, cmd); err != nil {
+		return nil, err
+	} else if status == 127 {
+		return nil, ErrUnsupported
+	} else if status != 0 {
+		var ex = fmt.Errorf("Command on %s exited with status %d\n>>> Command: %s\n%s",
+			d.Name,
+			status,
+			cmd,
+			strings.Join(output, "\n"))
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	if cmd == failedCmdSystemd {
+		if units, err = parseFailedSystemd([]byte(strings.Join(output, "\n"))); err != nil {
+			var ex = fmt.Errorf("Cannot parse failed units of %s: %w",
+				d.Name,
+				err)
+			p.log.Printf("[ERROR] %s\n", ex.Error())
+			return nil, ex
+		}
+	} else {
+		units = parseFailedRC(output)
+	}
+
+	for _, u := range units {
+		u.DevID = d.ID
+	}
+
+	return units, nil
+} // func (p *Probe) QueryFailedUnits(ctx context.Context, d *model.Device) ([]*model.FailedUnit, error)
+
+// parseFailedSystemd parses the output of systemctl --failed --output=json.
+func parseFailedSystemd(buf []byte) ([]*model.FailedUnit, error) {
+	var (
+		err   error
+		list  []systemdUnit
+		units []*model.FailedUnit
+	)
+
+	if err = json.Unmarshal(buf, &list); err != nil {
+		return nil, err
+	}
+
+	units = make([]*model.FailedUnit, len(list))
+
+	for i, u := range list {
+		units[i] = &model.FailedUnit{
+			Name:        u.Unit,
+			Description: u.Description,
+		}
+	}
+
+	return units, nil
+} // func parseFailedSystemd(buf []byte) ([]*model.FailedUnit, error)
+
+// parseFailedRC parses the output of failedCmdFreeBSD and failedCmdOpenBSD.
+// rcctl prints just the names of the failed daemons, rc.subr says
+// "<name> is not running."
+func parseFailedRC(output []string) []*model.FailedUnit {
+	var units = make([]*model.FailedUnit, 0)
+
+	for _, l := range nonEmpty(output) {
+		var name = strings.TrimSuffix(l, suffixNotRunning)
+
+		if strings.ContainsAny(name, " \t") {
+			continue
+		}
+
+		units = append(units, &model.FailedUnit{Name: name})
+	}
+
+	return units
+} // func parseFailedRC(output []string) []*model.FailedUnit
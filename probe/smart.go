@@ -0,0 +1,186 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/smart.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:09:31 krylon>
+
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blicero/carebear/model"
+)
+
+// smartctl needs root privileges to talk to the disks, so both commands are
+// subject to privilege escalation. Anything smartctl prints to stderr would
+// end up in the JSON we try to parse, so we discard it. We must not discard
+// what sudo or doas have to say, though, so the redirection goes inside the
+// escalated command. If smartctl is missing, sh exits with status 127.
+const (
+	smartScanCmd = "sh -c 'smartctl --scan --json 2>/dev/null'"
+	smartInfoCmd = "sh -c 'smartctl --json -a -d %s %s 2>/dev/null'"
+)
+
+// smartctl's exit status is a bit mask. Bits 0 and 1 mean smartctl could
+// not parse its command line or open the device, the higher bits report
+// problems with the disk, which we want to hear about.
+const smartFatal = 0x03
+
+// attrReallocated is the ID of the ATA attribute that counts reallocated sectors.
+const attrReallocated = 5
+
+type smartScan struct {
+	Devices []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"devices"`
+}
+
+type smartReport struct {
+	Device struct {
+		Name string `json:"name"`
+	} `json:"device"`
+	ModelName    string `json:"model_name"`
+	SerialNumber string `json:"serial_number"`
+	SmartStatus  *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	PowerOnTime *struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	Temperature *struct {
+		Current int64 `json:"current"`
+	} `json:"temperature"`
+	ATAAttributes *struct {
+		Table []struct {
+			ID  int `json:"id"`
+			Raw struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeLog *struct {
+		MediaErrors int64 `json:"media_errors"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// QuerySmart asks smartctl on the given Device about the health of its disks.
+// Disks that do not support SMART are skipped.
+func (p *Probe) QuerySmart(ctx context.Context, d *model.Device) ([]*model.SmartInfo, error) {
+	var (
+		err    error
+		status int
+		output []string
+		scan   smartScan
+		disks  []*model.SmartInfo
+		now    = time.Now()
+	)
+
+	if output, status, err = p.runCommand(ctx, d, asRoot(smartScanCmd)); err != nil {
+		return nil, err
+	} else if status == statusNotFound {
+		return nil, ErrUnsupported
+	} else if status&smartFatal != 0 {
+		var ex = fmt.Errorf("smartctl --scan on %s exited with status %d",
+			d.Name,
+			status)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	} else if err = json.Unmarshal([]byte(strings.Join(output, "\n")), &scan); err != nil {
+		var ex = fmt.Errorf("Cannot parse list of disks on %s: %w",
+			d.Name,
+			err)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	disks = make([]*model.SmartInfo, 0, len(scan.Devices))
+
+	for _, dev := range scan.Devices {
+		var (
+			si  *model.SmartInfo
+			cmd = fmt.Sprintf(smartInfoCmd, dev.Type, dev.Name)
+		)
+
+		if output, status, err = p.runCommand(ctx, d, asRoot(cmd)); err != nil {
+			return nil, err
+		} else if status&smartFatal != 0 {
+			p.log.Printf("[ERROR] smartctl failed to query %s on %s, exit status %d\n",
+				dev.Name,
+				d.Name,
+				status)
+			continue
+		} else if si, err = parseSmart([]byte(strings.Join(output, "\n"))); err != nil {
+			p.log.Printf("[ERROR] Cannot parse SMART status of %s on %s: %s\n",
+				dev.Name,
+				d.Name,
+				err.Error())
+			continue
+		} else if si == nil {
+			continue
+		}
+
+		si.DevID = d.ID
+		si.Timestamp = now
+		disks = append(disks, si)
+	}
+
+	return disks, nil
+} // func (p *Probe) QuerySmart(ctx context.Context, d *model.Device) ([]*model.SmartInfo, error)
+
+// parseSmart extracts the values we care about from the output of
+// smartctl --json -a. If the disk does not report an overall health status,
+// it does not support SMART, and we return nil.
+// For NVMe disks, we count media errors as reallocated sectors, they are
+// the closest equivalent.
+func parseSmart(buf []byte) (*model.SmartInfo, error) {
+	var (
+		err error
+		rep smartReport
+	)
+
+	if err = json.Unmarshal(buf, &rep); err != nil {
+		return nil, err
+	} else if rep.Device.Name == "" {
+		return nil, errors.New("smartctl did not report a device name")
+	} else if rep.SmartStatus == nil {
+		return nil, nil
+	}
+
+	var si = &model.SmartInfo{
+		Disk:         rep.Device.Name,
+		Model:        rep.ModelName,
+		Serial:       rep.SerialNumber,
+		Passed:       rep.SmartStatus.Passed,
+		Reallocated:  -1,
+		PowerOnHours: -1,
+		Temperature:  -1,
+	}
+
+	if rep.PowerOnTime != nil {
+		si.PowerOnHours = rep.PowerOnTime.Hours
+	}
+
+	if rep.Temperature != nil {
+		si.Temperature = rep.Temperature.Current
+	}
+
+	if rep.ATAAttributes != nil {
+		for _, attr := range rep.ATAAttributes.Table {
+			if attr.ID == attrReallocated {
+				si.Reallocated = attr.Raw.Value
+				break
+			}
+		}
+	} else if rep.NVMeLog != nil {
+		si.Reallocated = rep.NVMeLog.MediaErrors
+	}
+
+	return si, nil
+} // func parseSmart(buf []byte) (*model.SmartInfo, error)
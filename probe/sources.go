@@ -0,0 +1,257 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/sources.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:43:03 krylon>
+
+package probe
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blicero/carebear/model"
+)
+
+// updateSource is a package manager that installs software next to the one
+// our OSDriver deals with. On FreeBSD and OpenBSD, freebsd-update and
+// syspatch only cover the base system, third-party software comes from
+// pkg. On Linux desktops, many applications come from Flatpak or Snap.
+//
+// We try every source that applies to a Device's OS, and skip the ones
+// that are not installed.
+type updateSource struct {
+	// Name is what we tag the updates from the source with.
+	Name string
+	// Cmd lists the pending updates.
+	Cmd string
+	// Applies returns true if the source may be present on the Device.
+	Applies func(d *model.Device) bool
+	// Parse extracts the pending updates from the output of Cmd.
+	Parse func(output []string) []*model.PackageUpdate
+}
+
+// pkg version -R and pkg_add -u want to fetch the remote catalogue, which
+// takes root privileges. Flatpak and Snap only need to read it.
+const (
+	pkgVersionCmd    = rootPrefix + "pkg version -vRL="
+	pkgAddUpdateCmd  = rootPrefix + "pkg_add -u -n"
+	flatpakUpdateCmd = "flatpak remote-ls --updates --columns=application,version,branch,arch,origin"
+	snapUpdateCmd    = "snap refresh --list"
+)
+
+var updateSources = []updateSource{
+	{
+		Name:    "pkg",
+		Cmd:     pkgVersionCmd,
+		Applies: isOS("freebsd"),
+		Parse:   parsePkgVersion,
+	},
+	{
+		Name:    "pkg_add",
+		Cmd:     pkgAddUpdateCmd,
+		Applies: isOS("openbsd"),
+		Parse:   parsePkgAdd,
+	},
+	{
+		Name:    "flatpak",
+		Cmd:     flatpakUpdateCmd,
+		Applies: isLinux,
+		Parse:   parseFlatpak,
+	},
+	{
+		Name:    "snap",
+		Cmd:     snapUpdateCmd,
+		Applies: isLinux,
+		Parse:   parseSnap,
+	},
+}
+
+// isOS returns a function that checks if a Device runs the OS with the given ID.
+func isOS(id string) func(d *model.Device) bool {
+	return func(d *model.Device) bool {
+		return strings.EqualFold(d.OSID, id)
+	}
+} // func isOS(id string) func(d *model.Device) bool
+
+// isLinux returns true if the given Device runs neither one of the BSDs nor macOS.
+func isLinux(d *model.Device) bool {
+	switch strings.ToLower(d.OSID) {
+	case "darwin", "macos":
+		return false
+	default:
+		return !isBSD(d)
+	}
+} // func isLinux(d *model.Device) bool
+
+// Sample output:
+// curl-8.9.0                         <   needs updating (remote has 8.9.1)
+// pkg-1.21.3                         ?   orphaned: ports-mgmt/pkg
+
+var patPkgVersionRemote = regexp.MustCompile(`^(\S+)\s+<\s+needs updating \(remote has ([^)]+)\)`)
+
+// parsePkgVersion parses the output of pkg version -vRL=, skipping packages
+// that are newer than the remote version or that are not in the repository.
+func parsePkgVersion(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0)
+
+	for _, l := range nonEmpty(output) {
+		var match, pkg []string
+
+		if match = patPkgVersionRemote.FindStringSubmatch(l); match == nil {
+			continue
+		} else if pkg = patPkgVersion.FindStringSubmatch(match[1]); pkg == nil {
+			continue
+		}
+
+		updates = append(updates, &model.PackageUpdate{
+			Name:           pkg[1],
+			CurrentVersion: pkg[2],
+			NewVersion:     match[2],
+		})
+	}
+
+	return updates
+} // func parsePkgVersion(output []string) []*model.PackageUpdate
+
+// Sample output:
+// quirks-7.14 signed on 2024-08-10T12:34:56Z
+// curl-8.9.0->8.9.1: ok
+
+var patPkgAddUpdate = regexp.MustCompile(`^(\S+?)->(\S+?):`)
+
+// parsePkgAdd parses the output of pkg_add -u -n. pkg_add usually only
+// gives the new version, but it spells out the full name of the new
+// package if it differs from the old one.
+func parsePkgAdd(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0)
+
+	for _, l := range nonEmpty(output) {
+		var match, pkg, upd []string
+
+		if match = patPkgAddUpdate.FindStringSubmatch(l); match == nil {
+			continue
+		} else if pkg = patPkgVersion.FindStringSubmatch(match[1]); pkg == nil {
+			continue
+		}
+
+		var u = &model.PackageUpdate{
+			Name:           pkg[1],
+			CurrentVersion: pkg[2],
+			NewVersion:     match[2],
+		}
+
+		if upd = patPkgVersion.FindStringSubmatch(match[2]); upd != nil && upd[1] == pkg[1] {
+			u.NewVersion = upd[2]
+		}
+
+		updates = append(updates, u)
+	}
+
+	return updates
+} // func parsePkgAdd(output []string) []*model.PackageUpdate
+
+// parseFlatpak parses the tab-separated output of flatpak remote-ls with
+// the columns given in flatpakUpdateCmd. Many applications do not set a
+// version, so we fall back to the branch.
+func parseFlatpak(output []string) []*model.PackageUpdate {
+	var updates = make([]*model.PackageUpdate, 0)
+
+	for _, l := range output {
+		var fields = strings.Split(l, "\t")
+
+		if len(fields) < 5 || fields[0] == "Application ID" {
+			continue
+		}
+
+		var u = &model.PackageUpdate{
+			Name:       strings.TrimSpace(fields[0]),
+			NewVersion: strings.TrimSpace(fields[1]),
+			Arch:       strings.TrimSpace(fields[3]),
+			Repo:       strings.TrimSpace(fields[4]),
+		}
+
+		if u.NewVersion == "" {
+			u.NewVersion = strings.TrimSpace(fields[2])
+		}
+
+		updates = append(updates, u)
+	}
+
+	return updates
+} // func parseFlatpak(output []string) []*model.PackageUpdate
+
+// Sample output:
+// Name     Version        Rev    Size   Publisher   Notes
+// firefox  130.0-2        4848   278MB  mozilla✓    -
+//
+// If there is nothing to update, snap says "All snaps up to date." instead.
+
+// parseSnap parses the output of snap refresh --list. Only the lines after
+// the header are updates.
+func parseSnap(output []string) []*model.PackageUpdate {
+	var (
+		updates = make([]*model.PackageUpdate, 0)
+		table   bool
+	)
+
+	for _, l := range nonEmpty(output) {
+		var fields = strings.Fields(l)
+
+		if len(fields) > 0 && fields[0] == "Name" {
+			table = true
+			continue
+		} else if !table || len(fields) < 5 {
+			continue
+		}
+
+		updates = append(updates, &model.PackageUpdate{
+			Name:       fields[0],
+			NewVersion: fields[1],
+		})
+	}
+
+	return updates
+} // func parseSnap(output []string) []*model.PackageUpdate
+
+// querySource asks the given Device for the pending updates from one
+// updateSource and tags them with the source's name. If the source is not
+// installed on the Device, there is nothing to report.
+func (p *Probe) querySource(ctx context.Context, d *model.Device, src *updateSource) ([]*model.PackageUpdate, error) {
+	var (
+		err    error
+		status int
+		output []string
+	)
+
+	if output, status, err = p.runCommand(ctx, d, src.Cmd); err != nil {
+		return nil, err
+	} else if status == statusNotFound {
+		return nil, nil
+	} else if status != 0 {
+		var ex = fmt.Errorf("Command on %s (%s) exited with status %d\n>>> Command: %s\n%s",
+			d.Name,
+			src.Name,
+			status,
+			src.Cmd,
+			strings.Join(output, "\n"))
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return nil, ex
+	}
+
+	return tagSource(src.Parse(output), src.Name), nil
+} // func (p *Probe) querySource(ctx context.Context, d *model.Device, src *updateSource) ([]*model.PackageUpdate, error)
+
+// tagSource sets the Source of the given updates to src, unless the parser
+// has set it already.
+func tagSource(updates []*model.PackageUpdate, src string) []*model.PackageUpdate {
+	for _, u := range updates {
+		if u.Source == "" {
+			u.Source = src
+		}
+	}
+
+	return updates
+} // func tagSource(updates []*model.PackageUpdate, src string) []*model.PackageUpdate
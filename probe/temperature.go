@@ -0,0 +1,129 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/temperature.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:04:52 krylon>
+
+package probe
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/carebear/model"
+)
+
+// On Linux, we read the thermal zones and the hwmon sensors from sysfs and
+// print one line per sensor, the name and the temperature in millidegrees,
+// separated by a tab.
+//
+// On the BSDs, we ask sysctl. FreeBSD has dev.cpu.N.temperature and the
+// ACPI thermal zones, OpenBSD puts everything under hw.sensors.
+const (
+	tempCmdLinux = `for z in /sys/class/thermal/thermal_zone*; do ` +
+		`[ -r "$z/temp" ] && printf '%s\t%s\n' "$(cat "$z/type")" "$(cat "$z/temp")"; done; ` +
+		`for h in /sys/class/hwmon/hwmon*; do for t in "$h"/temp*_input; do ` +
+		`[ -r "$t" ] && printf '%s/%s\t%s\n' "$(cat "$h/name")" ` +
+		`"$(cat "${t%_input}_label" 2>/dev/null || basename "${t%_input}")" "$(cat "$t")"; done; done; true`
+	tempCmdFreeBSD = "sysctl -e dev.cpu hw.acpi.thermal 2>/dev/null | grep temperature; true"
+	tempCmdOpenBSD = "sysctl hw.sensors 2>/dev/null | grep degC; true"
+)
+
+// Sample output:
+// dev.cpu.0.temperature=45.0C
+// hw.sensors.cpu0.temp0=45.00 degC
+
+var patTempBSD = regexp.MustCompile(`^([^=]+)=(-?\d+(?:\.\d+)?)\s*(?:degC|C)\b`)
+
+// QueryTemperature reads the temperature sensors of the given Device.
+// Devices without any sensors, e.g. most virtual machines, yield an empty
+// set of readings.
+func (p *Probe) QueryTemperature(ctx context.Context, d *model.Device) (*model.Temperature, error) {
+	var (
+		err    error
+		cmd    string
+		output []string
+		temp   = &model.Temperature{
+			DevID:     d.ID,
+			Timestamp: time.Now(),
+		}
+	)
+
+	switch {
+	case !isBSD(d):
+		cmd = tempCmdLinux
+	case strings.EqualFold(d.OSID, "openbsd"):
+		cmd = tempCmdOpenBSD
+	default:
+		cmd = tempCmdFreeBSD
+	}
+
+	if output, err = p.executeCommand(ctx, d, cmd); err != nil {
+		return nil, err
+	}
+
+	if cmd == tempCmdLinux {
+		temp.Sensors = parseTemperatureLinux(output)
+	} else {
+		temp.Sensors = parseTemperatureBSD(output)
+	}
+
+	return temp, nil
+} // func (p *Probe) QueryTemperature(ctx context.Context, d *model.Device) (*model.Temperature, error)
+
+// parseTemperatureLinux parses the output of tempCmdLinux. sysfs reports
+// temperatures in millidegrees Celsius.
+func parseTemperatureLinux(output []string) []model.Sensor {
+	var sensors = make([]model.Sensor, 0, len(output))
+
+	for _, l := range nonEmpty(output) {
+		var (
+			err       error
+			milli     int64
+			name, val string
+			ok        bool
+		)
+
+		if name, val, ok = strings.Cut(l, "\t"); !ok {
+			continue
+		} else if milli, err = strconv.ParseInt(strings.TrimSpace(val), 10, 64); err != nil {
+			continue
+		}
+
+		sensors = append(sensors, model.Sensor{
+			Name:    strings.TrimSpace(name),
+			Celsius: float64(milli) / 1000,
+		})
+	}
+
+	return sensors
+} // func parseTemperatureLinux(output []string) []model.Sensor
+
+// parseTemperatureBSD parses the output of sysctl on FreeBSD and OpenBSD.
+func parseTemperatureBSD(output []string) []model.Sensor {
+	var sensors = make([]model.Sensor, 0, len(output))
+
+	for _, l := range nonEmpty(output) {
+		var (
+			err   error
+			deg   float64
+			match []string
+		)
+
+		if match = patTempBSD.FindStringSubmatch(l); match == nil {
+			continue
+		} else if deg, err = strconv.ParseFloat(match[2], 64); err != nil {
+			continue
+		}
+
+		sensors = append(sensors, model.Sensor{
+			Name:    strings.TrimPrefix(match[1], "hw.sensors."),
+			Celsius: deg,
+		})
+	}
+
+	return sensors
+} // func parseTemperatureBSD(output []string) []model.Sensor
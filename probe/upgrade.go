@@ -0,0 +1,163 @@
+// /home/krylon/go/src/github.com/blicero/carebear/probe/upgrade.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:25:29 krylon>
+
+package probe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blicero/carebear/model"
+	"github.com/blicero/carebear/settings"
+	"golang.org/x/crypto/ssh"
+)
+
+// upgradeTimeout is the maximum amount of time we allow an upgrade to run.
+// Installing a large batch of updates over a slow mirror takes a lot
+// longer than any of the commands we run to query a Device.
+const upgradeTimeout = time.Hour
+
+// syncWriter serializes writes to an io.Writer. The SSH session copies
+// stdout and stderr in separate goroutines, and we want both to end up
+// in the same place.
+type syncWriter struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+func (s *syncWriter) Write(b []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.w.Write(b)
+} // func (s *syncWriter) Write(b []byte) (int, error)
+
+// headBuffer keeps the first max bytes written to it and discards the
+// rest. sudo and doas complain right away if they refuse to run a command,
+// so we do not need to keep all the output of an upgrade to notice.
+type headBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (h *headBuffer) Write(b []byte) (int, error) {
+	if room := h.max - h.buf.Len(); room > 0 {
+		h.buf.Write(b[:min(room, len(b))])
+	}
+
+	return len(b), nil
+} // func (h *headBuffer) Write(b []byte) (int, error)
+
+// ApplyUpdates installs the pending updates on the given Device. The output
+// of the upgrade command is copied to out as it arrives. It returns the
+// command's exit status.
+func (p *Probe) ApplyUpdates(ctx context.Context, d *model.Device, out io.Writer) (int, error) {
+	var (
+		err     error
+		drv     OSDriver
+		cmd     string
+		method  string
+		session *ssh.Session
+		cancel  context.CancelFunc
+		errQ    = make(chan error, 1)
+		head    = &headBuffer{max: 4096}
+		w       = &syncWriter{w: io.MultiWriter(out, head)}
+	)
+
+	if drv = DriverFor(d); drv == nil || drv.UpgradeCmd() == "" {
+		p.log.Printf("[TRACE] Don't know how to install updates on %s (running %s)\n",
+			d.Name,
+			d.OS)
+		return 0, ErrUnsupported
+	}
+
+	ctx, cancel = context.WithTimeout(ctx, upgradeTimeout)
+	defer cancel()
+
+	if session, err = p.getSession(ctx, d); err != nil {
+		if err == ErrPingOffline {
+			return 0, err
+		}
+		var ex = fmt.Errorf("Failed to create SSH session for %s: %w",
+			d.Name,
+			err)
+		p.log.Printf("[ERROR] %s\n", ex.Error())
+		return 0, ex
+	}
+
+	defer session.Close()
+
+	method = p.escalation(d)
+	cmd = escalateCmd(method, asRoot(drv.UpgradeCmd()))
+
+	session.Stdout = w
+	session.Stderr = w
+
+	p.log.Printf("[INFO] Install updates on %s: %s\n",
+		d.Name,
+		cmd)
+
+	go func() {
+		errQ <- session.Run(cmd)
+	}()
+
+	select {
+	case err = <-errQ:
+	case <-ctx.Done():
+		session.Close() // nolint: errcheck
+		_ = p.disconnect(d)
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			var ex = &TimeoutError{
+				Device:  d.Name,
+				Command: cmd,
+				Timeout: upgradeTimeout,
+			}
+			p.log.Printf("[ERROR] %s\n", ex.Error())
+			return 0, ex
+		}
+
+		return 0, fmt.Errorf("Command %q on %s was cancelled: %w",
+			cmd,
+			d.Name,
+			ctx.Err())
+	}
+
+	if err != nil {
+		var xerr *ssh.ExitError
+
+		if !errors.As(err, &xerr) {
+			var ex = fmt.Errorf("Failed to execute command on %s: %w\n>>> Command: %s",
+				d.Name,
+				err,
+				cmd)
+			p.log.Printf("[ERROR] %s\n", ex.Error())
+			return 0, ex
+		}
+
+		if method != settings.EscalateNone {
+			if msg := escalationFailed(strings.Split(head.buf.String(), "\n")); msg != "" {
+				var ex = &EscalationError{
+					Device:  d.Name,
+					Command: cmd,
+					Method:  method,
+					Message: msg,
+				}
+				p.log.Printf("[ERROR] %s\n", ex.Error())
+				return xerr.ExitStatus(), ex
+			}
+		}
+
+		return xerr.ExitStatus(), nil
+	}
+
+	return 0, nil
+} // func (p *Probe) ApplyUpdates(ctx context.Context, d *model.Device, out io.Writer) (int, error)
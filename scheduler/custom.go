@@ -0,0 +1,114 @@
+// /home/krylon/go/src/github.com/blicero/carebear/scheduler/custom.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:07:57 krylon>
+
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/blicero/carebear/database"
+	"github.com/blicero/carebear/model"
+	"github.com/blicero/carebear/probe"
+	"github.com/blicero/carebear/settings"
+)
+
+// loadCustomProbes prepares the custom probes from the configuration file
+// and registers their info types with the database. Probes that cannot be
+// prepared are logged and skipped.
+func (s *Scheduler) loadCustomProbes() []*probe.Custom {
+	var (
+		err    error
+		db     *database.Database
+		probes = make([]*probe.Custom, 0, len(settings.Settings.CustomProbes))
+	)
+
+	db = s.pool.Get()
+	defer s.pool.Put(db)
+
+	for _, cfg := range settings.Settings.CustomProbes {
+		var c *probe.Custom
+
+		if c, err = probe.NewCustom(cfg); err != nil {
+			s.log.Printf("[ERROR] %s\n", err.Error())
+			continue
+		} else if _, err = db.InfoTypeRegister(c.Name); err != nil {
+			s.log.Printf("[ERROR] Cannot register custom probe %s: %s\n",
+				c.Name,
+				err.Error())
+			continue
+		}
+
+		s.log.Printf("[INFO] Custom probe %s runs every %s\n",
+			c.Name,
+			c.Interval)
+
+		probes = append(probes, c)
+	}
+
+	return probes
+} // func (s *Scheduler) loadCustomProbes() []*probe.Custom
+
+// customProbeLoop runs the given custom probe on all Devices it applies to
+// at the probe's interval, until the Scheduler stops. Since each custom
+// probe has its own interval, they do not fit into the main loop.
+func (s *Scheduler) customProbeLoop(ctx context.Context, c *probe.Custom) {
+	var tick = time.NewTicker(c.Interval)
+	defer tick.Stop()
+
+	for s.IsActive() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			s.log.Printf("[INFO] Run custom probe %s\n", c.Name)
+			var customQ = make(chan *model.Device)
+			go s.deviceDispatch(customQ)
+
+			for i := range probeWorkerCnt {
+				go s.queryDeviceCustomWorker(ctx, i, c, customQ)
+			}
+		}
+	}
+} // func (s *Scheduler) customProbeLoop(ctx context.Context, c *probe.Custom)
+
+func (s *Scheduler) queryDeviceCustomWorker(ctx context.Context, id int, c *probe.Custom, devQ <-chan *model.Device) {
+	var (
+		err error
+		db  *database.Database
+		v   *model.CustomValue
+	)
+
+	defer s.log.Printf("[DEBUG] queryDeviceCustomWorker #%02d (%s) is quitting.\n",
+		id,
+		c.Name)
+
+	for d := range devQ {
+		if !c.Applies(d) {
+			continue
+		}
+
+		s.log.Printf("[TRACE] %02d: Run custom probe %s on %s\n",
+			id,
+			c.Name,
+			d.Name)
+
+		if v, err = s.p.QueryCustom(ctx, d, c); err != nil {
+			s.logProbeError(d, c.Name, err)
+			continue
+		}
+
+		db = s.pool.Get()
+		if err = db.CustomValueAdd(v); err != nil {
+			s.log.Printf("[ERROR] %02d Failed to add result of custom probe %s on %s to Database: %s\n",
+				id,
+				c.Name,
+				d.Name,
+				err.Error())
+		}
+		s.pool.Put(db)
+	}
+} // func (s *Scheduler) queryDeviceCustomWorker(ctx context.Context, id int, c *probe.Custom, devQ <-chan *model.Device)
@@ -0,0 +1,285 @@
+// /home/krylon/go/src/github.com/blicero/carebear/scheduler/jobs.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:08:29 krylon>
+
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/blicero/carebear/common"
+	"github.com/blicero/carebear/database"
+	"github.com/blicero/carebear/model"
+	"github.com/blicero/carebear/model/event"
+	"github.com/blicero/carebear/model/job"
+)
+
+var (
+	// ErrJobRunning indicates that a Job is already running on a Device.
+	ErrJobRunning = errors.New("Another job is already running on the Device")
+	// ErrCritical indicates that a Device is flagged as critical, and the
+	// user did not ask to override that.
+	ErrCritical = errors.New("Device is flagged as critical")
+)
+
+// After asking a Device to reboot or shut down, we ping it until it goes
+// down and, after a reboot, comes back. shutdown waits a minute before it
+// does anything, a reboot may take a while, especially on a machine with
+// lots of RAM to test or a RAID to check.
+const (
+	powerWatchInterval = time.Second * 10
+	powerDownTimeout   = time.Minute * 5
+	powerUpTimeout     = time.Minute * 30
+)
+
+// jobStart marks the given Device as busy. It returns false if another Job
+// is already running on it.
+func (s *Scheduler) jobStart(d *model.Device) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.busy[d.ID] {
+		return false
+	}
+
+	s.busy[d.ID] = true
+	return true
+} // func (s *Scheduler) jobStart(d *model.Device) bool
+
+func (s *Scheduler) jobDone(d *model.Device) {
+	s.lock.Lock()
+	delete(s.busy, d.ID)
+	s.lock.Unlock()
+} // func (s *Scheduler) jobDone(d *model.Device)
+
+// ApplyUpdates installs the pending updates on the given Device and records
+// the attempt as a Job. The output of the upgrade is copied to out as it
+// arrives, out should not fail, or the Device is left waiting for us to
+// read its output. Once the upgrade is done, we check for pending updates
+// again, so the web interface does not show the old ones.
+//
+// An upgrade can take a long time, so we only hold on to a database
+// connection while we record the Job.
+func (s *Scheduler) ApplyUpdates(ctx context.Context, d *model.Device, out io.Writer) (*model.Job, error) {
+	var (
+		err error
+		db  *database.Database
+		buf bytes.Buffer
+		j   = &model.Job{
+			DevID:   d.ID,
+			Kind:    job.Upgrade,
+			Started: time.Now(),
+		}
+	)
+
+	if !s.jobStart(d) {
+		return nil, ErrJobRunning
+	}
+
+	defer s.jobDone(d)
+
+	db = s.pool.Get()
+	err = db.JobAdd(j)
+	s.pool.Put(db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	j.Status, err = s.p.ApplyUpdates(ctx, d, io.MultiWriter(&buf, out))
+	j.Finished = time.Now()
+	j.Output = buf.String()
+
+	if err != nil {
+		j.Error = err.Error()
+		s.logProbeError(d, "upgrade", err)
+	} else {
+		s.log.Printf("[INFO] Upgrade of %s finished with status %d after %s\n",
+			d.Name,
+			j.Status,
+			j.Duration())
+	}
+
+	db = s.pool.Get()
+	err = db.JobFinish(j)
+	s.pool.Put(db)
+
+	if err != nil {
+		return j, err
+	} else if err = s.queryUpdates(ctx, d); err != nil {
+		s.logProbeError(d, "pending updates", err)
+	} else if err = s.queryNeedReboot(ctx, d); err != nil {
+		s.logProbeError(d, "reboot status", err)
+	}
+
+	return j, nil
+} // func (s *Scheduler) ApplyUpdates(ctx context.Context, d *model.Device, out io.Writer) (*model.Job, error)
+
+// Shutdown asks the given Device to reboot, or to shut down if reboot is
+// false, and records the attempt as a Job. Devices flagged as critical are
+// left alone unless override is true.
+//
+// Once the Device has accepted the request, we watch it go down and, after
+// a reboot, come back, and record the downtime on its timeline. Shutdown
+// does not wait for that, the Job is finished once we are done watching.
+func (s *Scheduler) Shutdown(ctx context.Context, d *model.Device, reboot, override bool) (*model.Job, error) {
+	var (
+		err    error
+		db     *database.Database
+		output []string
+		out    = new(strings.Builder)
+		j      = &model.Job{
+			DevID:   d.ID,
+			Kind:    job.Shutdown,
+			Started: time.Now(),
+		}
+	)
+
+	if reboot {
+		j.Kind = job.Reboot
+	}
+
+	if d.Critical && !override {
+		s.log.Printf("[INFO] Refusing to %s %s, it is flagged as critical\n",
+			strings.ToLower(j.Kind.String()),
+			d.Name)
+		return nil, ErrCritical
+	} else if !s.jobStart(d) {
+		return nil, ErrJobRunning
+	}
+
+	db = s.pool.Get()
+	err = db.JobAdd(j)
+	s.pool.Put(db)
+
+	if err != nil {
+		s.jobDone(d)
+		return nil, err
+	}
+
+	s.log.Printf("[INFO] %s %s\n", j.Kind, d.Name)
+
+	if output, err = s.p.Shutdown(ctx, d, reboot); err != nil {
+		s.logProbeError(d, strings.ToLower(j.Kind.String()), err)
+		j.Finished = time.Now()
+		j.Error = err.Error()
+		j.Output = strings.Join(output, "\n")
+		s.jobDone(d)
+
+		db = s.pool.Get()
+		defer s.pool.Put(db)
+
+		if ex := db.JobFinish(j); ex != nil {
+			return j, ex
+		}
+
+		return j, err
+	}
+
+	for _, l := range output {
+		fmt.Fprintln(out, l)
+	}
+
+	fmt.Fprintf(out, "%s  %s was accepted\n",
+		time.Now().Format(common.TimestampFormat),
+		j.Kind)
+
+	go s.watchPower(d, j, out)
+
+	return j, nil
+} // func (s *Scheduler) Shutdown(ctx context.Context, d *model.Device, reboot, override bool) (*model.Job, error)
+
+// waitForPing pings the given Device until its state matches alive, and
+// returns the time that happened. It gives up after timeout, or when the
+// Scheduler is stopped.
+func (s *Scheduler) waitForPing(d *model.Device, alive bool, timeout time.Duration) (time.Time, error) {
+	var deadline = time.Now().Add(timeout)
+
+	for s.IsActive() && time.Now().Before(deadline) {
+		if s.echo.Ping(d) == alive {
+			return time.Now(), nil
+		}
+
+		time.Sleep(powerWatchInterval)
+	}
+
+	if alive {
+		return time.Time{}, fmt.Errorf("%s did not come back within %s", d.Name, timeout)
+	}
+
+	return time.Time{}, fmt.Errorf("%s did not go down within %s", d.Name, timeout)
+} // func (s *Scheduler) waitForPing(d *model.Device, alive bool, timeout time.Duration) (time.Time, error)
+
+// watchPower watches a Device go down after a reboot or shutdown Job, and
+// come back after a reboot, records the downtime, and finishes the Job.
+func (s *Scheduler) watchPower(d *model.Device, j *model.Job, out *strings.Builder) {
+	var (
+		err      error
+		db       *database.Database
+		down, up time.Time
+		ev       *model.Event
+	)
+
+	defer s.jobDone(d)
+
+	if down, err = s.waitForPing(d, false, powerDownTimeout); err == nil {
+		fmt.Fprintf(out, "%s  %s is down\n",
+			down.Format(common.TimestampFormat),
+			d.Name)
+
+		if j.Kind == job.Shutdown {
+			ev = &model.Event{
+				DevID:     d.ID,
+				Timestamp: down,
+				Kind:      event.Shutdown,
+				Message:   "Shut down from carebear",
+			}
+		} else if up, err = s.waitForPing(d, true, powerUpTimeout); err == nil {
+			var downtime = up.Sub(down).Round(time.Second)
+
+			fmt.Fprintf(out, "%s  %s is back after %s\n",
+				up.Format(common.TimestampFormat),
+				d.Name,
+				downtime)
+
+			ev = &model.Event{
+				DevID:     d.ID,
+				Timestamp: down,
+				Kind:      event.Downtime,
+				Message:   fmt.Sprintf("Down for %s during reboot from carebear", downtime),
+			}
+		}
+	}
+
+	// Waiting for the Device may take minutes, so we only ask for a
+	// database connection once we are done.
+	db = s.pool.Get()
+	defer s.pool.Put(db)
+
+	if err != nil {
+		j.Error = err.Error()
+		s.log.Printf("[ERROR] %s\n", err.Error())
+	} else if err = db.EventAdd(ev); err != nil {
+		s.log.Printf("[ERROR] Failed to record downtime of %s: %s\n",
+			d.Name,
+			err.Error())
+	}
+
+	j.Finished = time.Now()
+	j.Output = out.String()
+
+	if err = db.JobFinish(j); err != nil {
+		s.log.Printf("[ERROR] Failed to finish %s job on %s: %s\n",
+			j.Kind,
+			d.Name,
+			err.Error())
+	}
+} // func (s *Scheduler) watchPower(d *model.Device, j *model.Job, out *strings.Builder)
@@ -2,12 +2,15 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 24. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-09-06 15:57:14 krylon>
+// Time-stamp: <2026-10-16 18:26:29 krylon>
 
 // Package scheduler provides the logic to schedule tasks and execute them.
 package scheduler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -20,6 +23,7 @@ import (
 	"github.com/blicero/carebear/database"
 	"github.com/blicero/carebear/logdomain"
 	"github.com/blicero/carebear/model"
+	"github.com/blicero/carebear/model/event"
 	"github.com/blicero/carebear/ping"
 	"github.com/blicero/carebear/probe"
 	"github.com/blicero/carebear/scanner"
@@ -44,8 +48,9 @@ type Task struct {
 type Scheduler struct {
 	log    *log.Logger
 	pool   *database.Pool
-	lock   sync.RWMutex // nolint: unused
+	lock   sync.RWMutex
 	active atomic.Bool
+	busy   map[int64]bool
 	sc     *scanner.NetworkScanner
 	p      *probe.Probe
 	echo   *ping.Pinger
@@ -53,16 +58,18 @@ type Scheduler struct {
 }
 
 // Create returns a fresh Scheduler.
-func Create(sc *scanner.NetworkScanner) (*Scheduler, error) {
+// username and port are the defaults used to connect to Devices via SSH,
+// unless a Device has an SSH profile that says otherwise.
+func Create(sc *scanner.NetworkScanner, username string, port int) (*Scheduler, error) {
 	var (
-		err                     error
-		username, keypath, home string
-		s                       = &Scheduler{
+		err           error
+		keypath, home string
+		s             = &Scheduler{
 			TaskQ: make(chan Task),
+			busy:  make(map[int64]bool),
 		}
 	)
 
-	username = os.Getenv("USER")
 	home = os.Getenv("HOME")
 	keypath = filepath.Join(home, ".ssh")
 
@@ -70,7 +77,7 @@ func Create(sc *scanner.NetworkScanner) (*Scheduler, error) {
 		return nil, err
 		// } else if s.sc, err = scanner.NewNetworkScanner(); err != nil {
 		// 	return nil, err
-	} else if s.p, err = probe.New(username, keypath); err != nil {
+	} else if s.p, err = probe.New(username, port, keypath); err != nil {
 		return nil, err
 	} else if s.echo, err = ping.Create(); err != nil {
 		return nil, err
@@ -85,13 +92,19 @@ func Create(sc *scanner.NetworkScanner) (*Scheduler, error) {
 	s.pool = database.DBPool
 
 	return s, nil
-} // func Create() (*Scheduler, error)
+} // func Create(sc *scanner.NetworkScanner, username string, port int) (*Scheduler, error)
 
 // IsActive returns the state of the Scheduler's active flag.
 func (s *Scheduler) IsActive() bool {
 	return s.active.Load()
 } // func (s *Scheduler) IsActive() bool
 
+// ResetConnection drops the cached SSH connection to the given Device,
+// e.g. because its SSH profile has changed.
+func (s *Scheduler) ResetConnection(d *model.Device) error {
+	return s.p.Disconnect(d)
+} // func (s *Scheduler) ResetConnection(d *model.Device) error
+
 // Stop clears the Scheduler's active flag.
 func (s *Scheduler) Stop() {
 	s.sc.Stop()
@@ -107,21 +120,44 @@ func (s *Scheduler) Start() {
 
 func (s *Scheduler) run() {
 	s.log.Println("[INFO] Scheduler starting up.")
-	s.log.Printf("[INFO] Scan interval: Net = %s, Devices = %s, Ping = %s, Updates = %s, Disk space = %s\n",
+	s.log.Printf("[INFO] Scan interval: Net = %s, Devices = %s, Ping = %s, Updates = %s, Disk space = %s, Temperature = %s, Memory = %s, SMART = %s, Pools = %s, Services = %s, Inventory = %s, Packages = %s, Ports = %s, Guests = %s\n",
 		settings.Settings.ScanIntervalNet,
 		settings.Settings.ScanIntervalDev,
 		settings.Settings.PingInterval,
 		settings.Settings.ProbeIntervalUpdates,
-		settings.Settings.ProbeIntervalDiskFree)
+		settings.Settings.ProbeIntervalDiskFree,
+		settings.Settings.ProbeIntervalTemp,
+		settings.Settings.ProbeIntervalMemory,
+		settings.Settings.ProbeIntervalSmart,
+		settings.Settings.ProbeIntervalPools,
+		settings.Settings.ProbeIntervalServices,
+		settings.Settings.ProbeIntervalInv,
+		settings.Settings.ProbeIntervalPackages,
+		settings.Settings.ProbeIntervalPorts,
+		settings.Settings.ProbeIntervalGuests)
 
 	defer s.log.Println("[INFO] Scheduler is quitting now.")
 
+	// Cancelling the context aborts any commands still running on
+	// Devices when the Scheduler stops.
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
 	var (
 		tickScanNet       = time.NewTicker(settings.Settings.ScanIntervalNet)
 		tickScanDev       = time.NewTicker(settings.Settings.ScanIntervalDev)
 		tickCheckLive     = time.NewTicker(settings.Settings.PingInterval)
 		tickQueryUpdates  = time.NewTicker(settings.Settings.ProbeIntervalUpdates)
 		tickQueryDiskFree = time.NewTicker(settings.Settings.ProbeIntervalDiskFree)
+		tickQueryTemp     = time.NewTicker(settings.Settings.ProbeIntervalTemp)
+		tickQueryMemory   = time.NewTicker(settings.Settings.ProbeIntervalMemory)
+		tickQuerySmart    = time.NewTicker(settings.Settings.ProbeIntervalSmart)
+		tickQueryPools    = time.NewTicker(settings.Settings.ProbeIntervalPools)
+		tickQueryServices = time.NewTicker(settings.Settings.ProbeIntervalServices)
+		tickQueryInv      = time.NewTicker(settings.Settings.ProbeIntervalInv)
+		tickQueryPackages = time.NewTicker(settings.Settings.ProbeIntervalPackages)
+		tickQueryPorts    = time.NewTicker(settings.Settings.ProbeIntervalPorts)
+		tickQueryGuests   = time.NewTicker(settings.Settings.ProbeIntervalGuests)
 	)
 
 	defer tickScanNet.Stop()
@@ -129,6 +165,19 @@ func (s *Scheduler) run() {
 	defer tickCheckLive.Stop()
 	defer tickQueryUpdates.Stop()
 	defer tickQueryDiskFree.Stop()
+	defer tickQueryTemp.Stop()
+	defer tickQueryMemory.Stop()
+	defer tickQuerySmart.Stop()
+	defer tickQueryPools.Stop()
+	defer tickQueryServices.Stop()
+	defer tickQueryInv.Stop()
+	defer tickQueryPackages.Stop()
+	defer tickQueryPorts.Stop()
+	defer tickQueryGuests.Stop()
+
+	for _, c := range s.loadCustomProbes() {
+		go s.customProbeLoop(ctx, c)
+	}
 
 	for s.IsActive() {
 		select {
@@ -137,7 +186,7 @@ func (s *Scheduler) run() {
 			s.sc.CmdQ <- command.Command{ID: command.ScanStart}
 		case <-tickScanDev.C:
 			s.log.Println("[INFO] Probe Devices")
-			go s.scanDevices()
+			go s.scanDevices(ctx)
 		case <-tickCheckLive.C:
 			s.log.Println("[INFO] Start Ping scan")
 			go s.pingDevices()
@@ -147,7 +196,7 @@ func (s *Scheduler) run() {
 			go s.deviceDispatch(updateQ)
 
 			for i := range probeWorkerCnt {
-				go s.queryDeviceUpdateWorker(i, updateQ)
+				go s.queryDeviceUpdateWorker(ctx, i, updateQ)
 			}
 		case <-tickQueryDiskFree.C:
 			s.log.Println("[INFO] Query free disk space")
@@ -155,7 +204,79 @@ func (s *Scheduler) run() {
 			go s.deviceDispatch(diskQ)
 
 			for i := range probeWorkerCnt {
-				go s.queryDeviceDiskFreeWorker(i, diskQ)
+				go s.queryDeviceDiskFreeWorker(ctx, i, diskQ)
+			}
+		case <-tickQueryTemp.C:
+			s.log.Println("[INFO] Query temperature sensors")
+			var tempQ = make(chan *model.Device)
+			go s.deviceDispatch(tempQ)
+
+			for i := range probeWorkerCnt {
+				go s.queryDeviceTempWorker(ctx, i, tempQ)
+			}
+		case <-tickQueryMemory.C:
+			s.log.Println("[INFO] Query memory usage")
+			var memQ = make(chan *model.Device)
+			go s.deviceDispatch(memQ)
+
+			for i := range probeWorkerCnt {
+				go s.queryDeviceMemoryWorker(ctx, i, memQ)
+			}
+		case <-tickQuerySmart.C:
+			s.log.Println("[INFO] Query SMART status of disks")
+			var smartQ = make(chan *model.Device)
+			go s.deviceDispatch(smartQ)
+
+			for i := range probeWorkerCnt {
+				go s.queryDeviceSmartWorker(ctx, i, smartQ)
+			}
+		case <-tickQueryPools.C:
+			s.log.Println("[INFO] Query state of storage pools")
+			var poolQ = make(chan *model.Device)
+			go s.deviceDispatch(poolQ)
+
+			for i := range probeWorkerCnt {
+				go s.queryDevicePoolWorker(ctx, i, poolQ)
+			}
+		case <-tickQueryServices.C:
+			s.log.Println("[INFO] Query failed services")
+			var svcQ = make(chan *model.Device)
+			go s.deviceDispatch(svcQ)
+
+			for i := range probeWorkerCnt {
+				go s.queryDeviceServiceWorker(ctx, i, svcQ)
+			}
+		case <-tickQueryInv.C:
+			s.log.Println("[INFO] Query hardware and software inventory")
+			var invQ = make(chan *model.Device)
+			go s.deviceDispatch(invQ)
+
+			for i := range probeWorkerCnt {
+				go s.queryDeviceInventoryWorker(ctx, i, invQ)
+			}
+		case <-tickQueryPackages.C:
+			s.log.Println("[INFO] Query installed packages")
+			var pkgQ = make(chan *model.Device)
+			go s.deviceDispatch(pkgQ)
+
+			for i := range probeWorkerCnt {
+				go s.queryDevicePackageWorker(ctx, i, pkgQ)
+			}
+		case <-tickQueryPorts.C:
+			s.log.Println("[INFO] Query listening ports")
+			var portQ = make(chan *model.Device)
+			go s.deviceDispatch(portQ)
+
+			for i := range probeWorkerCnt {
+				go s.queryDevicePortsWorker(ctx, i, portQ)
+			}
+		case <-tickQueryGuests.C:
+			s.log.Println("[INFO] Query containers, VMs and jails")
+			var guestQ = make(chan *model.Device)
+			go s.deviceDispatch(guestQ)
+
+			for i := range probeWorkerCnt {
+				go s.queryDeviceGuestsWorker(ctx, i, guestQ)
 			}
 		}
 	}
@@ -198,17 +319,20 @@ func (s *Scheduler) pingWorker(id int, pq chan *model.Device) {
 	}
 } // func (s *Scheduler) pingWorker(id int, pq chan *model.Device)
 
-func (s *Scheduler) scanDevices() {
+func (s *Scheduler) scanDevices(ctx context.Context) {
 	var (
 		err  error
 		db   *database.Database
 		devs []*model.Device
 	)
 
+	// The workers need connections of their own, we must not hold on to
+	// ours while we wait for them.
 	db = s.pool.Get()
-	defer s.pool.Put(db)
+	devs, err = db.DeviceGetAll(true)
+	s.pool.Put(db)
 
-	if devs, err = db.DeviceGetAll(true); err != nil {
+	if err != nil {
 		s.log.Printf("[ERROR] Failed to load all Devices: %s\n",
 			err.Error())
 		return
@@ -221,7 +345,7 @@ func (s *Scheduler) scanDevices() {
 	var devQ = make(chan *model.Device, 2)
 
 	for i := range probeWorkerCnt {
-		go s.deviceProbeWorker(i+1, devQ)
+		go s.deviceProbeWorker(ctx, i+1, devQ)
 	}
 
 	for _, d := range devs {
@@ -229,13 +353,83 @@ func (s *Scheduler) scanDevices() {
 	}
 
 	close(devQ)
-} // func (s *Scheduler) scanDevices()
+} // func (s *Scheduler) scanDevices(ctx context.Context)
+
+// logProbeError logs the failure to query a Device, unless the Device was
+// simply offline. Timeouts and missing root privileges get logged as such,
+// so they can be told apart from commands that failed outright. Timeouts
+// also go on the Device's timeline.
+func (s *Scheduler) logProbeError(d *model.Device, what string, err error) {
+	var (
+		terr *probe.TimeoutError
+		eerr *probe.EscalationError
+	)
+
+	if errors.Is(err, probe.ErrPingOffline) {
+		return
+	} else if errors.Is(err, probe.ErrUnsupported) {
+		s.log.Printf("[TRACE] Cannot query %s for %s: %s\n",
+			d.Name,
+			what,
+			err.Error())
+		return
+	} else if errors.As(err, &terr) {
+		s.log.Printf("[WARN] Timeout querying %s for %s: %s did not finish within %s\n",
+			d.Name,
+			what,
+			terr.Command,
+			terr.Timeout)
+		// The caller may hold a database connection, so we must not wait
+		// for one here.
+		go s.recordTimeout(d, what, terr)
+		return
+	} else if errors.As(err, &eerr) {
+		s.log.Printf("[ERROR] Cannot query %s for %s, %s refused to run %s: %s\n",
+			d.Name,
+			what,
+			eerr.Method,
+			eerr.Command,
+			eerr.Message)
+		return
+	}
 
-func (s *Scheduler) deviceProbeWorker(id int, devQ <-chan *model.Device) {
+	s.log.Printf("[ERROR] Failed to query %s for %s: %s\n",
+		d.Name,
+		what,
+		err.Error())
+} // func (s *Scheduler) logProbeError(d *model.Device, what string, err error)
+
+// recordTimeout adds an Event for a command that timed out to the Device's timeline.
+func (s *Scheduler) recordTimeout(d *model.Device, what string, terr *probe.TimeoutError) {
 	var (
 		err error
-		up  *model.Uptime
-		db  *database.Database
+		db  = s.pool.Get()
+		ev  = &model.Event{
+			DevID:     d.ID,
+			Timestamp: time.Now(),
+			Kind:      event.Timeout,
+			Message: fmt.Sprintf("Timeout querying %s: %s did not finish within %s",
+				what,
+				terr.Command,
+				terr.Timeout),
+		}
+	)
+
+	defer s.pool.Put(db)
+
+	if err = db.EventAdd(ev); err != nil {
+		s.log.Printf("[ERROR] Failed to record timeout on %s: %s\n",
+			d.Name,
+			err.Error())
+	}
+} // func (s *Scheduler) recordTimeout(d *model.Device, what string, terr *probe.TimeoutError)
+
+func (s *Scheduler) deviceProbeWorker(ctx context.Context, id int, devQ <-chan *model.Device) {
+	var (
+		err  error
+		up   *model.Uptime
+		prev []*model.Uptime
+		db   *database.Database
 	)
 
 	defer s.log.Printf("[TRACE] Device Probe Worker #%02d is quitting.\n",
@@ -258,43 +452,59 @@ func (s *Scheduler) deviceProbeWorker(id int, devQ <-chan *model.Device) {
 			s.log.Printf("[DEBUG] Device %s is irrelevant.\n",
 				d.Name)
 			continue
-		} else if d.OS == "" {
+		} else if d.OS == "" || d.OSID == "" {
 			s.log.Printf("[INFO] Probing OS of device %s\n",
 				d.Name)
-			var osname string
-			if osname, err = s.p.QueryOS(d, 22); err != nil {
-				if err != probe.ErrPingOffline {
-					s.log.Printf("[ERROR] Failed to query %s for its OS: %s\n",
-						d.Name, err.Error())
-				}
+			var rel *model.OSRelease
+			if rel, err = s.p.QueryOS(ctx, d); err != nil {
+				s.logProbeError(d, "its OS", err)
 				continue
-			} else if err = db.DeviceUpdateOS(d, osname); err != nil {
+			} else if err = db.DeviceUpdateOS(d, rel); err != nil {
 				s.log.Printf("[ERROR] Failed to set OS of %s to %s: %s\n",
 					d.Name,
-					osname,
+					rel.Name,
 					err.Error())
 				continue
 			}
 		}
 
-		if up, err = s.p.QueryUptime(d, 22); err != nil {
-			if err != probe.ErrPingOffline {
-				s.log.Printf("[ERROR] Failed to query uptime of Device %s: %s\n",
-					d.Name,
-					err.Error())
-			}
+		if up, err = s.p.QueryUptime(ctx, d); err != nil {
+			s.logProbeError(d, "uptime", err)
 			continue
 		} else if up == nil {
 			s.log.Println("[CANTHAPPEN] QueryUptime did not return an error, but value was nil")
 			continue
+		} else if prev, err = db.UptimeGetByDevice(d, 1); err != nil {
+			s.log.Printf("[ERROR] Failed to load previous Uptime for Device %s: %s\n",
+				d.Name,
+				err.Error())
+			continue
 		} else if err = db.UptimeAdd(up); err != nil {
 			s.log.Printf("[ERROR] Failed to add Uptime for Device %s to database: %s\n",
 				d.Name,
 				err.Error())
 			continue
+		} else if len(prev) > 0 && up.RebootedSince(prev[0]) {
+			var ev = &model.Event{
+				DevID:     d.ID,
+				Timestamp: up.BootTime(),
+				Kind:      event.Reboot,
+				Message: fmt.Sprintf("Rebooted, previous boot was at %s",
+					prev[0].BootTime().Format(common.TimestampFormat)),
+			}
+
+			s.log.Printf("[INFO] %s was rebooted at %s\n",
+				d.Name,
+				ev.Timestamp.Format(common.TimestampFormat))
+
+			if err = db.EventAdd(ev); err != nil {
+				s.log.Printf("[ERROR] Failed to record reboot of %s: %s\n",
+					d.Name,
+					err.Error())
+			}
 		}
 	}
-} // func (s *Scheduler) deviceProbeWorker(id int, devQ <-chan *model.Device)
+} // func (s *Scheduler) deviceProbeWorker(ctx context.Context, id int, devQ <-chan *model.Device)
 
 func (s *Scheduler) deviceDispatch(devQ chan<- *model.Device) {
 	var (
@@ -310,10 +520,13 @@ func (s *Scheduler) deviceDispatch(devQ chan<- *model.Device) {
 			cnt)
 	}()
 
+	// The workers need connections of their own, we must not hold on to
+	// ours while we wait for them.
 	db = s.pool.Get()
-	defer s.pool.Put(db)
+	devs, err = db.DeviceGetAll(true)
+	s.pool.Put(db)
 
-	if devs, err = db.DeviceGetAll(true); err != nil {
+	if err != nil {
 		s.log.Printf("[ERROR] Failed to load all Devices: %s\n",
 			err.Error())
 		return
@@ -325,81 +538,707 @@ func (s *Scheduler) deviceDispatch(devQ chan<- *model.Device) {
 	}
 } // func (s *Scheduler) deviceDispatch(devQ chan <-*model.Device)
 
-func (s *Scheduler) queryDeviceUpdateWorker(id int, devQ <-chan *model.Device) {
-	var (
-		err error
-		db  *database.Database
-	)
+func (s *Scheduler) queryDeviceUpdateWorker(ctx context.Context, id int, devQ <-chan *model.Device) {
+	var err error
 
 	defer s.log.Printf("[DEBUG] queryDeviceUpdateWorker #%02d is quitting.\n", id)
 
-	db = s.pool.Get()
-	defer s.pool.Put(db)
-
 	for d := range devQ {
 		s.log.Printf("[DEBUG] %02d: Query %s for pending updates\n",
 			id+1,
 			d.Name)
 
-		var updates = &model.Updates{
+		// A Device may well need a reboot even if we cannot find out
+		// about pending updates, e.g. if the package manager's mirror
+		// is down.
+		if err = s.queryUpdates(ctx, d); err != nil {
+			s.logProbeError(d, "pending updates", err)
+		}
+
+		if err = s.queryNeedReboot(ctx, d); err != nil {
+			s.logProbeError(d, "reboot status", err)
+		}
+	}
+} // func (s *Scheduler) queryDeviceUpdateWorker(ctx context.Context, id int, devQ <-chan *model.Device)
+
+// queryUpdates asks the given Device for pending updates and stores the result.
+// We store the set even if it is empty, so the most recent set always tells
+// us what a Device still needs.
+func (s *Scheduler) queryUpdates(ctx context.Context, d *model.Device) error {
+	var (
+		err     error
+		status  bool
+		db      *database.Database
+		updates = &model.Updates{
 			DevID:     d.ID,
 			Timestamp: time.Now(),
 		}
+	)
 
-		if updates.AvailableUpdates, err = s.p.QueryUpdates(d, 22); err != nil {
-			if err != probe.ErrPingOffline {
-				s.log.Printf("[ERROR] Failed to query %s for pending updates: %s\n",
-					d.Name,
-					err.Error())
-			}
-			continue
-		} else if len(updates.AvailableUpdates) == 0 {
-			s.log.Printf("[TRACE] No updates were found for %s\n",
-				d.Name)
+	if updates.AvailableUpdates, err = s.p.QueryUpdates(ctx, d); err != nil {
+		return err
+	}
+
+	db = s.pool.Get()
+	defer s.pool.Put(db)
+
+	if err = db.Begin(); err != nil {
+		s.log.Printf("[ERROR] Failed to start transaction: %s\n",
+			err.Error())
+		return err
+	}
+
+	defer func() {
+		if status {
+			db.Commit() // nolint: errcheck
+		} else {
+			db.Rollback() // nolint: errcheck
+		}
+	}()
+
+	if err = db.UpdatesAdd(updates); err != nil {
+		s.log.Printf("[ERROR] Failed to store %d pending updates for %s to database: %s\n",
+			len(updates.AvailableUpdates),
+			d.Name,
+			err.Error())
+		return err
+	}
+
+	s.log.Printf("[TRACE] Found %d pending updates for %s\n",
+		len(updates.AvailableUpdates),
+		d.Name)
+
+	status = true
+	return nil
+} // func (s *Scheduler) queryUpdates(ctx context.Context, d *model.Device) error
+
+// queryNeedReboot asks the given Device if it needs to be rebooted and
+// stores the result. We check right after querying for updates, because
+// installing updates is what usually makes a reboot necessary.
+func (s *Scheduler) queryNeedReboot(ctx context.Context, d *model.Device) error {
+	var (
+		err    error
+		db     *database.Database
+		reboot = &model.NeedReboot{
+			DevID:     d.ID,
+			Timestamp: time.Now(),
+		}
+	)
+
+	if reboot.Required, err = s.p.QueryNeedReboot(ctx, d); err != nil {
+		return err
+	}
+
+	db = s.pool.Get()
+	err = db.NeedRebootAdd(reboot)
+	s.pool.Put(db)
+
+	if err != nil {
+		s.log.Printf("[ERROR] Failed to store reboot status of %s to database: %s\n",
+			d.Name,
+			err.Error())
+		return err
+	}
+
+	if reboot.Required {
+		s.log.Printf("[INFO] %s needs to be rebooted\n", d.Name)
+	}
+
+	return nil
+} // func (s *Scheduler) queryNeedReboot(ctx context.Context, d *model.Device) error
+
+func (s *Scheduler) queryDeviceDiskFreeWorker(ctx context.Context, id int, devQ <-chan *model.Device) {
+	var (
+		err  error
+		free *model.DiskFree
+		db   *database.Database
+	)
+
+	defer s.log.Printf("[DEBUG] queryDeviceDiskFreeWorker #%02d is quitting.\n",
+		id)
+
+	for d := range devQ {
+		s.log.Printf("[TRACE] %02d: Query %s for free disk space\n",
+			id,
+			d.Name)
+
+		if free, err = s.p.QueryDiskFree(ctx, d); err != nil {
+			s.logProbeError(d, "free disk space", err)
 			continue
-		} else if err = db.UpdatesAdd(updates); err != nil {
-			s.log.Printf("[ERROR] Failed to stored update set for %s to database: %s\n%s\n",
+		}
+
+		db = s.pool.Get()
+		if err = db.DiskFreeAdd(d, free); err != nil {
+			s.log.Printf("[ERROR] %02d Failed to add free disk space for %s to Database: %s\n",
+				id,
 				d.Name,
-				err.Error(),
-				strings.Join(updates.AvailableUpdates, "\n"))
+				err.Error())
+		}
+		s.pool.Put(db)
+	}
+} // func (s *Scheduler) queryDeviceDiskFreeWorker(ctx context.Context, id int, devQ <- chan *model.Device)
+
+func (s *Scheduler) queryDeviceTempWorker(ctx context.Context, id int, devQ <-chan *model.Device) {
+	var (
+		err  error
+		temp *model.Temperature
+		db   *database.Database
+	)
+
+	defer s.log.Printf("[DEBUG] queryDeviceTempWorker #%02d is quitting.\n",
+		id)
+
+	for d := range devQ {
+		s.log.Printf("[TRACE] %02d: Query %s for temperature\n",
+			id,
+			d.Name)
+
+		if temp, err = s.p.QueryTemperature(ctx, d); err != nil {
+			s.logProbeError(d, "temperature", err)
+			continue
+		} else if len(temp.Sensors) == 0 {
 			continue
 		}
+
+		db = s.pool.Get()
+		if err = db.TemperatureAdd(temp); err != nil {
+			s.log.Printf("[ERROR] %02d Failed to add temperature for %s to Database: %s\n",
+				id,
+				d.Name,
+				err.Error())
+		}
+		s.pool.Put(db)
 	}
-} // func (s *Scheduler) queryDeviceUpdateWorker(id int, devQ <-chan *model.Device)
+} // func (s *Scheduler) queryDeviceTempWorker(ctx context.Context, id int, devQ <-chan *model.Device)
 
-func (s *Scheduler) queryDeviceDiskFreeWorker(id int, devQ <-chan *model.Device) {
+func (s *Scheduler) queryDeviceMemoryWorker(ctx context.Context, id int, devQ <-chan *model.Device) {
 	var (
 		err error
+		mem *model.Memory
 		db  *database.Database
 	)
 
-	defer s.log.Printf("[DEBUG] queryDeviceDiskFreeWorker #%02d is quitting.\n",
+	defer s.log.Printf("[DEBUG] queryDeviceMemoryWorker #%02d is quitting.\n",
 		id)
 
-	db = s.pool.Get()
-	defer s.pool.Put(db)
+	for d := range devQ {
+		s.log.Printf("[TRACE] %02d: Query %s for memory usage\n",
+			id,
+			d.Name)
+
+		if mem, err = s.p.QueryMemory(ctx, d); err != nil {
+			s.logProbeError(d, "memory usage", err)
+			continue
+		}
+
+		db = s.pool.Get()
+		if err = db.MemoryAdd(mem); err != nil {
+			s.log.Printf("[ERROR] %02d Failed to add memory usage for %s to Database: %s\n",
+				id,
+				d.Name,
+				err.Error())
+		}
+		s.pool.Put(db)
+	}
+} // func (s *Scheduler) queryDeviceMemoryWorker(ctx context.Context, id int, devQ <-chan *model.Device)
+
+func (s *Scheduler) queryDeviceSmartWorker(ctx context.Context, id int, devQ <-chan *model.Device) {
+	var (
+		err   error
+		disks []*model.SmartInfo
+		db    *database.Database
+	)
+
+	defer s.log.Printf("[DEBUG] queryDeviceSmartWorker #%02d is quitting.\n",
+		id)
 
 	for d := range devQ {
-		var free = &model.DiskFree{
-			DevID:     d.ID,
-			Timestamp: time.Now(),
+		s.log.Printf("[TRACE] %02d: Query %s for SMART status\n",
+			id,
+			d.Name)
+
+		if disks, err = s.p.QuerySmart(ctx, d); err != nil {
+			s.logProbeError(d, "SMART status", err)
+			continue
 		}
 
-		s.log.Printf("[TRACE] %02d: Query %s for free disk space\n",
+		db = s.pool.Get()
+
+		for _, si := range disks {
+			if !si.Passed {
+				s.log.Printf("[CRITICAL] SMART self-assessment of %s on %s FAILED\n",
+					si.Disk,
+					d.Name)
+			}
+
+			if err = db.SmartAdd(si); err != nil {
+				s.log.Printf("[ERROR] %02d Failed to add SMART status of %s on %s to Database: %s\n",
+					id,
+					si.Disk,
+					d.Name,
+					err.Error())
+			}
+		}
+
+		s.pool.Put(db)
+	}
+} // func (s *Scheduler) queryDeviceSmartWorker(ctx context.Context, id int, devQ <-chan *model.Device)
+
+func (s *Scheduler) queryDevicePoolWorker(ctx context.Context, id int, devQ <-chan *model.Device) {
+	var (
+		err error
+		ps  *model.PoolStatus
+		db  *database.Database
+	)
+
+	defer s.log.Printf("[DEBUG] queryDevicePoolWorker #%02d is quitting.\n",
+		id)
+
+	for d := range devQ {
+		s.log.Printf("[TRACE] %02d: Query %s for storage pools\n",
 			id,
 			d.Name)
 
-		if free.PercentFree, err = s.p.QueryDiskFree(d, 22); err != nil {
-			s.log.Printf("[ERROR] %02d Failed to query %s for free disk space: %s\n",
+		if ps, err = s.p.QueryPools(ctx, d); err != nil {
+			s.logProbeError(d, "storage pools", err)
+			continue
+		} else if len(ps.Pools) == 0 {
+			continue
+		}
+
+		for _, pool := range ps.Degraded() {
+			s.log.Printf("[CRITICAL] Pool %s on %s is %s (%d errors)\n",
+				pool.Name,
+				d.Name,
+				pool.State,
+				pool.Errors)
+		}
+
+		db = s.pool.Get()
+		if err = db.PoolStatusAdd(ps); err != nil {
+			s.log.Printf("[ERROR] %02d Failed to add pool status for %s to Database: %s\n",
 				id,
 				d.Name,
 				err.Error())
+		}
+		s.pool.Put(db)
+	}
+} // func (s *Scheduler) queryDevicePoolWorker(ctx context.Context, id int, devQ <-chan *model.Device)
+
+func (s *Scheduler) queryDeviceServiceWorker(ctx context.Context, id int, devQ <-chan *model.Device) {
+	var err error
+
+	defer s.log.Printf("[DEBUG] queryDeviceServiceWorker #%02d is quitting.\n",
+		id)
+
+	for d := range devQ {
+		s.log.Printf("[TRACE] %02d: Query %s for failed services\n",
+			id,
+			d.Name)
+
+		if err = s.queryFailedUnits(ctx, d); err != nil {
+			s.logProbeError(d, "failed services", err)
+		}
+	}
+} // func (s *Scheduler) queryDeviceServiceWorker(ctx context.Context, id int, devQ <-chan *model.Device)
+
+func (s *Scheduler) queryDeviceInventoryWorker(ctx context.Context, id int, devQ <-chan *model.Device) {
+	var (
+		err error
+		inv *model.Inventory
+	)
+
+	defer s.log.Printf("[DEBUG] queryDeviceInventoryWorker #%02d is quitting.\n",
+		id)
+
+	for d := range devQ {
+		s.log.Printf("[TRACE] %02d: Query %s for inventory\n",
+			id,
+			d.Name)
+
+		if inv, err = s.p.QueryInventory(ctx, d); err != nil {
+			s.logProbeError(d, "inventory", err)
 			continue
-		} else if err = db.DiskFreeAdd(d, free); err != nil {
-			s.log.Printf("[ERROR] %02d Failed to add free disk space for %s to Database: %s\n",
-				id,
+		}
+
+		s.storeInventory(id, d, inv)
+	}
+} // func (s *Scheduler) queryDeviceInventoryWorker(ctx context.Context, id int, devQ <-chan *model.Device)
+
+// storeInventory adds the given inventory to the database, unless nothing
+// has changed since we last looked.
+func (s *Scheduler) storeInventory(id int, d *model.Device, inv *model.Inventory) {
+	var (
+		err     error
+		prev    []*model.Inventory
+		changes []model.InventoryChange
+		db      = s.pool.Get()
+	)
+
+	defer s.pool.Put(db)
+
+	if prev, err = db.InventoryGetByDevice(d, 1); err != nil {
+		s.log.Printf("[ERROR] %02d Failed to load inventory of %s: %s\n",
+			id,
+			d.Name,
+			err.Error())
+		return
+	} else if len(prev) > 0 {
+		if changes = inv.Diff(prev[0]); len(changes) == 0 {
+			return
+		}
+
+		for _, c := range changes {
+			s.log.Printf("[INFO] %s of %s has changed from %q to %q\n",
+				c.Name,
 				d.Name,
-				err.Error())
+				c.Old,
+				c.New)
+		}
+	}
+
+	if err = db.InventoryAdd(inv); err != nil {
+		s.log.Printf("[ERROR] %02d Failed to add inventory of %s to Database: %s\n",
+			id,
+			d.Name,
+			err.Error())
+	}
+} // func (s *Scheduler) storeInventory(id int, d *model.Device, inv *model.Inventory)
+
+func (s *Scheduler) queryDevicePackageWorker(ctx context.Context, id int, devQ <-chan *model.Device) {
+	var err error
+
+	defer s.log.Printf("[DEBUG] queryDevicePackageWorker #%02d is quitting.\n",
+		id)
+
+	for d := range devQ {
+		s.log.Printf("[TRACE] %02d: Query %s for installed packages\n",
+			id,
+			d.Name)
+
+		if err = s.queryInstalledPackages(ctx, d); err != nil {
+			s.logProbeError(d, "installed packages", err)
 		}
 	}
-} // func (s *Scheduler) queryDeviceDiskFreeWorker(id int, devQ <- chan *model.Device)
+} // func (s *Scheduler) queryDevicePackageWorker(ctx context.Context, id int, devQ <-chan *model.Device)
+
+func (s *Scheduler) queryDevicePortsWorker(ctx context.Context, id int, devQ <-chan *model.Device) {
+	var (
+		err   error
+		ports *model.Ports
+	)
+
+	defer s.log.Printf("[DEBUG] queryDevicePortsWorker #%02d is quitting.\n",
+		id)
+
+	for d := range devQ {
+		s.log.Printf("[TRACE] %02d: Query %s for listening ports\n",
+			id,
+			d.Name)
+
+		if ports, err = s.p.QueryPorts(ctx, d); err != nil {
+			s.logProbeError(d, "listening ports", err)
+			continue
+		}
+
+		s.storePorts(id, d, ports)
+	}
+} // func (s *Scheduler) queryDevicePortsWorker(ctx context.Context, id int, devQ <-chan *model.Device)
+
+// storePorts adds the given listening ports to the database, and puts the
+// ports that were not open last time on the Device's timeline.
+func (s *Scheduler) storePorts(id int, d *model.Device, ports *model.Ports) {
+	var (
+		err  error
+		prev *model.Ports
+		db   = s.pool.Get()
+	)
+
+	defer s.pool.Put(db)
+
+	if prev, err = db.PortsGetByDevice(d); err != nil {
+		s.log.Printf("[ERROR] %02d Failed to load listening ports of %s: %s\n",
+			id,
+			d.Name,
+			err.Error())
+		return
+	} else if prev != nil {
+		for _, l := range ports.Opened(prev) {
+			var ev = &model.Event{
+				DevID:     d.ID,
+				Timestamp: ports.Timestamp,
+				Kind:      event.PortOpened,
+				Message:   "Listening on " + l.Key(),
+			}
+
+			if l.Process != "" {
+				ev.Message += " (" + l.Process + ")"
+			}
+
+			s.log.Printf("[INFO] %s: %s\n",
+				d.Name,
+				ev.Message)
+
+			if err = db.EventAdd(ev); err != nil {
+				s.log.Printf("[ERROR] %02d Failed to record opened port on %s: %s\n",
+					id,
+					d.Name,
+					err.Error())
+			}
+		}
+	}
+
+	if err = db.PortsAdd(ports); err != nil {
+		s.log.Printf("[ERROR] %02d Failed to add listening ports of %s to Database: %s\n",
+			id,
+			d.Name,
+			err.Error())
+	}
+} // func (s *Scheduler) storePorts(id int, d *model.Device, ports *model.Ports)
+
+func (s *Scheduler) queryDeviceGuestsWorker(ctx context.Context, id int, devQ <-chan *model.Device) {
+	var (
+		err    error
+		guests *model.Guests
+	)
+
+	defer s.log.Printf("[DEBUG] queryDeviceGuestsWorker #%02d is quitting.\n",
+		id)
+
+	for d := range devQ {
+		s.log.Printf("[TRACE] %02d: Query %s for containers, VMs and jails\n",
+			id,
+			d.Name)
+
+		if guests, err = s.p.QueryGuests(ctx, d); err != nil {
+			s.logProbeError(d, "guests", err)
+			continue
+		}
+
+		s.storeGuests(id, d, guests)
+	}
+} // func (s *Scheduler) queryDeviceGuestsWorker(ctx context.Context, id int, devQ <-chan *model.Device)
+
+// storeGuests adds the given guests to the database, after linking them to
+// the Devices we know.
+func (s *Scheduler) storeGuests(id int, d *model.Device, guests *model.Guests) {
+	var (
+		err  error
+		devs []*model.Device
+		db   = s.pool.Get()
+	)
+
+	defer s.pool.Put(db)
+
+	if devs, err = db.DeviceGetAll(false); err != nil {
+		s.log.Printf("[ERROR] %02d Failed to load Devices: %s\n",
+			id,
+			err.Error())
+		return
+	}
+
+	linkGuests(guests, devs)
+
+	if err = db.GuestsAdd(guests); err != nil {
+		s.log.Printf("[ERROR] %02d Failed to add guests of %s to Database: %s\n",
+			id,
+			d.Name,
+			err.Error())
+	}
+} // func (s *Scheduler) storeGuests(id int, d *model.Device, guests *model.Guests)
+
+// linkGuests looks for Devices we know that are guests in the given set,
+// e.g. a VM we also monitor directly. We go by the host name without the
+// domain, which is the best guess we have. Containers rarely run an SSH
+// server, and their names are too generic to go by, so we only look at VMs
+// and jails.
+func linkGuests(guests *model.Guests, devs []*model.Device) {
+	var byName = make(map[string]int64, len(devs))
+
+	for _, d := range devs {
+		if d.ID != guests.DevID {
+			byName[shortName(d.Name)] = d.ID
+		}
+	}
+
+	for i := range guests.Guests {
+		var g = &guests.Guests[i]
+
+		if g.Kind == model.GuestLibvirt || g.Kind == model.GuestJail {
+			g.DevID = byName[shortName(g.Name)]
+		}
+	}
+} // func linkGuests(guests *model.Guests, devs []*model.Device)
+
+// shortName returns the host name without the domain, in lower case.
+func shortName(name string) string {
+	var host, _, _ = strings.Cut(name, ".")
+
+	return strings.ToLower(host)
+} // func shortName(name string) string
+
+// queryInstalledPackages asks the given Device for its installed packages
+// and stores the differences to what we knew before. When we see a Device
+// for the first time, we do not record every package as a change.
+func (s *Scheduler) queryInstalledPackages(ctx context.Context, d *model.Device) error {
+	var (
+		db      *database.Database
+		err     error
+		status  bool
+		pkgs    []*model.Package
+		prev    []*model.InstalledPackage
+		added   []*model.Package
+		removed []*model.InstalledPackage
+		changes []*model.PackageChange
+		now     = time.Now()
+	)
+
+	if pkgs, err = s.p.QueryPackages(ctx, d); err != nil {
+		return err
+	}
+
+	db = s.pool.Get()
+	defer s.pool.Put(db)
+
+	if err = db.Begin(); err != nil {
+		s.log.Printf("[ERROR] Failed to start transaction: %s\n",
+			err.Error())
+		return err
+	}
+
+	defer func() {
+		if status {
+			db.Commit() // nolint: errcheck
+		} else {
+			db.Rollback() // nolint: errcheck
+		}
+	}()
+
+	if prev, err = db.InstalledPackageGetByDevice(d); err != nil {
+		s.log.Printf("[ERROR] Failed to load installed packages of %s: %s\n",
+			d.Name,
+			err.Error())
+		return err
+	}
+
+	added, removed, changes = model.DiffPackages(d.ID, now, prev, pkgs)
+
+	for _, p := range added {
+		var pkg = &model.InstalledPackage{
+			Package: *p,
+			DevID:   d.ID,
+			Since:   now,
+		}
+
+		if err = db.InstalledPackageSet(pkg); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range removed {
+		if err = db.InstalledPackageDelete(p); err != nil {
+			return err
+		}
+	}
+
+	if len(prev) > 0 {
+		for _, c := range changes {
+			if err = db.PackageChangeAdd(c); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.log.Printf("[TRACE] %s has %d packages installed, %d changed since last check\n",
+		d.Name,
+		len(pkgs),
+		len(changes))
+
+	status = true
+	return nil
+} // func (s *Scheduler) queryInstalledPackages(ctx context.Context, d *model.Device) error
+
+// queryFailedUnits asks the given Device for failed services and reconciles
+// the result with what we already know: Services that are still failed keep
+// the time we first noticed them, services that have recovered are cleared.
+func (s *Scheduler) queryFailedUnits(ctx context.Context, d *model.Device) error {
+	var (
+		db          *database.Database
+		err         error
+		status      bool
+		units, prev []*model.FailedUnit
+		known       map[string]*model.FailedUnit
+		now         = time.Now()
+	)
+
+	if units, err = s.p.QueryFailedUnits(ctx, d); err != nil {
+		return err
+	}
+
+	db = s.pool.Get()
+	defer s.pool.Put(db)
+
+	if err = db.Begin(); err != nil {
+		s.log.Printf("[ERROR] Failed to start transaction: %s\n",
+			err.Error())
+		return err
+	}
+
+	defer func() {
+		if status {
+			db.Commit() // nolint: errcheck
+		} else {
+			db.Rollback() // nolint: errcheck
+		}
+	}()
+
+	if prev, err = db.FailedUnitGetByDevice(d); err != nil {
+		s.log.Printf("[ERROR] Failed to load failed services of %s: %s\n",
+			d.Name,
+			err.Error())
+		return err
+	}
+
+	known = make(map[string]*model.FailedUnit, len(prev))
+
+	for _, u := range prev {
+		known[u.Name] = u
+	}
+
+	for _, u := range units {
+		if k, ok := known[u.Name]; ok {
+			delete(known, u.Name)
+			k.Description = u.Description
+
+			if err = db.FailedUnitUpdateLastSeen(k, now); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		s.log.Printf("[INFO] Service %s has failed on %s\n",
+			u.Name,
+			d.Name)
+
+		u.Since = now
+		u.LastSeen = now
+
+		if err = db.FailedUnitAdd(u); err != nil {
+			return err
+		}
+	}
+
+	for _, u := range known {
+		s.log.Printf("[INFO] Service %s on %s has recovered\n",
+			u.Name,
+			d.Name)
+
+		if err = db.FailedUnitClear(u, now); err != nil {
+			return err
+		}
+	}
+
+	status = true
+	return nil
+} // func (s *Scheduler) queryFailedUnits(ctx context.Context, d *model.Device) error
@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 31. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-08-16 19:55:30 krylon>
+// Time-stamp: <2026-10-16 17:10:32 krylon>
 
 package settings
 
@@ -20,8 +20,9 @@ func TestReadDefault(t *testing.T) {
 	)
 
 	const (
-		liveTimeout = time.Second * 600
-		webPort     = 3819
+		liveTimeout    = time.Second * 600
+		webPort        = 3819
+		commandTimeout = time.Second * 120
 	)
 
 	path = time.Now().Format("/tmp/carebear_test_cfg_20060102_150405.toml")
@@ -46,4 +47,14 @@ func TestReadDefault(t *testing.T) {
 			cfg.LiveTimeout,
 			liveTimeout)
 	}
+
+	if cfg.CommandTimeout != commandTimeout {
+		t.Errorf("Unexpected CommandTimeout: %s (expect %s)",
+			cfg.CommandTimeout,
+			commandTimeout)
+	}
+
+	if len(cfg.DiskExcludeTypes) == 0 || cfg.DiskExcludeTypes[0] != "tmpfs" {
+		t.Errorf("Unexpected DiskExcludeTypes: %v", cfg.DiskExcludeTypes)
+	}
 } // func TestReadDefault(t *testing.T)
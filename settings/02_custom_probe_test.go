@@ -0,0 +1,77 @@
+// /home/krylon/go/src/github.com/blicero/carebear/settings/02_custom_probe_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:33:43 krylon>
+
+package settings
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReadCustomProbes(t *testing.T) {
+	const custom = `
+[[Probe.Custom]]
+Name = "nginx_connections"
+Command = "curl -s http://localhost/nginx_status"
+OS = ["debian", "bsd"]
+Interval = 300
+Parser = "regex"
+Pattern = 'Active connections: (?P<active>\d+)'
+
+[[Probe.Custom]]
+Name = "entropy"
+Command = "cat /proc/sys/kernel/random/entropy_avail"
+Unit = "bits"
+`
+
+	var (
+		err  error
+		path string
+		cfg  *Options
+	)
+
+	path = time.Now().Format("/tmp/carebear_test_custom_20060102_150405.toml")
+
+	defer os.Remove(path) // nolint: errcheck
+
+	if err = os.WriteFile(path, []byte(defaultConfig+custom), 0600); err != nil {
+		t.Fatalf("Cannot write configuration file %s: %s",
+			path,
+			err.Error())
+	} else if cfg, err = Parse(path); err != nil {
+		t.Fatalf("Error Parsing configuration file: %s",
+			err.Error())
+	} else if len(cfg.CustomProbes) != 2 {
+		t.Fatalf("Unexpected number of custom probes: %d (expect 2)",
+			len(cfg.CustomProbes))
+	}
+
+	if c := cfg.CustomProbes[0]; c.Name != "nginx_connections" ||
+		c.Interval != time.Second*300 ||
+		c.Parser != ParserRegex ||
+		len(c.OS) != 2 {
+		t.Errorf("Unexpected custom probe: %#v", c)
+	}
+
+	if c := cfg.CustomProbes[1]; c.Parser != ParserNumber ||
+		c.Interval != time.Second*defaultCustomInterval ||
+		c.Unit != "bits" {
+		t.Errorf("Custom probe did not get the defaults: %#v", c)
+	}
+
+	if err = os.WriteFile(path, []byte(defaultConfig+custom+`
+[[Probe.Custom]]
+Name = "entropy"
+Command = "true"
+`), 0600); err != nil {
+		t.Fatalf("Cannot write configuration file %s: %s",
+			path,
+			err.Error())
+	} else if _, err = Parse(path); err == nil {
+		t.Error("Parse should reject custom probes with the same name")
+	}
+} // func TestReadCustomProbes(t *testing.T)
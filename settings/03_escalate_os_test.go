@@ -0,0 +1,66 @@
+// /home/krylon/go/src/github.com/blicero/carebear/settings/03_escalate_os_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 18:21:18 krylon>
+
+package settings
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscalateOSDefault(t *testing.T) {
+	const table = `[Probe.EscalateOS]
+openbsd = "doas"
+freebsd = "doas"
+`
+
+	var (
+		err  error
+		path string
+		cfg  *Options
+		old  = strings.Replace(defaultConfig, table, "", 1)
+	)
+
+	if old == defaultConfig {
+		t.Fatal("Default configuration has no Probe.EscalateOS table")
+	}
+
+	path = time.Now().Format("/tmp/carebear_test_escalate_20060102_150405.toml")
+
+	defer os.Remove(path) // nolint: errcheck
+
+	if err = os.WriteFile(path, []byte(old), 0600); err != nil {
+		t.Fatalf("Cannot write configuration file %s: %s",
+			path,
+			err.Error())
+	} else if cfg, err = Parse(path); err != nil {
+		t.Fatalf("Error Parsing configuration file: %s",
+			err.Error())
+	}
+
+	for _, osid := range []string{"openbsd", "freebsd"} {
+		if cfg.EscalateOS[osid] != EscalateDoas {
+			t.Errorf("Unexpected escalation method for %s: %q (expect %q)",
+				osid,
+				cfg.EscalateOS[osid],
+				EscalateDoas)
+		}
+	}
+
+	if err = os.WriteFile(path, []byte(old+"\n[Probe.EscalateOS]\ndebian = \"sudo\"\n"), 0600); err != nil {
+		t.Fatalf("Cannot write configuration file %s: %s",
+			path,
+			err.Error())
+	} else if cfg, err = Parse(path); err != nil {
+		t.Fatalf("Error Parsing configuration file: %s",
+			err.Error())
+	} else if len(cfg.EscalateOS) != 1 || cfg.EscalateOS["debian"] != EscalateSudo {
+		t.Errorf("Explicit Probe.EscalateOS should replace the defaults: %v",
+			cfg.EscalateOS)
+	}
+} // func TestEscalateOSDefault(t *testing.T)
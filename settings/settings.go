@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 31. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-09-06 15:04:22 krylon>
+// Time-stamp: <2026-10-16 18:21:53 krylon>
 
 // Package settings deals with the configuration file. Duh.
 package settings
@@ -10,6 +10,8 @@ package settings
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/blicero/carebear/common"
@@ -37,6 +39,46 @@ Workers = 32
 LiveTimeout = 600
 IntervalUpdates = 3600
 IntervalDiskFree = 1800
+IntervalTemperature = 900
+IntervalMemory = 300
+IntervalSmart = 3600
+IntervalPools = 900
+IntervalServices = 600
+IntervalInventory = 21600
+IntervalPackages = 21600
+IntervalPorts = 900
+IntervalGuests = 900
+DiskExcludeTypes = ["tmpfs", "devtmpfs", "overlay", "squashfs", "devfs", "fdescfs", "procfs", "linprocfs", "efivarfs"]
+DiskExcludeMounts = []
+
+# Escalate is how we run commands that need root privileges: "none", "sudo"
+# or "doas", always non-interactively. EscalateOS overrides it for Devices
+# whose os-release ID or ID_LIKE matches, the SSH profile of a Device
+# overrides both. Without an EscalateOS table, OpenBSD and FreeBSD use doas.
+[Probe]
+KnownHosts = ""
+CommandTimeout = 120
+Escalate = "none"
+
+[Probe.EscalateOS]
+openbsd = "doas"
+freebsd = "doas"
+# debian = "sudo"
+
+# Custom probes run a shell command on every matching Device and store the
+# result. OS lists the values of ID or ID_LIKE from /etc/os-release to run on,
+# or "linux" or "bsd", an empty list matches all Devices. The parser is one of
+# "number", "regex" (named groups become values) or "json" (Pattern is a
+# dotted path into the output, e.g. "stats.connections").
+#
+# [[Probe.Custom]]
+# Name = "nginx_connections"
+# Command = "curl -s http://localhost/nginx_status"
+# OS = ["linux"]
+# Interval = 300
+# Parser = "regex"
+# Pattern = 'Active connections: (?P<active>\d+)'
+# Unit = ""
 
 [Ping]
 Interval = 500
@@ -65,13 +107,85 @@ type Options struct {
 	PoolSize              int64
 	ProbeIntervalUpdates  time.Duration
 	ProbeIntervalDiskFree time.Duration
+	ProbeIntervalTemp     time.Duration
+	ProbeIntervalMemory   time.Duration
+	ProbeIntervalSmart    time.Duration
+	ProbeIntervalPools    time.Duration
+	ProbeIntervalServices time.Duration
+	ProbeIntervalInv      time.Duration
+	ProbeIntervalPackages time.Duration
+	ProbeIntervalPorts    time.Duration
+	ProbeIntervalGuests   time.Duration
+	DiskExcludeTypes      []string
+	DiskExcludeMounts     []string
 	PingInterval          time.Duration
 	PingTimeout           time.Duration
 	PingCount             int64
+	KnownHostsPath        string
+	CommandTimeout        time.Duration
+	Escalate              string
+	EscalateOS            map[string]string
+	CustomProbes          []CustomProbe
 }
 
+// Methods to run commands that need root privileges. An empty string means
+// we fall back to the next, less specific setting.
+const (
+	EscalateNone = "none"
+	EscalateSudo = "sudo"
+	EscalateDoas = "doas"
+)
+
+// ValidEscalation returns true if method is a known way to run commands as
+// root, or empty.
+func ValidEscalation(method string) bool {
+	switch method {
+	case "", EscalateNone, EscalateSudo, EscalateDoas:
+		return true
+	default:
+		return false
+	}
+} // func ValidEscalation(method string) bool
+
+// CustomProbe is a probe defined by the user in the configuration file.
+type CustomProbe struct {
+	Name     string
+	Command  string
+	OS       []string
+	Interval time.Duration
+	Parser   string
+	Pattern  string
+	Unit     string
+}
+
+// Parsers for the output of custom probes.
+const (
+	ParserNumber = "number"
+	ParserRegex  = "regex"
+	ParserJSON   = "json"
+)
+
+// defaultCustomInterval is used for custom probes that do not specify an
+// interval.
+const defaultCustomInterval = 900
+
 var Settings *Options
 
+// defaultDiskExcludeTypes lists the types of filesystems we ignore when
+// probing disk usage, unless the configuration file says otherwise. It
+// matches the list in the configuration file we generate.
+var defaultDiskExcludeTypes = []any{
+	"tmpfs",
+	"devtmpfs",
+	"overlay",
+	"squashfs",
+	"devfs",
+	"fdescfs",
+	"procfs",
+	"linprocfs",
+	"efivarfs",
+}
+
 // Parse reads the configuration file at the given path.
 // If path is an empty string, it uses the global default path.
 func Parse(path string) (*Options, error) {
@@ -110,9 +224,37 @@ func Parse(path string) (*Options, error) {
 	cfg.PoolSize = tree.Get("Global.PoolSize").(int64)
 	cfg.ProbeIntervalUpdates = time.Duration(tree.Get("Device.IntervalUpdates").(int64)) * time.Second
 	cfg.ProbeIntervalDiskFree = time.Duration(tree.Get("Device.IntervalDiskFree").(int64)) * time.Second
+	cfg.ProbeIntervalTemp = time.Duration(tree.GetDefault("Device.IntervalTemperature", int64(900)).(int64)) * time.Second
+	cfg.ProbeIntervalMemory = time.Duration(tree.GetDefault("Device.IntervalMemory", int64(300)).(int64)) * time.Second
+	cfg.ProbeIntervalSmart = time.Duration(tree.GetDefault("Device.IntervalSmart", int64(3600)).(int64)) * time.Second
+	cfg.ProbeIntervalPools = time.Duration(tree.GetDefault("Device.IntervalPools", int64(900)).(int64)) * time.Second
+	cfg.ProbeIntervalServices = time.Duration(tree.GetDefault("Device.IntervalServices", int64(600)).(int64)) * time.Second
+	cfg.ProbeIntervalInv = time.Duration(tree.GetDefault("Device.IntervalInventory", int64(21600)).(int64)) * time.Second
+	cfg.ProbeIntervalPackages = time.Duration(tree.GetDefault("Device.IntervalPackages", int64(21600)).(int64)) * time.Second
+	cfg.ProbeIntervalPorts = time.Duration(tree.GetDefault("Device.IntervalPorts", int64(900)).(int64)) * time.Second
+	cfg.ProbeIntervalGuests = time.Duration(tree.GetDefault("Device.IntervalGuests", int64(900)).(int64)) * time.Second
+	cfg.DiskExcludeTypes = stringList(tree.GetDefault("Device.DiskExcludeTypes", defaultDiskExcludeTypes))
+	cfg.DiskExcludeMounts = stringList(tree.GetDefault("Device.DiskExcludeMounts", []any{}))
 	cfg.PingCount = tree.Get("Ping.Count").(int64)
 	cfg.PingInterval = time.Duration(tree.Get("Ping.Interval").(int64)) * time.Second
 	cfg.PingTimeout = time.Duration(tree.Get("Ping.Timeout").(int64)) * time.Millisecond
+	cfg.KnownHostsPath = tree.GetDefault("Probe.KnownHosts", "").(string)
+	cfg.CommandTimeout = time.Duration(tree.GetDefault("Probe.CommandTimeout", int64(120)).(int64)) * time.Second
+	cfg.Escalate = strings.ToLower(tree.GetDefault("Probe.Escalate", "").(string))
+
+	if !ValidEscalation(cfg.Escalate) {
+		return nil, fmt.Errorf("Invalid value for Probe.Escalate: %q", cfg.Escalate)
+	} else if cfg.EscalateOS, err = parseEscalateOS(tree); err != nil {
+		return nil, err
+	} else if cfg.CustomProbes, err = parseCustomProbes(tree); err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(cfg.KnownHostsPath, "~/") {
+		cfg.KnownHostsPath = filepath.Join(
+			krylib.GetHomeDirectory(),
+			cfg.KnownHostsPath[2:])
+	}
 
 	for _, dom := range logdomain.AllDomains() {
 		var lvl string
@@ -130,6 +272,131 @@ func Parse(path string) (*Options, error) {
 	return cfg, nil
 } // func Parse(path string) (*Settings, error)
 
+// stringList converts an array from the configuration file to a list of
+// strings, skipping any element that is not a string.
+func stringList(val any) []string {
+	var (
+		arr  []any
+		ok   bool
+		list []string
+	)
+
+	if arr, ok = val.([]any); !ok {
+		return nil
+	}
+
+	list = make([]string, 0, len(arr))
+
+	for _, v := range arr {
+		if s, ok := v.(string); ok {
+			list = append(list, s)
+		}
+	}
+
+	return list
+} // func stringList(val any) []string
+
+// defaultEscalateOS is used when the configuration file has no
+// Probe.EscalateOS table. We used to run the BSD update checks via doas
+// unconditionally, configuration files from back then should keep working.
+var defaultEscalateOS = map[string]string{
+	"openbsd": EscalateDoas,
+	"freebsd": EscalateDoas,
+}
+
+// parseEscalateOS reads the escalation methods for specific operating
+// systems from the configuration file.
+func parseEscalateOS(tree *toml.Tree) (map[string]string, error) {
+	var (
+		ok    bool
+		table *toml.Tree
+		esc   = make(map[string]string)
+	)
+
+	if !tree.Has("Probe.EscalateOS") {
+		for osid, method := range defaultEscalateOS {
+			esc[osid] = method
+		}
+		return esc, nil
+	} else if table, ok = tree.Get("Probe.EscalateOS").(*toml.Tree); !ok {
+		return nil, fmt.Errorf("Probe.EscalateOS must be a table")
+	}
+
+	for _, osid := range table.Keys() {
+		var method string
+
+		if method, ok = table.Get(osid).(string); !ok || !ValidEscalation(strings.ToLower(method)) {
+			return nil, fmt.Errorf("Invalid escalation method for %s: %v",
+				osid,
+				table.Get(osid))
+		}
+
+		esc[strings.ToLower(osid)] = strings.ToLower(method)
+	}
+
+	return esc, nil
+} // func parseEscalateOS(tree *toml.Tree) (map[string]string, error)
+
+// parseCustomProbes reads the user-defined probes from the configuration
+// file and checks that they make sense.
+func parseCustomProbes(tree *toml.Tree) ([]CustomProbe, error) {
+	var (
+		ok     bool
+		tables []*toml.Tree
+		names  = make(map[string]bool)
+	)
+
+	if !tree.Has("Probe.Custom") {
+		return nil, nil
+	} else if tables, ok = tree.Get("Probe.Custom").([]*toml.Tree); !ok {
+		return nil, fmt.Errorf("Probe.Custom must be an array of tables")
+	}
+
+	var probes = make([]CustomProbe, 0, len(tables))
+
+	for idx, t := range tables {
+		var c = CustomProbe{
+			Name:     t.GetDefault("Name", "").(string),
+			Command:  t.GetDefault("Command", "").(string),
+			OS:       stringList(t.GetDefault("OS", []any{})),
+			Interval: time.Duration(t.GetDefault("Interval", int64(defaultCustomInterval)).(int64)) * time.Second,
+			Parser:   strings.ToLower(t.GetDefault("Parser", ParserNumber).(string)),
+			Pattern:  t.GetDefault("Pattern", "").(string),
+			Unit:     t.GetDefault("Unit", "").(string),
+		}
+
+		switch {
+		case c.Name == "":
+			return nil, fmt.Errorf("Custom probe #%d has no name", idx+1)
+		case names[c.Name]:
+			return nil, fmt.Errorf("Custom probe %q is defined more than once", c.Name)
+		case c.Command == "":
+			return nil, fmt.Errorf("Custom probe %q has no command", c.Name)
+		case c.Interval <= 0:
+			return nil, fmt.Errorf("Custom probe %q has an invalid interval", c.Name)
+		}
+
+		switch c.Parser {
+		case ParserNumber:
+		case ParserRegex, ParserJSON:
+			if c.Pattern == "" {
+				return nil, fmt.Errorf("Custom probe %q needs a pattern for parser %s",
+					c.Name,
+					c.Parser)
+			}
+		default:
+			return nil, fmt.Errorf("Custom probe %q has unknown parser %q",
+				c.Name,
+				c.Parser)
+		}
+
+		names[c.Name] = true
+		probes = append(probes, c)
+	}
+
+	return probes, nil
+} // func parseCustomProbes(tree *toml.Tree) ([]CustomProbe, error)
+
 func createDefaultConfig(path string) error {
 	var (
 		err     error
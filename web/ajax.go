@@ -2,16 +2,23 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 14. 07. 2025 by Benjamin Walkenhorst
 // (c) 2025 Benjamin Walkenhorst
-// Time-stamp: <2025-07-14 15:09:45 krylon>
+// Time-stamp: <2026-10-16 18:08:16 krylon>
 
 package web
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/blicero/carebear/common"
+	"github.com/blicero/carebear/database"
+	"github.com/blicero/carebear/model"
+	"github.com/blicero/carebear/settings"
+	"github.com/gorilla/mux"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -37,3 +44,494 @@ func (srv *Server) handleBeacon(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(200)
 	w.Write(response) // nolint: errcheck,gosec
 } // func (srv *Web) handleBeacon(w http.ResponseWriter, r *http.Request)
+
+func (srv *Server) handleHostKeyAccept(w http.ResponseWriter, r *http.Request) {
+	srv.log.Printf("[TRACE] Handle %s from %s\n",
+		r.URL,
+		r.RemoteAddr)
+
+	var (
+		err   error
+		id    int64
+		idStr string
+		db    *database.Database
+		key   *model.HostKey
+		res   ajaxResponse
+	)
+
+	idStr = mux.Vars(r)["id"]
+
+	if id, err = strconv.ParseInt(idStr, 10, 64); err != nil {
+		res.Message = fmt.Sprintf("Cannot parse host key ID %q: %s",
+			idStr,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	}
+
+	db = srv.pool.Get()
+	defer srv.pool.Put(db)
+
+	if key, err = db.HostKeyGetByID(id); err != nil {
+		res.Message = fmt.Sprintf("Failed to load host key %d: %s",
+			id,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if key == nil {
+		res.Message = fmt.Sprintf("Host key %d was not found in database", id)
+		srv.log.Printf("[INFO] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if err = db.Begin(); err != nil {
+		res.Message = fmt.Sprintf("Failed to start transaction: %s",
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	}
+
+	// We commit before we respond, so the client does not see success
+	// for a change that did not make it into the database.
+	if err = db.HostKeyDeleteOther(key); err != nil {
+		res.Message = fmt.Sprintf("Failed to remove previous host keys of Device %d: %s",
+			key.DevID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		db.Rollback() // nolint: errcheck
+		goto SEND_RESPONSE
+	} else if err = db.HostKeySetTrusted(key, true); err != nil {
+		res.Message = fmt.Sprintf("Failed to mark host key %s as trusted: %s",
+			key.Fingerprint,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		db.Rollback() // nolint: errcheck
+		goto SEND_RESPONSE
+	} else if err = db.Commit(); err != nil {
+		res.Message = fmt.Sprintf("Failed to accept host key %s: %s",
+			key.Fingerprint,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		db.Rollback() // nolint: errcheck
+		goto SEND_RESPONSE
+	}
+
+	srv.log.Printf("[INFO] Host key %s %s of Device %d has been accepted\n",
+		key.KeyType,
+		key.Fingerprint,
+		key.DevID)
+
+	res.Status = true
+	res.Message = fmt.Sprintf("Host key %s has been accepted", key.Fingerprint)
+
+SEND_RESPONSE:
+	srv.sendAjaxResponse(w, &res)
+} // func (srv *Server) handleHostKeyAccept(w http.ResponseWriter, r *http.Request)
+
+func (srv *Server) handleSSHProfileSet(w http.ResponseWriter, r *http.Request) {
+	srv.log.Printf("[TRACE] Handle %s from %s\n",
+		r.URL,
+		r.RemoteAddr)
+
+	var (
+		err     error
+		id      int64
+		idStr   string
+		portStr string
+		db      *database.Database
+		dev     *model.Device
+		prof    model.SSHProfile
+		res     ajaxResponse
+	)
+
+	idStr = mux.Vars(r)["id"]
+
+	if id, err = strconv.ParseInt(idStr, 10, 64); err != nil {
+		res.Message = fmt.Sprintf("Cannot parse Device ID %q: %s",
+			idStr,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if err = r.ParseForm(); err != nil {
+		res.Message = fmt.Sprintf("Cannot parse form data: %s",
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	}
+
+	prof.DevID = id
+	prof.User = strings.TrimSpace(r.FormValue("user"))
+	prof.KeyFile = strings.TrimSpace(r.FormValue("key_file"))
+	prof.ProxyJump = strings.TrimSpace(r.FormValue("proxy_jump"))
+	prof.Escalate = r.FormValue("escalate")
+
+	if !settings.ValidEscalation(prof.Escalate) {
+		res.Message = fmt.Sprintf("Invalid escalation method %q", prof.Escalate)
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	}
+
+	if portStr = strings.TrimSpace(r.FormValue("port")); portStr != "" {
+		if prof.Port, err = strconv.Atoi(portStr); err != nil || prof.Port < 0 || prof.Port > 65535 {
+			res.Message = fmt.Sprintf("Invalid port number %q", portStr)
+			srv.log.Printf("[ERROR] %s\n", res.Message)
+			goto SEND_RESPONSE
+		}
+	}
+
+	db = srv.pool.Get()
+	defer srv.pool.Put(db)
+
+	if dev, err = db.DeviceGetByID(id); err != nil {
+		res.Message = fmt.Sprintf("Failed to load Device %d: %s",
+			id,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if dev == nil {
+		res.Message = fmt.Sprintf("Device %d was not found in database", id)
+		srv.log.Printf("[INFO] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if err = db.SSHProfileSet(&prof); err != nil {
+		res.Message = fmt.Sprintf("Failed to save SSH profile for %s: %s",
+			dev.Name,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	}
+
+	// Make sure the new settings are used the next time we talk to the Device.
+	if srv.scheduler != nil {
+		srv.scheduler.ResetConnection(dev) // nolint: errcheck
+	}
+
+	res.Status = true
+	res.Message = fmt.Sprintf("SSH profile for %s has been saved", dev.Name)
+
+SEND_RESPONSE:
+	srv.sendAjaxResponse(w, &res)
+} // func (srv *Server) handleSSHProfileSet(w http.ResponseWriter, r *http.Request)
+
+// jobSummary describes the outcome of a Job in a single line.
+func jobSummary(j *model.Job, err error) string {
+	switch {
+	case err != nil:
+		return "Error: " + err.Error()
+	case j.Error != "":
+		return "Failed: " + j.Error
+	case j.Status != 0:
+		return fmt.Sprintf("Failed with exit status %d after %s",
+			j.Status,
+			j.Duration().Round(time.Second))
+	default:
+		return fmt.Sprintf("Finished successfully after %s",
+			j.Duration().Round(time.Second))
+	}
+} // func jobSummary(j *model.Job, err error) string
+
+// handleDeviceApplyUpdates installs the pending updates on a Device and
+// streams the output to the client as plain text. Once we have started,
+// the upgrade runs to completion, even if the client goes away.
+func (srv *Server) handleDeviceApplyUpdates(w http.ResponseWriter, r *http.Request) {
+	srv.log.Printf("[TRACE] Handle %s from %s\n",
+		r.URL,
+		r.RemoteAddr)
+
+	var (
+		err   error
+		id    int64
+		idStr string
+		msg   string
+		db    *database.Database
+		dev   *model.Device
+		j     *model.Job
+		out   *streamWriter
+	)
+
+	idStr = mux.Vars(r)["id"]
+
+	if id, err = strconv.ParseInt(idStr, 10, 64); err != nil {
+		msg = fmt.Sprintf("Cannot parse Device ID %q: %s",
+			idStr,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	} else if srv.scheduler == nil {
+		msg = "Scheduler is not running"
+		srv.log.Printf("[ERROR] %s\n", msg)
+		http.Error(w, msg, http.StatusServiceUnavailable)
+		return
+	}
+
+	db = srv.pool.Get()
+	dev, err = db.DeviceGetByID(id)
+	srv.pool.Put(db)
+
+	if err != nil {
+		msg = fmt.Sprintf("Failed to load Device %d: %s",
+			id,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	} else if dev == nil {
+		msg = fmt.Sprintf("Device %d was not found in database", id)
+		srv.log.Printf("[INFO] %s\n", msg)
+		http.Error(w, msg, http.StatusNotFound)
+		return
+	}
+
+	out = newStreamWriter(w)
+	fmt.Fprintf(out, ">>> Installing updates on %s\n", dev.Name)
+
+	j, err = srv.scheduler.ApplyUpdates(context.WithoutCancel(r.Context()), dev, out)
+
+	fmt.Fprintf(out, "\n>>> %s\n", jobSummary(j, err))
+} // func (srv *Server) handleDeviceApplyUpdates(w http.ResponseWriter, r *http.Request)
+
+// handleNetworkApplyUpdates installs the pending updates on all Devices in
+// a Network that are online and have any, one after the other.
+func (srv *Server) handleNetworkApplyUpdates(w http.ResponseWriter, r *http.Request) {
+	srv.log.Printf("[TRACE] Handle %s from %s\n",
+		r.URL,
+		r.RemoteAddr)
+
+	var (
+		err     error
+		id      int64
+		idStr   string
+		msg     string
+		db      *database.Database
+		nw      *model.Network
+		devices []*model.Device
+		updates []*model.Updates
+		pending = make(map[int64]bool)
+		out     *streamWriter
+		cnt     int
+	)
+
+	idStr = mux.Vars(r)["id"]
+
+	if id, err = strconv.ParseInt(idStr, 10, 64); err != nil {
+		msg = fmt.Sprintf("Cannot parse Network ID %q: %s",
+			idStr,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	} else if srv.scheduler == nil {
+		msg = "Scheduler is not running"
+		srv.log.Printf("[ERROR] %s\n", msg)
+		http.Error(w, msg, http.StatusServiceUnavailable)
+		return
+	}
+
+	// ApplyUpdates needs database connections of its own, and we will be
+	// busy for a while, so we put ours back once we know what to do.
+	db = srv.pool.Get()
+
+	if nw, err = db.NetworkGetByID(id); err != nil {
+		srv.pool.Put(db)
+		msg = fmt.Sprintf("Failed to load Network %d: %s",
+			id,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	} else if nw == nil {
+		srv.pool.Put(db)
+		msg = fmt.Sprintf("Network %d was not found in database", id)
+		srv.log.Printf("[INFO] %s\n", msg)
+		http.Error(w, msg, http.StatusNotFound)
+		return
+	} else if devices, err = db.DeviceGetByNetwork(nw); err != nil {
+		srv.pool.Put(db)
+		msg = fmt.Sprintf("Failed to load devices for Network %s: %s",
+			nw.Addr,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	} else if updates, err = db.UpdatesGetRecent(); err != nil {
+		srv.pool.Put(db)
+		msg = fmt.Sprintf("Failed to load pending updates: %s",
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+
+	srv.pool.Put(db)
+
+	for _, u := range updates {
+		pending[u.DevID] = u.UpdatesPending()
+	}
+
+	out = newStreamWriter(w)
+
+	for _, dev := range devices {
+		if !pending[dev.ID] || !dev.IsLive() {
+			continue
+		}
+
+		var j *model.Job
+
+		cnt++
+		fmt.Fprintf(out, ">>> Installing updates on %s\n", dev.Name)
+		j, err = srv.scheduler.ApplyUpdates(context.WithoutCancel(r.Context()), dev, out)
+		fmt.Fprintf(out, "\n>>> %s: %s\n\n", dev.Name, jobSummary(j, err))
+	}
+
+	if cnt == 0 {
+		fmt.Fprintf(out, ">>> No Device in %s that is online has pending updates.\n",
+			nw.Addr)
+	}
+} // func (srv *Server) handleNetworkApplyUpdates(w http.ResponseWriter, r *http.Request)
+
+func (srv *Server) handleDeviceSetCritical(w http.ResponseWriter, r *http.Request) {
+	srv.log.Printf("[TRACE] Handle %s from %s\n",
+		r.URL,
+		r.RemoteAddr)
+
+	var (
+		err      error
+		id       int64
+		idStr    string
+		critical bool
+		db       *database.Database
+		dev      *model.Device
+		res      ajaxResponse
+	)
+
+	idStr = mux.Vars(r)["id"]
+
+	if id, err = strconv.ParseInt(idStr, 10, 64); err != nil {
+		res.Message = fmt.Sprintf("Cannot parse Device ID %q: %s",
+			idStr,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if err = r.ParseForm(); err != nil {
+		res.Message = fmt.Sprintf("Cannot parse form data: %s",
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if critical, err = strconv.ParseBool(r.FormValue("critical")); err != nil {
+		res.Message = fmt.Sprintf("Cannot parse Critical flag %q: %s",
+			r.FormValue("critical"),
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	}
+
+	db = srv.pool.Get()
+	defer srv.pool.Put(db)
+
+	if dev, err = db.DeviceGetByID(id); err != nil {
+		res.Message = fmt.Sprintf("Failed to load Device %d: %s",
+			id,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if dev == nil {
+		res.Message = fmt.Sprintf("Device %d was not found in database", id)
+		srv.log.Printf("[INFO] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if err = db.DeviceSetCritical(dev, critical); err != nil {
+		res.Message = fmt.Sprintf("Failed to set Critical flag of %s: %s",
+			dev.Name,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	}
+
+	res.Status = true
+	res.Message = fmt.Sprintf("Critical flag of %s is now %t", dev.Name, critical)
+
+SEND_RESPONSE:
+	srv.sendAjaxResponse(w, &res)
+} // func (srv *Server) handleDeviceSetCritical(w http.ResponseWriter, r *http.Request)
+
+// handleDevicePower asks a Device to reboot or shut down. We only wait for
+// the Device to accept the request, the Scheduler watches it go down and
+// come back in the background.
+func (srv *Server) handleDevicePower(w http.ResponseWriter, r *http.Request) {
+	srv.log.Printf("[TRACE] Handle %s from %s\n",
+		r.URL,
+		r.RemoteAddr)
+
+	var (
+		err      error
+		id       int64
+		idStr    string
+		reboot   bool
+		override bool
+		db       *database.Database
+		dev      *model.Device
+		res      ajaxResponse
+	)
+
+	idStr = mux.Vars(r)["id"]
+
+	if id, err = strconv.ParseInt(idStr, 10, 64); err != nil {
+		res.Message = fmt.Sprintf("Cannot parse Device ID %q: %s",
+			idStr,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if err = r.ParseForm(); err != nil {
+		res.Message = fmt.Sprintf("Cannot parse form data: %s",
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if srv.scheduler == nil {
+		res.Message = "Scheduler is not running"
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	}
+
+	switch r.FormValue("action") {
+	case "reboot":
+		reboot = true
+	case "shutdown":
+		reboot = false
+	default:
+		res.Message = fmt.Sprintf("Invalid action %q", r.FormValue("action"))
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	}
+
+	override, _ = strconv.ParseBool(r.FormValue("override"))
+
+	db = srv.pool.Get()
+	dev, err = db.DeviceGetByID(id)
+	srv.pool.Put(db)
+
+	if err != nil {
+		res.Message = fmt.Sprintf("Failed to load Device %d: %s",
+			id,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if dev == nil {
+		res.Message = fmt.Sprintf("Device %d was not found in database", id)
+		srv.log.Printf("[INFO] %s\n", res.Message)
+		goto SEND_RESPONSE
+	} else if _, err = srv.scheduler.Shutdown(context.WithoutCancel(r.Context()), dev, reboot, override); err != nil {
+		res.Message = fmt.Sprintf("Cannot %s %s: %s",
+			r.FormValue("action"),
+			dev.Name,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		goto SEND_RESPONSE
+	}
+
+	res.Status = true
+	if reboot {
+		res.Message = fmt.Sprintf("%s is going to reboot in a minute", dev.Name)
+	} else {
+		res.Message = fmt.Sprintf("%s is going to shut down in a minute", dev.Name)
+	}
+
+SEND_RESPONSE:
+	srv.sendAjaxResponse(w, &res)
+} // func (srv *Server) handleDevicePower(w http.ResponseWriter, r *http.Request)
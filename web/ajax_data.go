@@ -2,6 +2,12 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 10. 06. 2024 by Benjamin Walkenhorst
 // (c) 2024 Benjamin Walkenhorst
-// Time-stamp: <2025-07-14 14:35:48 krylon>
+// Time-stamp: <2026-10-16 16:51:30 krylon>
 
 package web
+
+// ajaxResponse is the basic reply we send to AJAX requests.
+type ajaxResponse struct {
+	Status  bool
+	Message string
+}
@@ -1,4 +1,4 @@
-// Time-stamp: <2022-10-24 18:58:09 krylon>
+// Time-stamp: <2026-10-16 17:29:11 krylon>
 // -*- mode: javascript; coding: utf-8; -*-
 // Copyright 2015-2020 Benjamin Walkenhorst <krylon@gmx.net>
 //
@@ -1170,3 +1170,148 @@ function page_frame_resize () {
     console.log(msg)
     alert(msg)
 } // function page_frame_resize ()
+
+function hostkey_accept (key_id) {
+    const url = `/ajax/hostkey/${key_id}/accept`
+
+    if (!confirm('Accept the new host key? This replaces the previously trusted key.')) {
+        return
+    }
+
+    const req = $.post(url,
+                       {},
+                       (reply) => {
+                           if (reply.Status) {
+                               window.location.reload()
+                           } else {
+                               const msg = `Error accepting host key ${key_id}: ${reply.Message}`
+                               console.error(msg)
+                               alert(msg)
+                           }
+                       },
+                       'json')
+
+    req.fail = (rep, stat, xhr) => {
+        console.error(`Error accepting host key ${key_id}: ${rep} / ${stat} / ${xhr}`)
+    }
+} // function hostkey_accept (key_id)
+
+function ssh_profile_save (dev_id) {
+    const url = `/ajax/device/${dev_id}/ssh_profile`
+    const data = $('#ssh-profile-form').serialize()
+
+    const req = $.post(url,
+                       data,
+                       (reply) => {
+                           if (reply.Status) {
+                               alert(reply.Message)
+                           } else {
+                               const msg = `Error saving SSH profile of Device ${dev_id}: ${reply.Message}`
+                               console.error(msg)
+                               alert(msg)
+                           }
+                       },
+                       'json')
+
+    req.fail = (rep, stat, xhr) => {
+        console.error(`Error saving SSH profile of Device ${dev_id}: ${rep} / ${stat} / ${xhr}`)
+    }
+} // function ssh_profile_save (dev_id)
+
+// apply_updates asks the server to install pending updates and displays the
+// output as it arrives. An upgrade can run for a long time, so we read the
+// response as a stream rather than wait for it to complete.
+async function apply_updates (url, what, output_id) {
+    if (!confirm(`Install pending updates on ${what}?`)) {
+        return
+    }
+
+    const out = $(`#${output_id}`)[0]
+    out.textContent = ''
+    out.hidden = false
+
+    try {
+        const res = await fetch(url, { method: 'POST' })
+
+        if (!res.ok) {
+            const msg = `Error installing updates on ${what}: ${await res.text()}`
+            console.error(msg)
+            alert(msg)
+            return
+        }
+
+        const reader = res.body.getReader()
+        const decoder = new TextDecoder()
+
+        for (;;) {
+            const { done, value } = await reader.read()
+            if (done) {
+                break
+            }
+            out.textContent += decoder.decode(value, { stream: true })
+            out.scrollTop = out.scrollHeight
+        }
+    } catch (err) {
+        const msg = `Error installing updates on ${what}: ${err}`
+        console.error(msg)
+        alert(msg)
+    }
+} // function apply_updates (url, what, output_id)
+
+function device_set_critical (dev_id, critical) {
+    const url = `/ajax/device/${dev_id}/critical`
+
+    const req = $.post(url,
+                       { critical: critical },
+                       (reply) => {
+                           if (!reply.Status) {
+                               const msg = `Error setting Critical flag of Device ${dev_id}: ${reply.Message}`
+                               console.error(msg)
+                               alert(msg)
+                               $('#device-critical')[0].checked = !critical
+                           }
+                       },
+                       'json')
+
+    req.fail((rep, stat, xhr) => {
+        console.error(`Error setting Critical flag of Device ${dev_id}: ${rep} / ${stat} / ${xhr}`)
+        $('#device-critical')[0].checked = !critical
+    })
+} // function device_set_critical (dev_id, critical)
+
+// device_power asks the server to reboot or shut down a Device. For Devices
+// flagged as critical, the user has to confirm a second time, and we tell
+// the server to override the flag.
+function device_power (dev_id, name, action) {
+    const url = `/ajax/device/${dev_id}/power`
+    const what = action === 'reboot' ? 'Reboot' : 'Shut down'
+    let override = false
+
+    if (!confirm(`${what} ${name}?`)) {
+        return
+    }
+
+    if ($('#device-critical')[0].checked) {
+        if (!confirm(`${name} is flagged as critical! ${what} it anyway?`)) {
+            return
+        }
+        override = true
+    }
+
+    const req = $.post(url,
+                       { action: action, override: override },
+                       (reply) => {
+                           if (reply.Status) {
+                               alert(reply.Message)
+                           } else {
+                               const msg = `Error: ${reply.Message}`
+                               console.error(msg)
+                               alert(msg)
+                           }
+                       },
+                       'json')
+
+    req.fail((rep, stat, xhr) => {
+        console.error(`Error requesting ${action} of Device ${dev_id}: ${rep} / ${stat} / ${xhr}`)
+    })
+} // function device_power (dev_id, name, action)
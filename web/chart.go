@@ -0,0 +1,72 @@
+// /home/krylon/go/src/github.com/blicero/carebear/web/chart.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 16. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-16 17:29:50 krylon>
+
+package web
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// sparkline renders a series of values as a simple SVG line chart, with the
+// minimum and maximum printed next to it. It is meant for small histories,
+// say, the last few dozen measurements, oldest first.
+// The chart contains nothing but numbers we format ourselves, so it is safe
+// to hand it to the template engine unescaped.
+func sparkline(values []float64, width, height int) template.HTML {
+	const margin = 2
+
+	if len(values) == 0 {
+		return ""
+	}
+
+	var (
+		min, max = values[0], values[0]
+		points   = make([]string, len(values))
+		bld      strings.Builder
+	)
+
+	for _, v := range values {
+		if v < min {
+			min = v
+		} else if v > max {
+			max = v
+		}
+	}
+
+	var (
+		span  = max - min
+		step  float64
+		inner = float64(height - 2*margin)
+	)
+
+	if span == 0 {
+		span = 1
+	}
+
+	if len(values) > 1 {
+		step = float64(width-2*margin) / float64(len(values)-1)
+	}
+
+	for i, v := range values {
+		points[i] = fmt.Sprintf("%.1f,%.1f",
+			margin+float64(i)*step,
+			margin+inner-(v-min)/span*inner)
+	}
+
+	fmt.Fprintf(&bld, `<svg class="sparkline" width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">`,
+		width,
+		height,
+		width,
+		height)
+	fmt.Fprintf(&bld, `<polyline fill="none" stroke="#0d6efd" stroke-width="1.5" points="%s" />`,
+		strings.Join(points, " "))
+	bld.WriteString("</svg>")
+	fmt.Fprintf(&bld, ` <small>min %.1f / max %.1f</small>`, min, max)
+
+	return template.HTML(bld.String()) // nolint: gosec
+} // func sparkline(values []float64, width, height int) template.HTML
@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 12. 12. 2018 by Benjamin Walkenhorst
 // (c) 2018 Benjamin Walkenhorst
-// Time-stamp: <2025-08-29 20:23:46 krylon>
+// Time-stamp: <2026-10-16 17:07:33 krylon>
 
 package web
 
@@ -56,6 +56,8 @@ var funcmap = template.FuncMap{
 	"intRange":         intRange,
 	"inc":              inc,
 	"since":            since,
+	"sparkline":        sparkline,
+	"fmt_duration":     formatDuration,
 }
 
 type generator struct {
@@ -312,6 +314,10 @@ func inc(n int64) int64 {
 	return n + 1
 } // func inc(n int64) int64
 
+func formatDuration(d time.Duration) string {
+	return d.Truncate(time.Minute).String()
+} // func formatDuration(d time.Duration) string
+
 func since(t time.Time) string {
 	return time.Since(t).Truncate(time.Second).String()
 }
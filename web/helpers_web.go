@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 04. 09. 2019 by Benjamin Walkenhorst
 // (c) 2019 Benjamin Walkenhorst
-// Time-stamp: <2025-07-14 14:36:55 krylon>
+// Time-stamp: <2026-10-16 17:26:44 krylon>
 //
 // Helper functions for use by the HTTP request handlers
 
@@ -56,6 +56,57 @@ func jsonEscape(i string) string { // nolint: unused
 // }
 // func getMimeType(path string) (string, error)
 
+func (srv *Server) sendAjaxResponse(w http.ResponseWriter, res any) {
+	var (
+		err error
+		buf []byte
+	)
+
+	if buf, err = json.Marshal(res); err != nil {
+		srv.log.Printf("[ERROR] Failed to serialize AJAX response: %s\n",
+			err.Error())
+		buf = errJSON(err.Error())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", noCache)
+	w.WriteHeader(200)
+	w.Write(buf) // nolint: errcheck,gosec
+} // func (srv *Server) sendAjaxResponse(w http.ResponseWriter, res any)
+
+// streamWriter sends everything written to it to the client right away.
+// Once the client has gone away, further output is silently dropped, so
+// whatever produces it is not disturbed.
+type streamWriter struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	failed bool
+}
+
+func newStreamWriter(w http.ResponseWriter) *streamWriter {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", noCache)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(200)
+
+	return &streamWriter{
+		w:  w,
+		rc: http.NewResponseController(w),
+	}
+} // func newStreamWriter(w http.ResponseWriter) *streamWriter
+
+func (s *streamWriter) Write(b []byte) (int, error) {
+	if s.failed {
+		return len(b), nil
+	} else if _, err := s.w.Write(b); err != nil {
+		s.failed = true
+	} else if err = s.rc.Flush(); err != nil {
+		s.failed = true
+	}
+
+	return len(b), nil
+} // func (s *streamWriter) Write(b []byte) (int, error)
+
 func (srv *Server) baseData(title string, r *http.Request) tmplDataBase { // nolint: unused
 	return tmplDataBase{
 		Title: title,
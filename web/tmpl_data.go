@@ -2,15 +2,19 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 06. 05. 2020 by Benjamin Walkenhorst
 // (c) 2020 Benjamin Walkenhorst
-// Time-stamp: <2025-09-06 16:16:11 krylon>
+// Time-stamp: <2026-10-16 17:46:10 krylon>
 //
 // This file contains data structures to be passed to HTML templates.
 
 package web
 
 import (
+	"sort"
+	"time"
+
 	"github.com/blicero/carebear/model"
 	"github.com/blicero/carebear/scanner"
+	"github.com/blicero/carebear/settings"
 )
 
 type tmplDataBase struct { // nolint: unused
@@ -44,8 +48,28 @@ type tmplDataDeviceAll struct {
 	Devices []*model.Device
 	Updates map[int64]*model.Updates
 	Disk    map[int64]*model.DiskFree
+	Reboot  map[int64]*model.NeedReboot
+	Memory  map[int64]*model.Memory
+	Smart   map[int64][]*model.SmartInfo
+	Pools   map[int64]*model.PoolStatus
+	Failed  map[int64][]*model.FailedUnit
 }
 
+// NeedReboot returns true if the Device with the given ID needs to be rebooted.
+func (d *tmplDataDeviceAll) NeedReboot(devID int64) bool {
+	var r, ok = d.Reboot[devID]
+
+	return ok && r.Required
+} // func (d *tmplDataDeviceAll) NeedReboot(devID int64) bool
+
+// PoolsDegraded returns true if any of the storage pools of the Device with
+// the given ID is not healthy.
+func (d *tmplDataDeviceAll) PoolsDegraded(devID int64) bool {
+	var ps, ok = d.Pools[devID]
+
+	return ok && len(ps.Degraded()) > 0
+} // func (d *tmplDataDeviceAll) PoolsDegraded(devID int64) bool
+
 func (d *tmplDataDeviceAll) DiskFree(devID int64) int64 {
 	var (
 		free *model.DiskFree
@@ -61,10 +85,159 @@ func (d *tmplDataDeviceAll) DiskFree(devID int64) int64 {
 
 type tmplDataDeviceDetails struct {
 	tmplDataBase
-	Device  *model.Device
-	Network *model.Network
-	Uptime  *model.Uptime
-	Updates *model.Updates
+	Device     *model.Device
+	Network    *model.Network
+	Uptime     *model.Uptime
+	Updates    *model.Updates
+	HostKeys   []*model.HostKey
+	Profile    *model.SSHProfile
+	Temp       []*model.Temperature
+	Reboot     *model.NeedReboot
+	Events     []*model.Event
+	Memory     []*model.Memory
+	Disk       *model.DiskFree
+	Smart      []*model.SmartInfo
+	Pools      *model.PoolStatus
+	Failed     []*model.FailedUnit
+	Inv        []*model.Inventory
+	Packages   []*model.InstalledPackage
+	PkgChanges []*model.PackageChange
+	Ports      *model.Ports
+	Guests     *model.Guests
+	Host       *model.Device
+	HostGuest  *model.Guest
+	Jobs       []*model.Job
+	Custom     []*customResults
+}
+
+// customResults holds the results of a custom probe on a Device, the most
+// recent first.
+type customResults struct {
+	Probe  settings.CustomProbe
+	Values []*model.CustomValue
+}
+
+// Latest returns the most recent result.
+func (c *customResults) Latest() *model.CustomValue {
+	return c.Values[0]
+} // func (c *customResults) Latest() *model.CustomValue
+
+// Keys returns the names of the values in the most recent result, sorted.
+func (c *customResults) Keys() []string {
+	var keys = make([]string, 0, len(c.Values[0].Values))
+
+	for k := range c.Values[0].Values {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+} // func (c *customResults) Keys() []string
+
+// History returns the value of the given name from each result that has a
+// numeric one, oldest first, for drawing a chart. It returns nil if the
+// most recent value is not a number.
+func (c *customResults) History(key string) []float64 {
+	if _, ok := c.Values[0].Number(key); !ok {
+		return nil
+	}
+
+	var hist = make([]float64, 0, len(c.Values))
+
+	for i := len(c.Values) - 1; i >= 0; i-- {
+		if num, ok := c.Values[i].Number(key); ok {
+			hist = append(hist, num)
+		}
+	}
+
+	return hist
+} // func (c *customResults) History(key string) []float64
+
+// inventoryVersion is a version of a Device's Inventory along with the
+// changes from the version before.
+type inventoryVersion struct {
+	Timestamp time.Time
+	Changes   []model.InventoryChange
+}
+
+// TempHistory returns the highest reading of each set of temperature
+// readings, oldest first, for drawing a chart.
+func (d *tmplDataDeviceDetails) TempHistory() []float64 {
+	var hist = make([]float64, len(d.Temp))
+
+	for i, t := range d.Temp {
+		hist[len(d.Temp)-1-i] = t.Max()
+	}
+
+	return hist
+} // func (d *tmplDataDeviceDetails) TempHistory() []float64
+
+// InventoryChanges returns the changes between consecutive versions of the
+// Device's Inventory, the most recent first.
+func (d *tmplDataDeviceDetails) InventoryChanges() []inventoryVersion {
+	var versions = make([]inventoryVersion, 0, len(d.Inv))
+
+	for i := 0; i+1 < len(d.Inv); i++ {
+		versions = append(versions, inventoryVersion{
+			Timestamp: d.Inv[i].Timestamp,
+			Changes:   d.Inv[i].Diff(d.Inv[i+1]),
+		})
+	}
+
+	return versions
+} // func (d *tmplDataDeviceDetails) InventoryChanges() []inventoryVersion
+
+// SmartFailing returns the disks whose SMART self-assessment failed.
+func (d *tmplDataDeviceDetails) SmartFailing() []*model.SmartInfo {
+	var failing = make([]*model.SmartInfo, 0)
+
+	for _, si := range d.Smart {
+		if !si.Passed {
+			failing = append(failing, si)
+		}
+	}
+
+	return failing
+} // func (d *tmplDataDeviceDetails) SmartFailing() []*model.SmartInfo
+
+// MemoryHistory returns the percentage of RAM in use for each sample,
+// oldest first, for drawing a chart.
+func (d *tmplDataDeviceDetails) MemoryHistory() []float64 {
+	var hist = make([]float64, len(d.Memory))
+
+	for i, m := range d.Memory {
+		hist[len(d.Memory)-1-i] = m.PercentUsed()
+	}
+
+	return hist
+} // func (d *tmplDataDeviceDetails) MemoryHistory() []float64
+
+// HostKeyMismatch returns true if the Device has presented a host key
+// that we do not trust (yet).
+func (d *tmplDataDeviceDetails) HostKeyMismatch() bool {
+	for _, k := range d.HostKeys {
+		if !k.Trusted {
+			return true
+		}
+	}
+
+	return false
+} // func (d *tmplDataDeviceDetails) HostKeyMismatch() bool
+
+type tmplDataPackageSearch struct {
+	tmplDataBase
+	Name     string
+	Op       string
+	Version  string
+	Packages []*model.InstalledPackage
+	Devices  map[int64]*model.Device
+}
+
+type tmplDataUpdatesPackage struct {
+	tmplDataBase
+	Name    string
+	Updates []*model.PackageUpdate
+	Devices map[int64]*model.Device
 }
 
 // Local Variables:  //
@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 07. 06. 2024 by Benjamin Walkenhorst
 // (c) 2024 Benjamin Walkenhorst
-// Time-stamp: <2025-09-06 16:05:26 krylon>
+// Time-stamp: <2026-10-16 17:46:10 krylon>
 
 package web
 
@@ -19,6 +19,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/blicero/carebear/model"
 	"github.com/blicero/carebear/scanner"
 	"github.com/blicero/carebear/scheduler"
+	"github.com/blicero/carebear/settings"
 	"github.com/gorilla/mux"
 )
 
@@ -143,9 +145,17 @@ func Create(addr string, scan *scanner.NetworkScanner, sched *scheduler.Schedule
 	srv.router.HandleFunc("/network/{id:(?:\\d+)$}", srv.handleNetworkDetails)
 	srv.router.HandleFunc("/device/all", srv.handleDeviceAll)
 	srv.router.HandleFunc("/device/{id:(?:\\d+)$}", srv.handleDeviceDetails)
+	srv.router.HandleFunc("/updates/package", srv.handleUpdatesPackage)
+	srv.router.HandleFunc("/packages/search", srv.handlePackageSearch)
 
 	// AJAX Handlers
 	srv.router.HandleFunc("/ajax/beacon", srv.handleBeacon)
+	srv.router.HandleFunc("/ajax/hostkey/{id:(?:\\d+)}/accept", srv.handleHostKeyAccept).Methods("POST")
+	srv.router.HandleFunc("/ajax/device/{id:(?:\\d+)}/ssh_profile", srv.handleSSHProfileSet).Methods("POST")
+	srv.router.HandleFunc("/ajax/device/{id:(?:\\d+)}/apply_updates", srv.handleDeviceApplyUpdates).Methods("POST")
+	srv.router.HandleFunc("/ajax/device/{id:(?:\\d+)}/critical", srv.handleDeviceSetCritical).Methods("POST")
+	srv.router.HandleFunc("/ajax/device/{id:(?:\\d+)}/power", srv.handleDevicePower).Methods("POST")
+	srv.router.HandleFunc("/ajax/network/{id:(?:\\d+)}/apply_updates", srv.handleNetworkApplyUpdates).Methods("POST")
 
 	return srv, nil
 } // func Create(addr string) (*Server, error)
@@ -415,6 +425,36 @@ func (srv *Server) handleDeviceAll(w http.ResponseWriter, r *http.Request) {
 		srv.log.Printf("[ERROR] %s\n", msg)
 		srv.sendErrorMessage(w, msg)
 		return
+	} else if data.Reboot, err = db.NeedRebootGetRecent(); err != nil {
+		msg = fmt.Sprintf("Failed to load reboot status: %s",
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Memory, err = db.MemoryGetRecent(); err != nil {
+		msg = fmt.Sprintf("Failed to load memory usage: %s",
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Smart, err = db.SmartGetFailing(); err != nil {
+		msg = fmt.Sprintf("Failed to load SMART status: %s",
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Pools, err = db.PoolStatusGetRecent(); err != nil {
+		msg = fmt.Sprintf("Failed to load pool status: %s",
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Failed, err = db.FailedUnitGetActive(); err != nil {
+		msg = fmt.Sprintf("Failed to load failed services: %s",
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", msg)
+		srv.sendErrorMessage(w, msg)
+		return
 	}
 
 	data.Updates = make(map[int64]*model.Updates, len(updates))
@@ -444,7 +484,9 @@ func (srv *Server) handleDeviceDetails(w http.ResponseWriter, r *http.Request) {
 		r.RemoteAddr)
 
 	const (
-		tmplName = "device_details"
+		tmplName   = "device_details"
+		historyCnt = 96
+		jobCnt     = 10
 	)
 
 	var (
@@ -455,6 +497,7 @@ func (srv *Server) handleDeviceDetails(w http.ResponseWriter, r *http.Request) {
 		db         *database.Database
 		upd        []*model.Updates
 		uptime     []*model.Uptime
+		guests     map[int64]*model.Guests
 		tmpl       *template.Template
 		data       = tmplDataDeviceDetails{
 			tmplDataBase: tmplDataBase{
@@ -519,6 +562,192 @@ func (srv *Server) handleDeviceDetails(w http.ResponseWriter, r *http.Request) {
 			msg)
 		srv.sendErrorMessage(w, msg)
 		return
+	} else if data.HostKeys, err = db.HostKeyGetByDevice(data.Device); err != nil {
+		msg = fmt.Sprintf("Failed to load host keys for %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Profile, err = db.SSHProfileGetByDevice(data.Device); err != nil {
+		msg = fmt.Sprintf("Failed to load SSH profile for %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Temp, err = db.TemperatureGetByDevice(data.Device, historyCnt); err != nil {
+		msg = fmt.Sprintf("Failed to load temperature readings for %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Reboot, err = db.NeedRebootGetByDevice(data.Device); err != nil {
+		msg = fmt.Sprintf("Failed to load reboot status for %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Disk, err = db.DiskFreeGetByDevice(data.Device); err != nil {
+		msg = fmt.Sprintf("Failed to load disk usage for %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Memory, err = db.MemoryGetByDevice(data.Device, historyCnt); err != nil {
+		msg = fmt.Sprintf("Failed to load memory usage for %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Smart, err = db.SmartGetByDevice(data.Device); err != nil {
+		msg = fmt.Sprintf("Failed to load SMART status for %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Pools, err = db.PoolStatusGetByDevice(data.Device); err != nil {
+		msg = fmt.Sprintf("Failed to load pool status for %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Failed, err = db.FailedUnitGetByDevice(data.Device); err != nil {
+		msg = fmt.Sprintf("Failed to load failed services of %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Inv, err = db.InventoryGetByDevice(data.Device, historyCnt); err != nil {
+		msg = fmt.Sprintf("Failed to load inventory of %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Packages, err = db.InstalledPackageGetByDevice(data.Device); err != nil {
+		msg = fmt.Sprintf("Failed to load installed packages of %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.PkgChanges, err = db.PackageChangeGetByDevice(data.Device, historyCnt); err != nil {
+		msg = fmt.Sprintf("Failed to load package changes of %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Ports, err = db.PortsGetByDevice(data.Device); err != nil {
+		msg = fmt.Sprintf("Failed to load listening ports of %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Guests, err = db.GuestsGetByDevice(data.Device); err != nil {
+		msg = fmt.Sprintf("Failed to load guests of %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if guests, err = db.GuestsGetRecent(); err != nil {
+		msg = fmt.Sprintf("Failed to load guests of all Devices: %s",
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Jobs, err = db.JobGetByDevice(data.Device, jobCnt); err != nil {
+		msg = fmt.Sprintf("Failed to load jobs of %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Events, err = db.EventGetByDevice(data.Device, historyCnt); err != nil {
+		msg = fmt.Sprintf("Failed to load timeline for %s (%d): %s",
+			data.Device.Name,
+			data.Device.ID,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n",
+			msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	}
+
+	for hostID, g := range guests {
+		if data.HostGuest = g.Find(data.Device.ID); data.HostGuest == nil {
+			continue
+		} else if data.Host, err = db.DeviceGetByID(hostID); err != nil {
+			msg = fmt.Sprintf("Failed to load host of %s (%d): %s",
+				data.Device.Name,
+				data.Device.ID,
+				err.Error())
+			srv.log.Printf("[ERROR] %s\n",
+				msg)
+			srv.sendErrorMessage(w, msg)
+			return
+		}
+
+		break
+	}
+
+	for _, c := range settings.Settings.CustomProbes {
+		var vals []*model.CustomValue
+
+		if vals, err = db.CustomValueGetByDevice(data.Device, c.Name, historyCnt); err != nil {
+			msg = fmt.Sprintf("Failed to load results of custom probe %s for %s (%d): %s",
+				c.Name,
+				data.Device.Name,
+				data.Device.ID,
+				err.Error())
+			srv.log.Printf("[ERROR] %s\n",
+				msg)
+			srv.sendErrorMessage(w, msg)
+			return
+		} else if len(vals) > 0 {
+			data.Custom = append(data.Custom, &customResults{Probe: c, Values: vals})
+		}
 	}
 
 	if len(upd) > 0 {
@@ -544,6 +773,164 @@ func (srv *Server) handleDeviceDetails(w http.ResponseWriter, r *http.Request) {
 	}
 } // func (srv *Server) handleDeviceDetails(w http.ResponseWriter, r *http.Request)
 
+func (srv *Server) handleUpdatesPackage(w http.ResponseWriter, r *http.Request) {
+	srv.log.Printf("[TRACE] Handle %s from %s\n",
+		r.URL,
+		r.RemoteAddr)
+
+	const (
+		tmplName = "updates_package"
+	)
+
+	var (
+		err  error
+		msg  string
+		db   *database.Database
+		devs []*model.Device
+		tmpl *template.Template
+		data = tmplDataUpdatesPackage{
+			tmplDataBase: tmplDataBase{
+				Title: "Pending updates by package",
+				Debug: common.Debug,
+				URL:   r.URL.String(),
+			},
+			Name: strings.TrimSpace(r.URL.Query().Get("name")),
+		}
+	)
+
+	if data.Name != "" {
+		db = srv.pool.Get()
+		defer srv.pool.Put(db)
+
+		if data.Updates, err = db.PackageUpdateGetByName(data.Name); err != nil {
+			msg = fmt.Sprintf("Failed to look up pending updates for %s: %s",
+				data.Name,
+				err.Error())
+			srv.log.Printf("[ERROR] %s\n", msg)
+			srv.sendErrorMessage(w, msg)
+			return
+		} else if devs, err = db.DeviceGetAll(false); err != nil {
+			msg = fmt.Sprintf("Failed to load all devices: %s",
+				err.Error())
+			srv.log.Printf("[ERROR] %s\n", msg)
+			srv.sendErrorMessage(w, msg)
+			return
+		}
+
+		data.Title = fmt.Sprintf("Devices with pending updates for %s", data.Name)
+		data.Devices = make(map[int64]*model.Device, len(devs))
+
+		for _, d := range devs {
+			data.Devices[d.ID] = d
+		}
+	}
+
+	if tmpl = srv.tmpl.Lookup(tmplName); tmpl == nil {
+		msg = fmt.Sprintf("Could not find template %q", tmplName)
+		srv.log.Println("[CRITICAL] " + msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	}
+
+	w.Header().Set("Cache-Control", noCache)
+	if err = tmpl.Execute(w, &data); err != nil {
+		srv.log.Printf("[ERROR] Failed to render template %s: %s\n",
+			tmplName,
+			err.Error())
+	}
+} // func (srv *Server) handleUpdatesPackage(w http.ResponseWriter, r *http.Request)
+
+// versionOps maps the comparison operators of the package search form to a
+// test of the result of model.CompareVersions.
+var versionOps = map[string]func(int) bool{
+	"lt": func(c int) bool { return c < 0 },
+	"le": func(c int) bool { return c <= 0 },
+	"eq": func(c int) bool { return c == 0 },
+	"ge": func(c int) bool { return c >= 0 },
+	"gt": func(c int) bool { return c > 0 },
+}
+
+func (srv *Server) handlePackageSearch(w http.ResponseWriter, r *http.Request) {
+	srv.log.Printf("[TRACE] Handle %s from %s\n",
+		r.URL,
+		r.RemoteAddr)
+
+	const (
+		tmplName = "packages_search"
+	)
+
+	var (
+		err  error
+		msg  string
+		db   *database.Database
+		devs []*model.Device
+		pkgs []*model.InstalledPackage
+		tmpl *template.Template
+		qry  = r.URL.Query()
+		data = tmplDataPackageSearch{
+			tmplDataBase: tmplDataBase{
+				Title: "Installed packages",
+				Debug: common.Debug,
+				URL:   r.URL.String(),
+			},
+			Name:    strings.TrimSpace(qry.Get("name")),
+			Op:      qry.Get("op"),
+			Version: strings.TrimSpace(qry.Get("version")),
+		}
+	)
+
+	if data.Name != "" {
+		var test, ok = versionOps[data.Op]
+
+		db = srv.pool.Get()
+		defer srv.pool.Put(db)
+
+		if pkgs, err = db.InstalledPackageSearch(data.Name); err != nil {
+			msg = fmt.Sprintf("Failed to look up installed packages named %s: %s",
+				data.Name,
+				err.Error())
+			srv.log.Printf("[ERROR] %s\n", msg)
+			srv.sendErrorMessage(w, msg)
+			return
+		} else if devs, err = db.DeviceGetAll(false); err != nil {
+			msg = fmt.Sprintf("Failed to load all devices: %s",
+				err.Error())
+			srv.log.Printf("[ERROR] %s\n", msg)
+			srv.sendErrorMessage(w, msg)
+			return
+		}
+
+		data.Packages = make([]*model.InstalledPackage, 0, len(pkgs))
+
+		for _, p := range pkgs {
+			if !ok || data.Version == "" || test(model.CompareVersions(p.Version, data.Version)) {
+				data.Packages = append(data.Packages, p)
+			}
+		}
+
+		data.Title = fmt.Sprintf("Devices with %s installed", data.Name)
+		data.Devices = make(map[int64]*model.Device, len(devs))
+
+		for _, d := range devs {
+			data.Devices[d.ID] = d
+		}
+	}
+
+	if tmpl = srv.tmpl.Lookup(tmplName); tmpl == nil {
+		msg = fmt.Sprintf("Could not find template %q", tmplName)
+		srv.log.Println("[CRITICAL] " + msg)
+		srv.sendErrorMessage(w, msg)
+		return
+	}
+
+	w.Header().Set("Cache-Control", noCache)
+	if err = tmpl.Execute(w, &data); err != nil {
+		srv.log.Printf("[ERROR] Failed to render template %s: %s\n",
+			tmplName,
+			err.Error())
+	}
+} // func (srv *Server) handlePackageSearch(w http.ResponseWriter, r *http.Request)
+
 //////////////////////////////////////////////////////////////////////////////
 /// Handle static assets /////////////////////////////////////////////////////
 //////////////////////////////////////////////////////////////////////////////